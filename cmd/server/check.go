@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+// runCheckCommand implements `zenmonitor check <monitor>...`: probe the
+// named monitors once and print the results, without the server or the
+// database. The exit code is 0 if all are UP, 1 if any is DOWN and 2 on
+// usage or config errors.
+func runCheckCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: zenmonitor check <monitor>...")
+		return 2
+	}
+	cfg, err := loadConfig(configSource())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 2
+	}
+	return checkMonitors(os.Stdout, cfg, args)
+}
+
+func checkMonitors(w io.Writer, cfg *config.Config, names []string) int {
+	var monitors []config.MonitorConfig
+	for _, name := range names {
+		m := findMonitor(cfg, name)
+		if m == nil {
+			fmt.Fprintf(os.Stderr, "No monitor named %q\n", name)
+			return 2
+		}
+		monitors = append(monitors, *m)
+	}
+
+	code := 0
+	for _, m := range monitors {
+		r := monitor.RunCheck(m)
+		printResult(w, r)
+		if !r.Status {
+			code = 1
+		}
+	}
+	return code
+}
+
+func findMonitor(cfg *config.Config, name string) *config.MonitorConfig {
+	for i := range cfg.Monitors {
+		if cfg.Monitors[i].Name == name {
+			return &cfg.Monitors[i]
+		}
+	}
+	return nil
+}
+
+// printResult writes one line per check, e.g.
+// "api: UP in 84ms (status 200)" or "db: DOWN in 10s: dial tcp ...".
+func printResult(w io.Writer, r monitor.CheckResult) {
+	status := "DOWN"
+	switch {
+	case r.Status && r.Degraded:
+		status = "DEGRADED"
+	case r.Status:
+		status = "UP"
+	}
+	fmt.Fprintf(w, "%s: %s in %s", r.MonitorName, status, r.Latency.Round(time.Microsecond))
+	if r.StatusCode != 0 {
+		fmt.Fprintf(w, " (status %d)", r.StatusCode)
+	}
+	if r.Error != "" {
+		fmt.Fprintf(w, ": %s", r.Error)
+	}
+	fmt.Fprintln(w)
+}
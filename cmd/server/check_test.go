@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+func TestCheckMonitors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	cfg, err := config.LoadConfigReader(strings.NewReader(`
+monitors:
+  - {name: api, type: http, url: "` + srv.URL + `"}
+  - {name: broken, type: http, url: "` + srv.URL + `/broken"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		monitors []string
+		wantCode int
+		want     []string // output lines, as patterns
+	}{
+		{"up", []string{"api"}, 0, []string{`api: UP in \d\S*s \(status 200\)`}},
+		{"one down", []string{"api", "broken"}, 1, []string{
+			`api: UP in \d\S*s \(status 200\)`,
+			`broken: DOWN in \d\S*s \(status 500\): status code 500, expected 200`,
+		}},
+		{"unknown monitor", []string{"api", "nope"}, 2, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if code := checkMonitors(&out, cfg, tt.monitors); code != tt.wantCode {
+				t.Errorf("exit code %d, want %d", code, tt.wantCode)
+			}
+			lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			if len(tt.want) == 0 {
+				if out.Len() > 0 {
+					t.Errorf("printed %q, want nothing", out.String())
+				}
+				return
+			}
+			if len(lines) != len(tt.want) {
+				t.Fatalf("printed %q, want %d lines", out.String(), len(tt.want))
+			}
+			for i, pattern := range tt.want {
+				if !regexp.MustCompile("^" + pattern + "$").MatchString(lines[i]) {
+					t.Errorf("line %d is %q, want %s", i+1, lines[i], pattern)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+	"github.com/pronzzz/zenmonitor/internal/store"
+)
+
+// runImportCommand implements `zenmonitor import <file>`: load past checks
+// from a .csv or .json file into the database, e.g. when moving to a new
+// instance. Malformed rows are reported and skipped. Exit code 0 when
+// every row was imported, 1 when some were skipped, 2 on errors.
+func runImportCommand(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: zenmonitor import <checks.csv|checks.json>")
+		return 2
+	}
+	path := args[0]
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open %s: %v\n", path, err)
+		return 2
+	}
+	defer f.Close()
+
+	var rows []importRow
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		rows, err = readCSVChecks(f)
+	case ".json":
+		rows, err = readJSONChecks(f)
+	default:
+		err = fmt.Errorf("unknown file type, expected .csv or .json")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read %s: %v\n", path, err)
+		return 2
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create data dir: %v\n", err)
+		return 2
+	}
+	st, err := store.NewSQLiteStore(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open database at %s: %v\n", dbPath, err)
+		return 2
+	}
+	defer st.Close()
+
+	imported, skipped := importChecks(st, rows, os.Stderr)
+	fmt.Printf("Imported %d checks, skipped %d\n", imported, skipped)
+	if skipped > 0 {
+		return 1
+	}
+	return 0
+}
+
+// importRow is one check from an import file, or why it couldn't be read.
+type importRow struct {
+	line   int // line (CSV) or array index (JSON), for error messages
+	result monitor.CheckResult
+	err    error
+}
+
+// importChecks stores the valid rows and reports the others to w.
+func importChecks(st monitor.Store, rows []importRow, w io.Writer) (imported, skipped int) {
+	for _, row := range rows {
+		err := row.err
+		if err == nil {
+			err = st.LogCheck(row.result)
+		}
+		if err != nil {
+			fmt.Fprintf(w, "Skipping row %d: %v\n", row.line, err)
+			skipped++
+			continue
+		}
+		imported++
+	}
+	return imported, skipped
+}
+
+// importJSON is a check in an import file. It matches the check objects of
+// the history API, plus the monitor name.
+type importJSON struct {
+	Monitor    string    `json:"monitor"`
+	Timestamp  time.Time `json:"timestamp"`
+	Up         *bool     `json:"up"`
+	LatencyMs  float64   `json:"latency_ms"`
+	Error      string    `json:"error"`
+	StatusCode int       `json:"status_code"`
+}
+
+// readJSONChecks reads a JSON array of checks.
+func readJSONChecks(r io.Reader) ([]importRow, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+	rows := make([]importRow, 0, len(raw))
+	for i, msg := range raw {
+		row := importRow{line: i + 1}
+		var c importJSON
+		if err := json.Unmarshal(msg, &c); err != nil {
+			row.err = err
+		} else {
+			row.result, row.err = toImportResult(c)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// readCSVChecks reads CSV with a header row naming the columns: monitor,
+// timestamp (RFC 3339) and up are required; latency_ms, error and
+// status_code are optional. Column order doesn't matter.
+func readCSVChecks(r io.Reader) ([]importRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("missing header row: %w", err)
+	}
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"monitor", "timestamp", "up"} {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("header has no %s column", name)
+		}
+	}
+
+	var rows []importRow
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		row := importRow{line: line}
+		if err != nil {
+			row.err = err
+			rows = append(rows, row)
+			continue
+		}
+		row.result, row.err = parseCSVCheck(rec, col)
+		rows = append(rows, row)
+	}
+}
+
+func parseCSVCheck(rec []string, col map[string]int) (monitor.CheckResult, error) {
+	field := func(name string) string {
+		if i, ok := col[name]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	var c importJSON
+	var err error
+	c.Monitor = field("monitor")
+	if c.Timestamp, err = time.Parse(time.RFC3339, field("timestamp")); err != nil {
+		return monitor.CheckResult{}, fmt.Errorf("bad timestamp: %w", err)
+	}
+	up, err := strconv.ParseBool(field("up"))
+	if err != nil {
+		return monitor.CheckResult{}, fmt.Errorf("bad up value %q", field("up"))
+	}
+	c.Up = &up
+	if v := field("latency_ms"); v != "" {
+		if c.LatencyMs, err = strconv.ParseFloat(v, 64); err != nil {
+			return monitor.CheckResult{}, fmt.Errorf("bad latency_ms %q", v)
+		}
+	}
+	if v := field("status_code"); v != "" {
+		if c.StatusCode, err = strconv.Atoi(v); err != nil {
+			return monitor.CheckResult{}, fmt.Errorf("bad status_code %q", v)
+		}
+	}
+	c.Error = field("error")
+	return toImportResult(c)
+}
+
+// toImportResult validates an imported check and converts it.
+func toImportResult(c importJSON) (monitor.CheckResult, error) {
+	switch {
+	case c.Monitor == "":
+		return monitor.CheckResult{}, fmt.Errorf("monitor is empty")
+	case c.Timestamp.IsZero():
+		return monitor.CheckResult{}, fmt.Errorf("timestamp is missing")
+	case c.Timestamp.After(time.Now()):
+		return monitor.CheckResult{}, fmt.Errorf("timestamp %s is in the future", c.Timestamp.Format(time.RFC3339))
+	case c.Up == nil:
+		return monitor.CheckResult{}, fmt.Errorf("up is missing")
+	case c.LatencyMs < 0:
+		return monitor.CheckResult{}, fmt.Errorf("latency_ms is negative")
+	}
+	return monitor.CheckResult{
+		MonitorName: c.Monitor,
+		Timestamp:   c.Timestamp,
+		Status:      *c.Up,
+		Latency:     time.Duration(c.LatencyMs * float64(time.Millisecond)),
+		Error:       c.Error,
+		StatusCode:  c.StatusCode,
+	}, nil
+}
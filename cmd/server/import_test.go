@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/store"
+)
+
+func TestImportCSV(t *testing.T) {
+	csv := `timestamp,monitor,up,latency_ms,status_code,error
+2026-03-01T10:00:00Z,api,true,12.5,200,
+2026-03-01T10:01:00Z,api,false,,500,status code 500
+2026-03-01T10:02:00Z,api,maybe,,,
+2026-03-01T10:03:00Z,,true,,,
+2026-03-01T10:04:00Z,db,true,3,,
+`
+	rows, err := readCSVChecks(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "zen.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	var out bytes.Buffer
+	imported, skipped := importChecks(st, rows, &out)
+	if imported != 3 || skipped != 2 {
+		t.Errorf("imported %d, skipped %d; want 3 and 2", imported, skipped)
+	}
+	for _, want := range []string{`Skipping row 4: bad up value "maybe"`, "Skipping row 5: monitor is empty"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output %q doesn't contain %q", out.String(), want)
+		}
+	}
+
+	history, err := st.GetHistory("api", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("got %d api checks, want 2: %+v", len(history), history)
+	}
+	first, second := history[0], history[1]
+	if !first.Timestamp.Equal(time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)) || !first.Status ||
+		first.Latency != 12500*time.Microsecond || first.StatusCode != 200 {
+		t.Errorf("first check %+v", first)
+	}
+	if second.Status || second.StatusCode != 500 || second.Error != "status code 500" {
+		t.Errorf("second check %+v", second)
+	}
+	if history, _ := st.GetHistory("db", 10); len(history) != 1 || history[0].Latency != 3*time.Millisecond {
+		t.Errorf("db checks %+v, want one of 3ms", history)
+	}
+}
+
+func TestReadCSVChecksHeader(t *testing.T) {
+	for _, csv := range []string{"", "monitor,up\n", "timestamp,up\n"} {
+		if _, err := readCSVChecks(strings.NewReader(csv)); err == nil {
+			t.Errorf("%q: no error for a bad header", csv)
+		}
+	}
+}
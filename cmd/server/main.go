@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -18,84 +21,341 @@ import (
 	_ "modernc.org/sqlite"
 )
 
+// dbPath is where the SQLite database lives, relative to the working directory.
+const dbPath = "data/zen.db"
+
 func main() {
+	// One-shot subcommands, no server
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "check":
+			os.Exit(runCheckCommand(os.Args[2:]))
+		case "schema":
+			os.Exit(runSchemaCommand(os.Args[2:]))
+		case "import":
+			os.Exit(runImportCommand(os.Args[2:]))
+		default:
+			fmt.Fprintf(os.Stderr, "unknown command %q\nusage: zenmonitor [check <monitor>... | schema [--example] | import <file>]\n", os.Args[1])
+			os.Exit(2)
+		}
+	}
+
 	log.Println("Starting ZenMonitor...")
 
 	// 1. Load Config
-	// In Docker, we might map /app/config/monitors.yaml or just monitors.yaml in cwd
-	// Let's try explicit first, then cwd
-	configPath := "monitors.yaml"
-	if os.Getenv("CONFIG_PATH") != "" {
-		configPath = os.Getenv("CONFIG_PATH")
-	}
-
-	cfg, err := config.LoadConfig(configPath)
+	configPath := configSource()
+	cfg, err := loadConfig(configPath)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
-	log.Printf("Loaded %d monitors from %s", len(cfg.Monitors), configPath)
+	applyEnv(cfg)
+	gracePeriod, err := shutdownTimeout(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logLoaded(cfg, configPath)
 
 	// 2. Init Store
 	// Ensure data directory exists
 	if err := os.MkdirAll("data", 0755); err != nil {
 		log.Printf("Warning: failed to create data dir: %v", err)
 	}
-	dbPath := "data/zen.db"
-	st, err := store.NewSQLiteStore(dbPath)
+	storeOpts := store.Options{JournalMode: cfg.Global.Database.JournalMode}
+	if ci := cfg.Global.Database.CheckpointInterval; ci != "" {
+		storeOpts.CheckpointInterval = config.ParseDuration(ci)
+	}
+	st, err := store.NewSQLiteStoreWithOptions(dbPath, storeOpts)
 	if err != nil {
 		log.Fatalf("Failed to initialize database at %s: %v", dbPath, err)
 	}
 	defer st.Close()
 
-	// Prune old data on startup
-	go func() {
-		if err := st.PruneOldData(cfg.Global.HistoryDays); err != nil {
-			log.Printf("Failed to prune old data: %v", err)
-		}
-	}()
-
 	// 3. Init Notifier
 	notif := notifier.NewService(cfg.Notifications)
+	notif.Audit = st
+	notif.Workers = cfg.Global.NotifyWorkers
+	notif.TimeFormat = cfg.Global.TimeLayout()
+	if nc := cfg.Global.NotificationCooldown; nc != "" {
+		notif.Cooldown = config.ParseDuration(nc)
+	}
+	notif.Cooldowns = cfg.CooldownOverrides()
+	if loc, err := cfg.Global.Location(); err == nil { // validated in LoadConfig
+		notif.Location = loc
+	}
 
-	// 4. Init & Start Monitor Engine
+	// 4. Init Monitor Engine
 	engine := monitor.NewEngine(cfg, st, notif)
-	engine.Start()
-	log.Println("Monitoring engine started.")
-	defer engine.Stop()
 
-	// 5. Setup Web Server
-	handler := web.NewHandler(st, cfg)
+	// Prune old data on startup, and periodically along with a VACUUM if
+	// configured. Retention follows config reloads.
+	var vacuumEvery time.Duration
+	if vi := cfg.Global.Database.VacuumInterval; vi != "" {
+		vacuumEvery = config.ParseDuration(vi)
+	}
+	go maintainDatabase(st, engine.Config, vacuumEvery)
 
-	port := "8080"
-	if os.Getenv("PORT") != "" {
-		port = os.Getenv("PORT")
+	// 5. Setup Web Server. It listens before the engine starts probing, so
+	// /healthz and the dashboard answer while slow first checks run.
+	reload := func() (monitor.ReloadSummary, error) {
+		return reloadConfig(configPath, engine)
 	}
-	
+	handler := web.NewHandler(st, cfg, engine, reload)
+
+	addr := listenAddr(cfg)
 	server := &http.Server{
-		Addr:    ":" + port,
+		Addr:    addr,
 		Handler: handler,
 	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("HTTP server failed: %v", err)
+	}
 
+	tlsCfg := cfg.Global.TLS
 	go func() {
-		log.Printf("Web server listening on port %s", port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := serve(server, ln, tlsCfg); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("HTTP server failed: %v", err)
 		}
 	}()
 
+	var redirectServer *http.Server
+	if tlsCfg.RedirectHTTP != "" {
+		redirectServer = &http.Server{
+			Addr:    tlsCfg.RedirectHTTP,
+			Handler: httpsRedirect(addr),
+		}
+		go func() {
+			log.Printf("Redirecting HTTP on %s to HTTPS", tlsCfg.RedirectHTTP)
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("HTTP redirect server failed: %v", err)
+			}
+		}()
+	}
+
+	// Start probing
+	if cfg.Global.StartupCheck != "" {
+		sum := engine.SelfCheck()
+		log.Printf("Startup check: %d monitors, %d up, %d down, %d errors", sum.Total, sum.Up, sum.Down, sum.Errors)
+		if cfg.Global.StartupCheck == "strict" && sum.Total > 0 && sum.Errors == sum.Total {
+			log.Fatalf("Startup check failed: every monitor errored, check the config and network")
+		}
+	}
+	engine.Start()
+	log.Println("Monitoring engine started.")
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go reloadOnHangup(hup, configPath, engine)
+
 	// 6. Graceful Shutdown
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	<-stop
 
-	log.Println("Shutting down...")
-	// Engine stops via defer
-	// Store closes via defer
-	// Server shutdown could be explicit
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	log.Printf("Shutting down (waiting up to %s)...", gracePeriod)
+	// Store closes via defer, after checks in flight are recorded and
+	// alerts are flushed since it logs them. One deadline for all of it.
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
 	defer cancel()
 	if err := server.Shutdown(ctx); err != nil {
 		log.Printf("Server shutdown error: %v", err)
 	}
+	if redirectServer != nil {
+		redirectServer.Shutdown(ctx)
+	}
+	shutdown(ctx, engine, notif)
 	log.Println("ZenMonitor stopped.")
 }
+
+// shutdown stops the engine, then sends the alerts still queued, which
+// include those of checks that finished meanwhile. Both give up when ctx
+// is done, so a hung check or notification channel can't hold it up.
+func shutdown(ctx context.Context, engine *monitor.Engine, notif *notifier.Service) {
+	if err := engine.Stop(ctx); err != nil {
+		log.Printf("Monitoring engine not stopped: %v", err)
+	}
+	if err := notif.Close(ctx); err != nil {
+		log.Printf("Notification queue not drained: %v", err)
+	}
+}
+
+// errStdinReload is returned by reloadConfig for a config read from stdin,
+// which was consumed at startup.
+var errStdinReload = errors.New("config was read from stdin, it can't be read again")
+
+// reloadConfig loads the config from path again and applies its monitors
+// and views to the engine, and their cooldowns to its notifier, for SIGHUP
+// and POST /api/reload. A config that doesn't load leaves the running one
+// alone. The outcome is logged.
+func reloadConfig(path string, engine *monitor.Engine) (monitor.ReloadSummary, error) {
+	var (
+		sum  monitor.ReloadSummary
+		next *config.Config
+		err  = errStdinReload
+	)
+	if path != "-" {
+		next, err = loadConfig(path)
+	}
+	if err == nil {
+		applyEnv(next)
+		for _, err := range next.Skipped {
+			log.Printf("Warning: skipped invalid monitor, %v", err)
+		}
+		sum, err = engine.Reload(next)
+	}
+	if err != nil {
+		log.Printf("Config reload failed, keeping the running config: %v", err)
+		return sum, err
+	}
+	if notif, ok := engine.Notifier.(*notifier.Service); ok {
+		notif.SetCooldowns(engine.Config().CooldownOverrides())
+	}
+	log.Printf("Config reloaded: added %v, removed %v, changed %v", sum.Added, sum.Removed, sum.Changed)
+	if sum.RestartNeeded {
+		log.Printf("Warning: global or notification settings changed, they take effect on restart")
+	}
+	return sum, nil
+}
+
+// reloadOnHangup reloads the config on every SIGHUP received on hup.
+func reloadOnHangup(hup <-chan os.Signal, path string, engine *monitor.Engine) {
+	for range hup {
+		log.Printf("SIGHUP, reloading %s", path)
+		reloadConfig(path, engine) // logs the outcome
+	}
+}
+
+// applyEnv applies settings given as environment variables, which take
+// precedence over the config file.
+func applyEnv(cfg *config.Config) {
+	if secret := os.Getenv("INGEST_SECRET"); secret != "" {
+		cfg.Global.IngestSecret = secret
+	}
+	if token := os.Getenv("ADMIN_TOKEN"); token != "" {
+		cfg.Global.AdminToken = token
+	}
+}
+
+// logLoaded reports what was loaded from the config, warning about
+// skipped monitors and a config without any.
+func logLoaded(cfg *config.Config, configPath string) {
+	log.Printf("Loaded %d monitors from %s", len(cfg.Monitors), configPath)
+	for _, err := range cfg.Skipped {
+		log.Printf("Warning: skipped invalid monitor, %v", err)
+	}
+	if len(cfg.Monitors) == 0 {
+		// Keep serving so health checks pass and the dashboard can say why it's empty
+		log.Printf("Warning: no monitors configured in %s, nothing will be checked", configPath)
+	}
+}
+
+// shutdownTimeout is global.shutdown_timeout, or SHUTDOWN_TIMEOUT when
+// that's set. Both take the same durations ("30s", or bare seconds).
+func shutdownTimeout(cfg *config.Config) (time.Duration, error) {
+	v := os.Getenv("SHUTDOWN_TIMEOUT")
+	if v == "" {
+		return config.ParseDuration(cfg.Global.ShutdownTimeout), nil
+	}
+	d, err := config.ParsePositiveDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("SHUTDOWN_TIMEOUT: %w", err)
+	}
+	return d, nil
+}
+
+// configSource returns where to load the config from. In Docker, we might map
+// /app/config/monitors.yaml or just monitors.yaml in cwd. CONFIG_PATH may
+// also be "-" (stdin) or an http(s):// URL.
+func configSource() string {
+	if os.Getenv("CONFIG_PATH") != "" {
+		return os.Getenv("CONFIG_PATH")
+	}
+	return "monitors.yaml"
+}
+
+// loadConfig loads the config, with the overlay for the environment named
+// by ZEN_ENV (e.g. monitors.prod.yaml) merged on top when it's set.
+func loadConfig(path string) (*config.Config, error) {
+	env := os.Getenv("ZEN_ENV")
+	if env == "" {
+		return config.LoadConfig(path)
+	}
+	overlay := config.OverlayPath(path, env)
+	log.Printf("Applying %s overlay %s", env, overlay)
+	return config.LoadConfigWithOverlay(path, overlay)
+}
+
+// listenAddr picks the web server address: LISTEN_ADDR env, then
+// global.listen, then all interfaces on PORT (default 8080).
+func listenAddr(cfg *config.Config) string {
+	if addr := os.Getenv("LISTEN_ADDR"); addr != "" {
+		return addr
+	}
+	if cfg.Global.Listen != "" {
+		return cfg.Global.Listen
+	}
+	port := "8080"
+	if os.Getenv("PORT") != "" {
+		port = os.Getenv("PORT")
+	}
+	return ":" + port
+}
+
+// serve runs the web server on ln, over HTTPS when tlsCfg has a certificate.
+func serve(server *http.Server, ln net.Listener, tlsCfg config.TLSConfig) error {
+	if tlsCfg.Enabled() {
+		log.Printf("Web server listening on %s (HTTPS)", ln.Addr())
+		return server.ServeTLS(ln, tlsCfg.CertFile, tlsCfg.KeyFile)
+	}
+	log.Printf("Web server listening on %s", ln.Addr())
+	return server.Serve(ln)
+}
+
+// httpsRedirect sends plain HTTP requests to the same host on the HTTPS
+// listener's port.
+func httpsRedirect(tlsAddr string) http.Handler {
+	_, tlsPort, _ := net.SplitHostPort(tlsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if tlsPort != "" && tlsPort != "443" {
+			host = net.JoinHostPort(host, tlsPort)
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}
+
+// maintainDatabase prunes history past its retention right away and, when
+// every is set, prunes again and vacuums the file on that schedule.
+// SQLite doesn't shrink the file after deletes on its own. current is the
+// config to take retention from, looked up on every run.
+func maintainDatabase(st *store.SQLiteStore, current func() *config.Config, every time.Duration) {
+	prune := func() bool {
+		cfg := current()
+		if err := st.PruneOldData(cfg.Global.HistoryDays, cfg.RetentionOverrides()); err != nil {
+			log.Printf("Failed to prune old data: %v", err)
+			return false
+		}
+		return true
+	}
+	prune()
+	if every <= 0 {
+		return
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !prune() {
+			continue
+		}
+		start := time.Now()
+		if err := st.Vacuum(); err != nil {
+			log.Printf("Failed to vacuum database: %v", err)
+			continue
+		}
+		log.Printf("Database maintenance done in %s", time.Since(start).Round(time.Millisecond))
+	}
+}
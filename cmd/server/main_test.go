@@ -0,0 +1,481 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+	"github.com/pronzzz/zenmonitor/internal/notifier"
+)
+
+const twoMonitors = `
+global: {check_interval: 1h}
+monitors:
+  - {name: a, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: b, type: tcp, host: 127.0.0.1, port: 2}
+`
+
+// testEngine writes src to a config file and builds an engine from it.
+func testEngine(t *testing.T, src string) (*monitor.Engine, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "monitors.yaml")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return monitor.NewEngine(cfg, nil, nil), path
+}
+
+func monitorNames(e *monitor.Engine) []string {
+	var names []string
+	for _, m := range e.Config().Monitors {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+func TestReloadConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		next     string
+		wantErr  bool
+		monitors []string
+	}{
+		{
+			name:     "good config",
+			next:     "global: {check_interval: 1h}\nmonitors: [{name: a, type: tcp, host: 127.0.0.1, port: 1}]",
+			monitors: []string{"a"},
+		},
+		{
+			name:     "invalid config",
+			next:     "monitors: [{name: a, type: tcp, host: 127.0.0.1, port: 1, interval: often}]",
+			wantErr:  true,
+			monitors: []string{"a", "b"},
+		},
+		{
+			name:     "empty config",
+			next:     "",
+			wantErr:  true,
+			monitors: []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, path := testEngine(t, twoMonitors)
+			if err := os.WriteFile(path, []byte(tt.next), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := reloadConfig(path, engine)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("reloadConfig: err = %v, want error %v", err, tt.wantErr)
+			}
+			if got := monitorNames(engine); !slices.Equal(got, tt.monitors) {
+				t.Errorf("running monitors %v, want %v", got, tt.monitors)
+			}
+		})
+	}
+}
+
+func TestReloadConfigFromStdin(t *testing.T) {
+	engine, _ := testEngine(t, twoMonitors)
+	if _, err := reloadConfig("-", engine); !errors.Is(err, errStdinReload) {
+		t.Errorf("reloadConfig(\"-\"): err = %v, want errStdinReload", err)
+	}
+	if got := monitorNames(engine); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("running monitors %v after a refused reload", got)
+	}
+}
+
+// countingSender counts the messages it's asked to send.
+type countingSender struct {
+	sent atomic.Int32
+}
+
+func (s *countingSender) Send(string) error {
+	s.sent.Add(1)
+	return nil
+}
+
+// A monitor's notification_cooldown applies from the reload on.
+func TestReloadConfigCooldowns(t *testing.T) {
+	const running = `
+global: {check_interval: 1h}
+monitors:
+  - {name: a, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: b, type: tcp, host: 127.0.0.1, port: 2, notification_cooldown: 5m}
+`
+	tests := []struct {
+		name    string
+		next    string
+		monitor string
+		want    int32 // alerts sent for two outages a minute apart
+	}{
+		{"added monitor", running + "  - {name: c, type: tcp, host: 127.0.0.1, port: 3, notification_cooldown: 5m}\n", "c", 1},
+		{"cooldown set", strings.Replace(running, "port: 1}", "port: 1, notification_cooldown: 5m}", 1), "a", 1},
+		{"cooldown removed", strings.Replace(running, ", notification_cooldown: 5m", "", 1), "b", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, path := testEngine(t, running)
+			sender := &countingSender{}
+			notif := &notifier.Service{
+				Channels:  []notifier.Channel{{Type: "test", Sender: sender, Tmpl: template.Must(template.New("test").Parse("{{.Monitor}}"))}},
+				Cooldowns: engine.Config().CooldownOverrides(),
+			}
+			engine.Notifier = notif
+			if err := os.WriteFile(path, []byte(tt.next), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := reloadConfig(path, engine); err != nil {
+				t.Fatal(err)
+			}
+
+			start := time.Now()
+			for i := range 2 {
+				ts := start.Add(time.Duration(i) * time.Minute)
+				notif.Notify(monitor.CheckResult{MonitorName: tt.monitor, Timestamp: ts}, true)
+			}
+			if err := notif.Close(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := sender.sent.Load(); got != tt.want {
+				t.Errorf("%d alerts sent, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReloadOnHangup(t *testing.T) {
+	engine, path := testEngine(t, twoMonitors)
+	hup := make(chan os.Signal)
+	go reloadOnHangup(hup, path, engine)
+	defer close(hup)
+
+	os.WriteFile(path, []byte("global: {check_interval: 1h}\nmonitors: [{name: c, type: tcp, host: 127.0.0.1, port: 3}]"), 0o644)
+	hup <- syscall.SIGHUP
+	deadline := time.Now().Add(5 * time.Second)
+	for !slices.Equal(monitorNames(engine), []string{"c"}) {
+		if time.Now().After(deadline) {
+			t.Fatalf("running monitors %v, want [c] after SIGHUP", monitorNames(engine))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A bad config is logged and leaves the running one
+	os.WriteFile(path, []byte("monitors: [{name: x, type: tcp, port: 1, host: h, interval: often}]"), 0o644)
+	hup <- syscall.SIGHUP
+	hup <- syscall.SIGHUP // taken once the first reload is done
+	if got := monitorNames(engine); !slices.Equal(got, []string{"c"}) {
+		t.Errorf("running monitors %v after a bad reload, want [c]", got)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		env     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "default", want: 5 * time.Second},
+		{name: "config", config: "shutdown_timeout: 1m", want: time.Minute},
+		{name: "config in seconds", config: "shutdown_timeout: 45", want: 45 * time.Second},
+		{name: "env overrides config", config: "shutdown_timeout: 1m", env: "20s", want: 20 * time.Second},
+		{name: "env in seconds", env: "30", want: 30 * time.Second},
+		{name: "env invalid", env: "soon", wantErr: true},
+		{name: "env zero", env: "0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SHUTDOWN_TIMEOUT", tt.env)
+			engine, _ := testEngine(t, "global: {check_interval: 1h, "+tt.config+"}\n")
+			got, err := shutdownTimeout(engine.Config())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("shutdownTimeout = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// hungSender never answers, until release is closed.
+type hungSender struct {
+	release chan struct{}
+}
+
+func (s *hungSender) Send(string) error {
+	<-s.release
+	return errors.New("gave up")
+}
+
+// A check that hangs and alerts queued for a channel that hangs don't
+// hold shutdown past its deadline.
+func TestShutdownWithinTimeout(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release) // before srv.Close, which waits for the handler
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	sender := &hungSender{release: release}
+	notif := &notifier.Service{
+		Channels: []notifier.Channel{{Type: "test", Sender: sender, Tmpl: template.Must(template.New("test").Parse("{{.Monitor}}"))}},
+		Workers:  1,
+	}
+	// More than the queue holds
+	for range 200 {
+		notif.Notify(monitor.CheckResult{MonitorName: "api", Timestamp: time.Now()}, true)
+	}
+	engine, _ := testEngine(t, "global: {check_interval: 1h}\nmonitors: [{name: api, type: http, url: \""+srv.URL+"\"}]")
+	engine.Start()
+	<-started
+
+	const timeout = 200 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	begin := time.Now()
+	shutdown(ctx, engine, notif)
+	if took := time.Since(begin); took > timeout+time.Second {
+		t.Errorf("shutdown took %s with a %s timeout", took, timeout)
+	}
+	for _, want := range []string{"Monitoring engine not stopped", "Notification queue not drained"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("log %q doesn't say %q", buf.String(), want)
+		}
+	}
+}
+
+func TestListenAddr(t *testing.T) {
+	tests := []struct {
+		name       string
+		listen     string
+		listenAddr string
+		port       string
+		want       string
+	}{
+		{name: "default", want: ":8080"},
+		{name: "PORT", port: "9000", want: ":9000"},
+		{name: "global.listen", listen: "127.0.0.1:9100", port: "9000", want: "127.0.0.1:9100"},
+		{name: "LISTEN_ADDR", listen: "127.0.0.1:9100", listenAddr: "[::1]:9200", want: "[::1]:9200"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LISTEN_ADDR", tt.listenAddr)
+			t.Setenv("PORT", tt.port)
+			engine, _ := testEngine(t, "global: {check_interval: 1h, listen: \""+tt.listen+"\"}\n")
+			if got := listenAddr(engine.Config()); got != tt.want {
+				t.Errorf("listenAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListenOnlyOnConfiguredAddress(t *testing.T) {
+	t.Setenv("LISTEN_ADDR", "")
+	engine, _ := testEngine(t, "global: {check_interval: 1h, listen: \"127.0.0.1:0\"}\n")
+	ln, err := net.Listen("tcp", listenAddr(engine.Config()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go http.Serve(ln, http.NotFoundHandler())
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	tests := []struct {
+		host   string
+		wantOK bool
+	}{
+		{"127.0.0.1", true},
+		{"127.0.0.2", false}, // a loopback address too, but not the configured one
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			conn, err := net.DialTimeout("tcp", net.JoinHostPort(tt.host, port), time.Second)
+			if err == nil {
+				conn.Close()
+			}
+			if (err == nil) != tt.wantOK {
+				t.Errorf("connecting to %s: err = %v, want success %v", tt.host, err, tt.wantOK)
+			}
+		})
+	}
+}
+
+// writeCert writes a self-signed certificate for 127.0.0.1 and its key to
+// dir, returning their paths.
+func writeCert(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "zenmonitor test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestServeTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, _ := writeCert(t, dir)
+	pemCert, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(pemCert)
+
+	tests := []struct {
+		name    string
+		tls     string
+		wantTLS bool
+	}{
+		{"plain", "", false},
+		{"cert_dir", "tls: {cert_dir: " + dir + "}", true},
+		{"cert_file and key_file", "tls: {cert_file: " + dir + "/tls.crt, key_file: " + dir + "/tls.key}", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, _ := testEngine(t, "global: {check_interval: 1h, "+tt.tls+"}\n")
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("dashboard"))
+			})}
+			go serve(server, ln, engine.Config().Global.TLS)
+			defer server.Close()
+
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+			scheme, other := "http", "https"
+			if tt.wantTLS {
+				scheme, other = other, scheme
+			}
+			resp, err := client.Get(scheme + "://" + ln.Addr().String() + "/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK || (resp.TLS != nil) != tt.wantTLS {
+				t.Errorf("%s: status %d, TLS %v; want 200 and TLS %v", scheme, resp.StatusCode, resp.TLS != nil, tt.wantTLS)
+			}
+
+			// The other scheme doesn't get the dashboard
+			if resp, err := client.Get(other + "://" + ln.Addr().String() + "/"); err == nil {
+				resp.Body.Close()
+				if resp.StatusCode == http.StatusOK {
+					t.Errorf("%s on the same port served the dashboard", other)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+	tests := []struct {
+		tlsAddr string
+		host    string
+		want    string
+	}{
+		{":443", "example.com", "https://example.com/status?x=1"},
+		{":443", "example.com:80", "https://example.com/status?x=1"},
+		{":8443", "example.com:8080", "https://example.com:8443/status?x=1"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/status?x=1", nil)
+		req.Host = tt.host
+		httpsRedirect(tt.tlsAddr).ServeHTTP(rec, req)
+		if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != tt.want {
+			t.Errorf("%s via %s: %d to %q, want 301 to %q", tt.host, tt.tlsAddr, rec.Code, rec.Header().Get("Location"), tt.want)
+		}
+	}
+}
+
+func TestLogLoadedEmptyConfig(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	tests := []struct {
+		src      string
+		wantWarn bool
+	}{
+		{"global: {check_interval: 1m}\n", true},
+		{"monitors: []\n", true},
+		{twoMonitors, false},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		cfg, err := config.LoadConfigReader(strings.NewReader(tt.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		logLoaded(cfg, "monitors.yaml")
+		warned := strings.Contains(buf.String(), "Warning: no monitors configured in monitors.yaml")
+		if warned != tt.wantWarn {
+			t.Errorf("%q: logged %q, want the warning %v", tt.src, buf.String(), tt.wantWarn)
+		}
+	}
+}
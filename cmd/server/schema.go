@@ -0,0 +1,29 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// runSchemaCommand implements `zenmonitor schema [--example]`: print the
+// JSON Schema of monitors.yaml, or a commented sample config.
+func runSchemaCommand(args []string) int {
+	switch {
+	case len(args) == 0:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(config.Schema()); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write schema: %v\n", err)
+			return 1
+		}
+		return 0
+	case len(args) == 1 && args[0] == "--example":
+		fmt.Print(config.ExampleConfig)
+		return 0
+	}
+	fmt.Fprintln(os.Stderr, "usage: zenmonitor schema [--example]")
+	return 2
+}
@@ -0,0 +1,65 @@
+package config
+
+import "fmt"
+
+// LatencyAnomalyConfig flags checks that are slow compared to the
+// monitor's own recent history rather than a fixed threshold: a check is
+// anomalous when its latency exceeds multiplier times the given
+// percentile of the UP checks in the window.
+type LatencyAnomalyConfig struct {
+	// How many times the baseline counts as anomalous, default 2
+	Multiplier float64 `yaml:"multiplier,omitempty"`
+	// Percentile of past latencies used as the baseline, default 95
+	Percentile float64 `yaml:"percentile,omitempty"`
+	// Trailing period the baseline is computed over, default 24h
+	Window string `yaml:"window,omitempty"`
+	// Stored checks the window needs before anything is flagged, default 20
+	MinSamples int `yaml:"min_samples,omitempty"`
+	// What an anomalous check becomes: degraded (default) or down, which
+	// alerts like any outage
+	State string `yaml:"state,omitempty" schema:"enum=degraded|down"`
+}
+
+// Defaults for LatencyAnomalyConfig.
+const (
+	DefaultAnomalyMultiplier = 2.0
+	DefaultAnomalyPercentile = 95.0
+	DefaultAnomalyWindow     = "24h"
+	DefaultAnomalyMinSamples = 20
+)
+
+// resolve validates the settings and fills in their defaults.
+func (a *LatencyAnomalyConfig) resolve() error {
+	if a.Multiplier == 0 {
+		a.Multiplier = DefaultAnomalyMultiplier
+	}
+	if a.Multiplier < 1 {
+		return fmt.Errorf("multiplier must be at least 1")
+	}
+	if a.Percentile == 0 {
+		a.Percentile = DefaultAnomalyPercentile
+	}
+	if a.Percentile < 0 || a.Percentile > 100 {
+		return fmt.Errorf("percentile must be between 0 and 100")
+	}
+	if a.Window == "" {
+		a.Window = DefaultAnomalyWindow
+	}
+	if err := parseInterval(a.Window); err != nil {
+		return fmt.Errorf("window: %w", err)
+	}
+	if a.MinSamples == 0 {
+		a.MinSamples = DefaultAnomalyMinSamples
+	}
+	if a.MinSamples < 0 {
+		return fmt.Errorf("min_samples must not be negative")
+	}
+	switch a.State {
+	case "":
+		a.State = StateDegraded
+	case StateDegraded, StateDown:
+	default:
+		return fmt.Errorf("state must be degraded or down, not %q", a.State)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AuthConfig describes a login request made before an HTTP check. A token
+// is taken from its response and added to the check's own request, so
+// endpoints behind a login can be monitored.
+type AuthConfig struct {
+	URL     string            `yaml:"url" schema:"required"`
+	Method  string            `yaml:"method,omitempty"` // default POST with a body, GET without
+	Body    string            `yaml:"body,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"` // e.g. Content-Type: application/json
+
+	// Where the token comes from, exactly one of: a dotted path into a
+	// JSON body ("data.access_token", "tokens.0"), a response header or
+	// a cookie set by the response.
+	TokenJSON   string `yaml:"token_json,omitempty"`
+	TokenHeader string `yaml:"token_header,omitempty"`
+	TokenCookie string `yaml:"token_cookie,omitempty"`
+
+	// Where the token goes: a request header (default Authorization) whose
+	// value is inject_format with {token} replaced (default "Bearer {token}"),
+	// or a cookie. Tokens taken from a cookie are sent back as the same
+	// cookie unless inject_header is set.
+	InjectHeader string `yaml:"inject_header,omitempty"`
+	InjectFormat string `yaml:"inject_format,omitempty"`
+	InjectCookie string `yaml:"inject_cookie,omitempty"`
+}
+
+// TokenPlaceholder is replaced with the token in inject_format.
+const TokenPlaceholder = "{token}"
+
+// resolve validates the auth step and fills in its defaults.
+func (a *AuthConfig) resolve() error {
+	if a.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if a.Method == "" {
+		a.Method = "GET"
+		if a.Body != "" {
+			a.Method = "POST"
+		}
+	}
+
+	sources := 0
+	for _, s := range []string{a.TokenJSON, a.TokenHeader, a.TokenCookie} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("exactly one of token_json, token_header and token_cookie must be set")
+	}
+
+	if a.InjectHeader != "" && a.InjectCookie != "" {
+		return fmt.Errorf("inject_header and inject_cookie are mutually exclusive")
+	}
+	if a.InjectHeader == "" && a.InjectCookie == "" {
+		if a.TokenCookie != "" {
+			a.InjectCookie = a.TokenCookie
+		} else {
+			a.InjectHeader = "Authorization"
+		}
+	}
+	if a.InjectCookie != "" && a.InjectFormat != "" {
+		return fmt.Errorf("inject_format only applies to inject_header")
+	}
+	if a.InjectHeader != "" {
+		if a.InjectFormat == "" {
+			a.InjectFormat = "Bearer " + TokenPlaceholder
+		}
+		if !strings.Contains(a.InjectFormat, TokenPlaceholder) {
+			return fmt.Errorf("inject_format must contain %s", TokenPlaceholder)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAuthConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    string
+		want    AuthConfig // URL left out
+		wantErr string
+	}{
+		{
+			name: "defaults for a JSON token",
+			auth: "token_json: token",
+			want: AuthConfig{Method: "GET", TokenJSON: "token", InjectHeader: "Authorization", InjectFormat: "Bearer {token}"},
+		},
+		{
+			name: "POST with a body",
+			auth: "body: x, token_header: X-Token",
+			want: AuthConfig{Method: "POST", Body: "x", TokenHeader: "X-Token", InjectHeader: "Authorization", InjectFormat: "Bearer {token}"},
+		},
+		{
+			name: "cookies go back as cookies",
+			auth: "token_cookie: sid",
+			want: AuthConfig{Method: "GET", TokenCookie: "sid", InjectCookie: "sid"},
+		},
+		{name: "no token source", auth: "method: POST", wantErr: "exactly one of"},
+		{name: "two token sources", auth: "token_json: a, token_header: b", wantErr: "exactly one of"},
+		{name: "header and cookie", auth: "token_json: a, inject_header: X, inject_cookie: c", wantErr: "mutually exclusive"},
+		{name: "format for a cookie", auth: "token_cookie: sid, inject_format: '{token}'", wantErr: "only applies to inject_header"},
+		{name: "format without the token", auth: "token_json: a, inject_format: Bearer", wantErr: "must contain {token}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parse(t, "monitors:\n  - {name: api, type: http, url: \"http://127.0.0.1/\", auth: {url: \"http://127.0.0.1/login\", "+tt.auth+"}}\n")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := *cfg.Monitors[0].Auth
+			got.URL = ""
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("auth %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
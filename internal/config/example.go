@@ -0,0 +1,51 @@
+package config
+
+// ExampleConfig is a commented sample monitors.yaml covering the common
+// options. It must stay loadable by LoadConfig.
+const ExampleConfig = `# ZenMonitor configuration. Validate it in your editor against the schema
+# from "zenmonitor schema".
+
+global:
+  check_interval: 60s     # default for monitors without an interval
+  history_days: 90        # check history kept in the database
+  dashboard_refresh: 30s  # "0" turns auto-refresh off
+  dashboard_sort: status  # config, name, status (down first) or uptime
+  # timezone: Europe/Berlin     # for alerts and the dashboard, UTC by default
+  # timestamp_format: rfc1123  # rfc3339 (default), datetime or a Go layout
+  # stale_after: 3        # alert when a monitor has no result for 3 intervals
+  # flap_threshold: 6     # alert on 6 UP/DOWN changes within flap_window (1h)
+  # config_mode: lenient # skip invalid monitors instead of refusing to start
+  # database:
+  #   vacuum_interval: 24h  # prune and give freed space back to the filesystem
+
+notifications:
+  - type: telegram
+    token: "YOUR_BOT_TOKEN"   # or token_file: /run/secrets/telegram_token
+    chat_id: "123456789"
+  # - type: slack
+  #   webhook_url: https://hooks.slack.com/services/...
+  #   slack_blocks: true
+
+monitors:
+  # HTTP: expect a 200 (the default) and some text in the body
+  - name: Website
+    url: https://example.com
+    expect_body: Example Domain
+    interval: 30s
+    down_interval: 10s    # check more often while down
+
+  # TCP: the port accepts connections
+  - name: Database
+    host: db.internal
+    port: 5432
+
+  # SMTP: the server greets and accepts EHLO
+  - name: Mail
+    type: smtp
+    host: mx.example.com
+
+  # Aggregate: up while all (or any, or a quorum) of its children are up
+  - name: Backend
+    children: [Website, Database]
+    aggregation: all
+`
@@ -0,0 +1,18 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// normalizeFingerprint turns a SHA-256 fingerprint as tools print it
+// ("AB:CD:..." or "abcd...") into lower case hex without separators.
+func normalizeFingerprint(fp string) (string, error) {
+	fp = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(fp), ":", ""))
+	if b, err := hex.DecodeString(fp); err != nil || len(b) != sha256.Size {
+		return "", fmt.Errorf("want a SHA-256 fingerprint (64 hex digits), got %q", fp)
+	}
+	return fp, nil
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// parse loads a config from YAML source.
+func parse(t *testing.T, src string) (*Config, error) {
+	t.Helper()
+	return LoadConfigReader(strings.NewReader(src))
+}
+
+// secretFile writes content to a file in a temporary directory and returns
+// its path.
+func secretFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
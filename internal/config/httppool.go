@@ -0,0 +1,43 @@
+package config
+
+import "fmt"
+
+// HTTPPoolConfig makes HTTP checks keep connections open and reuse them
+// across checks, instead of connecting afresh every time. That saves
+// handshakes when many monitors share hosts, but checks on a reused
+// connection no longer measure DNS, connect and TLS time.
+type HTTPPoolConfig struct {
+	// Idle connections kept in total, default 100
+	MaxIdleConns int `yaml:"max_idle_conns,omitempty"`
+	// Idle connections kept per host, default 10 (net/http's is 2)
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host,omitempty"`
+	// How long an idle connection is kept, default 90s
+	IdleConnTimeout string `yaml:"idle_conn_timeout,omitempty"`
+}
+
+// Defaults for HTTPPoolConfig.
+const (
+	DefaultMaxIdleConns        = 100
+	DefaultMaxIdleConnsPerHost = 10
+	DefaultIdleConnTimeout     = "90s"
+)
+
+// resolve validates the settings and fills in their defaults.
+func (p *HTTPPoolConfig) resolve() error {
+	if p.MaxIdleConns < 0 || p.MaxIdleConnsPerHost < 0 {
+		return fmt.Errorf("max_idle_conns and max_idle_conns_per_host must not be negative")
+	}
+	if p.MaxIdleConns == 0 {
+		p.MaxIdleConns = DefaultMaxIdleConns
+	}
+	if p.MaxIdleConnsPerHost == 0 {
+		p.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	}
+	if p.IdleConnTimeout == "" {
+		p.IdleConnTimeout = DefaultIdleConnTimeout
+	}
+	if err := parseInterval(p.IdleConnTimeout); err != nil {
+		return fmt.Errorf("idle_conn_timeout: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// detachMonitors takes the entries of the top-level monitors list out of a
+// parsed document, leaving the rest to decode as usual. A monitors value
+// that isn't a list stays put for the decoder to complain about.
+func detachMonitors(root *yaml.Node) []*yaml.Node {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return nil
+	}
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		key, val := doc.Content[i], doc.Content[i+1]
+		if key.Value != "monitors" || val.Kind != yaml.SequenceNode {
+			continue
+		}
+		doc.Content = slices.Delete(doc.Content, i, i+2)
+		return val.Content
+	}
+	return nil
+}
+
+// skipBrokenReferences drops the monitors that are invalid only in
+// relation to others, e.g. an aggregate over a skipped child, then takes
+// the names of monitors that weren't loaded out of views. Dropping one
+// monitor can break another, so it repeats until nothing changes.
+func (cfg *Config) skipBrokenReferences() {
+	for {
+		types := monitorTypes(cfg.Monitors)
+		kept := cfg.Monitors[:0]
+		for _, m := range cfg.Monitors {
+			err := validateAggregate(&m, types)
+			if err == nil {
+				err = validateDependsOn(m, types)
+			}
+			if err != nil {
+				cfg.Skipped = append(cfg.Skipped, err)
+				continue
+			}
+			kept = append(kept, m)
+		}
+		done := len(kept) == len(cfg.Monitors)
+		cfg.Monitors = kept
+		if done {
+			break
+		}
+	}
+
+	known := monitorTypes(cfg.Monitors)
+	for i := range cfg.Views {
+		v := &cfg.Views[i]
+		v.Monitors = slices.DeleteFunc(v.Monitors, func(name string) bool {
+			_, ok := known[name]
+			return !ok
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const mixedMonitors = `
+monitors:
+  - {name: web, type: tcp, host: 127.0.0.1, port: 80}
+  - {name: typo, type: tcp, host: 127.0.0.1, port: 80, interval: 5x}
+  - {name: garbled, type: tcp, host: 127.0.0.1, port: eighty}
+  - {name: site, children: [web, typo]}
+  - {name: api, type: tcp, host: 127.0.0.1, port: 81, depends_on: [site]}
+  - {name: db, type: tcp, host: 127.0.0.1, port: 5432}
+views:
+  - {name: ops, monitors: [web, typo, db]}
+`
+
+func TestConfigMode(t *testing.T) {
+	tests := []struct {
+		mode        string
+		wantErr     string
+		wantLoaded  []string
+		wantSkipped []string // monitors named in the skip errors, in order
+	}{
+		{mode: "", wantErr: `monitor "typo": interval`},
+		{mode: "strict", wantErr: `monitor "typo": interval`},
+		{
+			mode:        "lenient",
+			wantLoaded:  []string{"web", "db"},
+			wantSkipped: []string{"typo", "garbled", "site", "api"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			src := mixedMonitors
+			if tt.mode != "" {
+				src = fmt.Sprintf("global: {config_mode: %s}\n%s", tt.mode, src)
+			}
+			cfg, err := parse(t, src)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var loaded []string
+			for _, m := range cfg.Monitors {
+				loaded = append(loaded, m.Name)
+			}
+			if !slices.Equal(loaded, tt.wantLoaded) {
+				t.Errorf("loaded %v, want %v", loaded, tt.wantLoaded)
+			}
+			if len(cfg.Skipped) != len(tt.wantSkipped) {
+				t.Fatalf("skipped %v, want %d monitors", cfg.Skipped, len(tt.wantSkipped))
+			}
+			for i, name := range tt.wantSkipped {
+				if !strings.Contains(cfg.Skipped[i].Error(), name) {
+					t.Errorf("skip error %d is %q, want it about %s", i, cfg.Skipped[i], name)
+				}
+			}
+			if got := cfg.View("ops").Monitors; !slices.Equal(got, []string{"web", "db"}) {
+				t.Errorf("view lists %v, want the loaded monitors only", got)
+			}
+		})
+	}
+
+	if _, err := parse(t, "global: {config_mode: loose}\n"); err == nil {
+		t.Errorf("unknown config_mode loaded")
+	}
+}
@@ -1,10 +1,23 @@
 package config
 
 import (
+	"cmp"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
 	"time"
 
+	"github.com/pronzzz/zenmonitor/internal/cron"
+	"github.com/pronzzz/zenmonitor/internal/expr"
 	"gopkg.in/yaml.v3"
 )
 
@@ -13,84 +26,1037 @@ type Config struct {
 	Global        GlobalConfig         `yaml:"global"`
 	Notifications []NotificationConfig `yaml:"notifications"`
 	Monitors      []MonitorConfig      `yaml:"monitors"`
+	// Extra dashboard pages with a subset of the monitors
+	Views []ViewConfig `yaml:"views,omitempty"`
+
+	// Why each monitor left out by config_mode: lenient was skipped
+	Skipped []error `yaml:"-"`
 }
 
 type GlobalConfig struct {
 	CheckInterval string `yaml:"check_interval"`
 	HistoryDays   int    `yaml:"history_days"`
+	UserAgent     string `yaml:"user_agent,omitempty"` // Default for all HTTP checks
+	// Default resolver for HTTP, TCP and SMTP checks, see
+	// MonitorConfig.Resolver
+	Resolver string `yaml:"resolver,omitempty"`
+	// How often the dashboard refreshes itself. "0" disables auto-refresh.
+	DashboardRefresh string `yaml:"dashboard_refresh,omitempty"`
+	// Order of monitors on the dashboard: config (default), name, status
+	// (down first) or uptime (lowest first). ?sort= overrides it per request.
+	DashboardSort string `yaml:"dashboard_sort,omitempty" schema:"enum=config|name|status|uptime"`
+	// Public status page: the dashboard shows only names, status and
+	// uptime (no targets or error messages), and the API endpoints that
+	// expose internals or change state are not served.
+	Public bool `yaml:"public,omitempty"`
+	// IANA timezone alerts and the dashboard show times in and cron
+	// expressions are read in, e.g. "Europe/Berlin" or "Local" for the
+	// server's. Defaults to UTC.
+	Timezone string `yaml:"timezone,omitempty"`
+	// How alerts and the dashboard format times: rfc3339 (default),
+	// rfc1123, datetime, kitchen or a Go layout like "Jan 02 15:04 MST"
+	TimestampFormat string `yaml:"timestamp_format,omitempty"`
+	// Encode status by shape and symbol as well as color (✓/✗, round/square
+	// dots) for colorblind users.
+	AccessibleStatus bool `yaml:"accessible_status,omitempty"`
+	// Probe every monitor right away on startup (default true). Set to false
+	// to wait one full interval, e.g. to avoid a stampede at boot.
+	CheckOnStart *bool `yaml:"check_on_start,omitempty"`
+	// Probe monitors a config reload added or changed right away (default
+	// true), rather than after their interval or check_on_start.
+	CheckOnReload *bool `yaml:"check_on_reload,omitempty"`
+	// Check every monitor once before serving: "report" logs a summary,
+	// "strict" also refuses to start when every check errors out (which
+	// usually means a config or network problem). Off when empty.
+	StartupCheck string `yaml:"startup_check,omitempty" schema:"enum=report|strict"`
+	// How LoadConfig treats an invalid monitor: "strict" (default) fails
+	// the whole load, "lenient" skips that monitor and loads the rest so
+	// one typo doesn't stop all monitoring. Keep CI on strict.
+	ConfigMode string `yaml:"config_mode,omitempty" schema:"enum=strict|lenient"`
+	// Address for the web server, e.g. "127.0.0.1:8080" to only serve
+	// behind a local reverse proxy. Defaults to all interfaces on $PORT.
+	Listen string `yaml:"listen,omitempty"`
+	// Serve the dashboard over HTTPS when configured
+	TLS TLSConfig `yaml:"tls,omitempty"`
+	// SQLite file handling
+	Database DatabaseConfig `yaml:"database,omitempty"`
+	// Upper bounds (seconds) of the check latency histogram buckets on /metrics
+	LatencyBuckets []float64 `yaml:"latency_buckets,omitempty"`
+	// Max checks per second against any one host (0 = unlimited), so many
+	// monitors on the same host don't trip its rate limits. Bursts of up
+	// to host_rate_burst checks (default 1) are let through at once.
+	HostRateLimit float64 `yaml:"host_rate_limit,omitempty"`
+	HostRateBurst int     `yaml:"host_rate_burst,omitempty"`
+	// Reuse HTTP connections across checks, see HTTPPoolConfig. Off (a
+	// fresh connection per check) when unset.
+	HTTPPool *HTTPPoolConfig `yaml:"http_pool,omitempty"`
+	// Default store_every for monitors
+	StoreEvery int `yaml:"store_every,omitempty"`
+	// Allow exec monitors, which run arbitrary commands on this host. Off
+	// by default so a config from elsewhere can't run anything.
+	AllowExec bool `yaml:"allow_exec,omitempty"`
+	// Alert when a monitor hasn't produced a result for this many of its
+	// intervals (hung check, push agent gone). 0 disables the watchdog.
+	StaleAfter int `yaml:"stale_after,omitempty"`
+	// UP <-> DOWN changes are counted over this window (default 1h) for
+	// the flap count on the dashboard and in metrics. flap_threshold
+	// changes within it alert that a monitor is flapping, 0 (default)
+	// doesn't. Monitors can override the threshold.
+	FlapWindow    string `yaml:"flap_window,omitempty"`
+	FlapThreshold int    `yaml:"flap_threshold,omitempty"`
+	// Alerts sent concurrently per channel (default 4); alerts for one
+	// monitor are always sent in order
+	NotifyWorkers int `yaml:"notify_workers,omitempty"`
+	// How long shutdown waits for open requests, checks in flight and
+	// queued alerts, all together (default 5s). The SHUTDOWN_TIMEOUT
+	// environment variable overrides it.
+	ShutdownTimeout string `yaml:"shutdown_timeout,omitempty"`
+	// Minimum time between DOWN alerts of a monitor, e.g. "15m": a monitor
+	// failing again within it doesn't alert, nor does that outage's
+	// recovery. Off when empty; monitors can override it.
+	NotificationCooldown string `yaml:"notification_cooldown,omitempty"`
+	// Default cap on HTTP response bodies; a check reading more fails
+	MaxResponseBytes int64 `yaml:"max_response_bytes,omitempty"`
+	// Shared secret remote agents sign pushed results with (HMAC-SHA256).
+	// The ingest endpoint is disabled while empty. INGEST_SECRET overrides.
+	IngestSecret     string `yaml:"ingest_secret,omitempty"`
+	IngestSecretFile string `yaml:"ingest_secret_file,omitempty"`
+	// Bearer token for admin endpoints such as POST /api/reload, which are
+	// disabled while empty. ADMIN_TOKEN overrides.
+	AdminToken string `yaml:"admin_token,omitempty"`
+}
+
+// DashboardSorts are the valid dashboard_sort values.
+var DashboardSorts = []string{"config", "name", "status", "uptime"}
+
+// Defaults for the HTTP check guards.
+const (
+	DefaultMaxRedirects     = 10 // same as net/http
+	DefaultMaxResponseBytes = 10 << 20
+)
+
+// Location returns the configured display timezone, UTC if unset.
+func (g GlobalConfig) Location() (*time.Location, error) {
+	return time.LoadLocation(g.Timezone)
+}
+
+// DefaultLatencyBuckets match the Prometheus client defaults.
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type DatabaseConfig struct {
+	// wal (default), delete, truncate or persist. Use delete on network
+	// filesystems where WAL's shared memory doesn't work.
+	JournalMode string `yaml:"journal_mode,omitempty" schema:"enum=wal|delete|truncate|persist"`
+	// How often to checkpoint the WAL file, e.g. "5m". Empty leaves it to SQLite.
+	CheckpointInterval string `yaml:"checkpoint_interval,omitempty"`
+	// How often to prune old history and VACUUM the file to give the space
+	// back, e.g. "24h". Empty prunes once at startup and never vacuums.
+	VacuumInterval string `yaml:"vacuum_interval,omitempty"`
+}
+
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file,omitempty"`
+	KeyFile  string `yaml:"key_file,omitempty"`
+	// Directory holding tls.crt/tls.key (Kubernetes secret layout) or
+	// cert.pem/key.pem, used when cert_file/key_file aren't given.
+	CertDir string `yaml:"cert_dir,omitempty"`
+	// Optional plain HTTP address (e.g. ":80") that redirects to HTTPS
+	RedirectHTTP string `yaml:"redirect_http,omitempty"`
+}
+
+// Enabled reports whether the web server should use TLS.
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// resolve fills CertFile/KeyFile from CertDir and checks the pair is complete.
+func (t *TLSConfig) resolve() error {
+	if t.CertDir != "" && t.CertFile == "" && t.KeyFile == "" {
+		pairs := [][2]string{{"tls.crt", "tls.key"}, {"cert.pem", "key.pem"}}
+		for _, p := range pairs {
+			cert, key := filepath.Join(t.CertDir, p[0]), filepath.Join(t.CertDir, p[1])
+			if fileExists(cert) && fileExists(key) {
+				t.CertFile, t.KeyFile = cert, key
+				break
+			}
+		}
+		if !t.Enabled() {
+			return fmt.Errorf("no tls.crt/tls.key or cert.pem/key.pem found in %s", t.CertDir)
+		}
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("cert_file and key_file must be set together")
+	}
+	if t.RedirectHTTP != "" && !t.Enabled() {
+		return fmt.Errorf("redirect_http requires a certificate")
+	}
+	return nil
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
 }
 
 type NotificationConfig struct {
-	Type       string `yaml:"type"`
+	Type       string `yaml:"type" schema:"required,enum=telegram|slack|opsgenie"`
 	Token      string `yaml:"token,omitempty"`
 	ChatID     string `yaml:"chat_id,omitempty"`
 	WebhookURL string `yaml:"webhook_url,omitempty"`
-	
-	// Internal parsed fields?
+	APIKey     string `yaml:"api_key,omitempty"` // opsgenie
+	APIURL     string `yaml:"api_url,omitempty"` // opsgenie, e.g. https://api.eu.opsgenie.com
+	// slack: send a Block Kit layout (header, colored details) instead of plain text
+	SlackBlocks bool `yaml:"slack_blocks,omitempty"`
+
+	// Read secrets from mounted files (Docker/K8s secrets) instead of inline.
+	// Setting both the inline value and its _file variant is an error.
+	TokenFile      string `yaml:"token_file,omitempty"`
+	ChatIDFile     string `yaml:"chat_id_file,omitempty"`
+	WebhookURLFile string `yaml:"webhook_url_file,omitempty"`
+	APIKeyFile     string `yaml:"api_key_file,omitempty"`
+
+	// Optional text/template for the alert message, see notifier.MessageData
+	// for the available fields. Empty keeps the default message.
+	MessageTemplate string `yaml:"message_template,omitempty"`
+
+	// Throttle sends to this channel so bursts of alerts don't get the
+	// bot or webhook rate limited: at most rate_limit messages per second
+	// (bursts of rate_burst, default 1) and max_concurrent at a time.
+	// 0 means no limit.
+	RateLimit     float64 `yaml:"rate_limit,omitempty"`
+	RateBurst     int     `yaml:"rate_burst,omitempty"`
+	MaxConcurrent int     `yaml:"max_concurrent,omitempty"`
+
+	// Only send these events to this channel, e.g. [down] for a pager
+	// that resolves incidents on its own while chat also gets [down, up].
+	// Empty sends everything. See NotificationEvents.
+	Events []string `yaml:"events,omitempty" schema:"enum=down|up|stale|slo_burn|slo_ok|flapping|flap_ok"`
 }
 
+// NotificationEvents are the values of NotificationConfig.Events: a
+// monitor going down, recovering, going stale (push monitors), an error
+// budget starting or stopping to burn, and a monitor starting or stopping
+// to flap.
+var NotificationEvents = []string{"down", "up", "stale", "slo_burn", "slo_ok", "flapping", "flap_ok"}
+
 type MonitorConfig struct {
-	Name         string `yaml:"name"`
-	Type         string `yaml:"type"` // http, tcp, icmp
+	Name         string `yaml:"name" schema:"required"`
+	Type         string `yaml:"type" schema:"enum=http|tcp|smtp|icmp|exec|aggregate|push"` // inferred when empty
 	URL          string `yaml:"url,omitempty"`
 	Host         string `yaml:"host,omitempty"`
 	Port         int    `yaml:"port,omitempty"`
 	Method       string `yaml:"method,omitempty"` // GET, POST
 	ExpectStatus int    `yaml:"expect_status,omitempty"`
-	Interval     string `yaml:"interval,omitempty"` // Override global
+	Interval     string `yaml:"interval,omitempty"`       // Override global
+	DownInterval string `yaml:"down_interval,omitempty"`  // Interval while DOWN, defaults to interval
+	Cron         string `yaml:"cron,omitempty"`           // e.g. "0 3 * * *" in global.timezone, mutually exclusive with interval
+	CheckOnStart *bool  `yaml:"check_on_start,omitempty"` // Override global check_on_start
+	UserAgent    string `yaml:"user_agent,omitempty"`     // Override global user_agent
+
+	// Status codes that mark the monitor DOWN, e.g. [200] for an endpoint
+	// that must stay behind auth. expect_status isn't defaulted when set.
+	ExpectNotStatus []int `yaml:"expect_not_status,omitempty"`
+	// Maps status codes or ranges ("401", "200-299") to up, degraded or
+	// down. Replaces expect_status/expect_not_status when set.
+	StatusMap map[string]string `yaml:"status_map,omitempty"`
+	// Response body size bounds in bytes (0 = no bound). Catches blank or
+	// truncated pages that still return 200.
+	MinBodySize int64 `yaml:"min_body_size,omitempty"`
+	MaxBodySize int64 `yaml:"max_body_size,omitempty"`
+	// Response headers that must be present. An empty value only checks
+	// presence, anything else must match one of the header's values exactly.
+	ExpectHeaders map[string]string `yaml:"expect_headers,omitempty"`
+	// Content-Type the response must have, e.g. "application/json".
+	// Prefix match, parameters such as charset are ignored.
+	ExpectContentType string `yaml:"expect_content_type,omitempty"`
+	// Substrings the (decoded) response body must contain. expect_body is
+	// shorthand for a single expect_body_all entry.
+	ExpectBody    string   `yaml:"expect_body,omitempty"`
+	ExpectBodyAll []string `yaml:"expect_body_all,omitempty"` // every one must appear
+	ExpectBodyAny []string `yaml:"expect_body_any,omitempty"` // at least one must appear
+	// Regular expression (Go syntax) the body must match
+	ExpectBodyRegex string `yaml:"expect_body_regex,omitempty"`
+	// URL the check must end up at after following redirects, e.g. a login
+	// page. expect_final_url must match exactly, the _contains variant is a
+	// substring match.
+	ExpectFinalURL         string `yaml:"expect_final_url,omitempty"`
+	ExpectFinalURLContains string `yaml:"expect_final_url_contains,omitempty"`
+	// Condition the response must meet, combining the assertions above in
+	// one boolean expression, e.g.
+	// status == 200 && latency < 500ms && body contains "ok". See expr.Expr
+	// for the syntax. expect_status isn't defaulted when set.
+	ExpectExpr string `yaml:"expect_expr,omitempty"`
+	// TCP checks: data written after connecting (e.g. a PROXY protocol
+	// header), and tcp_mode "connect" (default, connect then close) or
+	// "read" (wait for a response, which must contain expect_data if set).
+	SendData   string `yaml:"send_data,omitempty"`
+	TCPMode    string `yaml:"tcp_mode,omitempty" schema:"enum=connect|read"`
+	ExpectData string `yaml:"expect_data,omitempty"`
+	// Dial this unix socket instead of the URL's host, e.g. url: http://unix/health
+	SocketPath string `yaml:"socket_path,omitempty"`
+	// DNS server used to resolve this monitor's host ("10.0.0.53[:53]")
+	// instead of the system resolver. Handy for split-horizon DNS. An
+	// https:// URL is a DNS-over-HTTPS endpoint
+	// (https://1.1.1.1/dns-query). Override global resolver.
+	Resolver string `yaml:"resolver,omitempty"`
+	// Local address TCP/HTTP checks connect from, for multi-homed hosts
+	SourceIP string `yaml:"source_ip,omitempty"`
+	// Retry HTTP requests that fail without a response (connection refused
+	// or reset, DNS blip) this many times before the check counts as failed.
+	// Timeouts and TLS errors aren't retried. Only for idempotent methods.
+	// Backoff starts at retry_delay (default 1s) and doubles, up to 30s.
+	// All attempts together stay within the check's 10s timeout.
+	Retries    int    `yaml:"retries,omitempty"`
+	RetryDelay string `yaml:"retry_delay,omitempty"`
+	// Guards against broken or hostile endpoints: redirects followed before
+	// giving up (default 10) and body bytes read (default global value).
+	MaxRedirects     int   `yaml:"max_redirects,omitempty"`
+	MaxResponseBytes int64 `yaml:"max_response_bytes,omitempty"`
+	// exec monitors: the program and its arguments (no shell), run with
+	// a timeout (default 30s). Exit code 0 is UP. Requires global.allow_exec.
+	Command     []string `yaml:"command,omitempty"`
+	ExecTimeout string   `yaml:"exec_timeout,omitempty"`
+	// TLS versions an HTTPS check accepts, "1.0" to "1.3". A server that
+	// can't negotiate within the range (e.g. one downgraded to TLS 1.1
+	// with min_tls_version: "1.2") marks the monitor DOWN.
+	MinTLSVersion string `yaml:"min_tls_version,omitempty" schema:"enum=1.0|1.1|1.2|1.3"`
+	MaxTLSVersion string `yaml:"max_tls_version,omitempty" schema:"enum=1.0|1.1|1.2|1.3"`
+	// SHA-256 fingerprint (hex, colons optional) the server's leaf
+	// certificate must have. Any other certificate, e.g. one presented by
+	// an intercepting proxy, marks the monitor DOWN.
+	ExpectCertFingerprint string `yaml:"expect_cert_fingerprint,omitempty"`
+	// Login request made before each HTTP check, whose token is added to
+	// the check's request. See AuthConfig.
+	Auth *AuthConfig `yaml:"auth,omitempty"`
+
+	// Aggregate monitors don't probe anything themselves, their status is
+	// derived from the named child monitors.
+	Children    []string `yaml:"children,omitempty"`
+	Aggregation string   `yaml:"aggregation,omitempty" schema:"enum=all|any|quorum"` // default all
+	Quorum      int      `yaml:"quorum,omitempty"`                                   // children that must be up in quorum mode
+
+	// Free-form metadata (team, severity, runbook) available to alert
+	// templates as {{.Labels.runbook}} and exported on /metrics through
+	// zenmonitor_monitor_info. Keys must be valid Prometheus label names.
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	// Store only every Nth check while the monitor is steadily UP, to keep
+	// the database small. Failures and transitions are always stored.
+	// Overrides global.store_every; 0 or 1 stores every check.
+	StoreEvery int `yaml:"store_every,omitempty"`
+	// Override global.flap_threshold
+	FlapThreshold int `yaml:"flap_threshold,omitempty"`
+	// Override global.notification_cooldown, "0" for none
+	NotificationCooldown string `yaml:"notification_cooldown,omitempty"`
+
+	// Days of check history to keep for this monitor, overriding
+	// global.history_days (longer for critical monitors, shorter for noisy ones)
+	RetentionDays int `yaml:"retention_days,omitempty"`
+
+	// The target must NOT be reachable, e.g. an admin port from outside:
+	// a successful check counts as DOWN and a failed one as UP. For tcp
+	// monitors it's a connect-only check that the port is closed, for
+	// verifying firewall rules.
+	Inverted bool `yaml:"inverted,omitempty"`
+
+	// Uptime objective with error budget burn alerts, see SLOConfig
+	SLO *SLOConfig `yaml:"slo,omitempty"`
+
+	// Flag checks much slower than the monitor's recent history, see
+	// LatencyAnomalyConfig
+	LatencyAnomaly *LatencyAnomalyConfig `yaml:"latency_anomaly,omitempty"`
+
+	// Monitors this one sits behind (e.g. a gateway). While any of them is
+	// DOWN, this monitor's alerts are suppressed to avoid alert storms.
+	DependsOn []string `yaml:"depends_on,omitempty"`
+
+	// How much this monitor counts towards the health score (default 1,
+	// 0 leaves it out), see HealthWeight
+	Weight *float64 `yaml:"weight,omitempty"`
+}
+
+// HealthWeight is the monitor's weight in the health score: weight, or 1
+// when unset.
+func (m MonitorConfig) HealthWeight() float64 {
+	if m.Weight == nil {
+		return 1
+	}
+	return *m.Weight
 }
 
-// LoadConfig reads and parses the YAML config
+// labelNameRe matches valid Prometheus label names.
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// LoadConfig reads and parses the YAML config. path is a file, "-" for
+// stdin, or an http(s):// URL to fetch it from.
 func LoadConfig(path string) (*Config, error) {
+	data, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(data)
+}
+
+// readConfig returns the raw config from a file, stdin ("-") or a URL.
+func readConfig(path string) ([]byte, error) {
+	switch {
+	case path == "-":
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+		return data, nil
+	case strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://"):
+		data, err := fetchConfig(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch config: %w", err)
+		}
+		return data, nil
+	}
+
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
+	return data, nil
+}
+
+// LoadConfigReader parses a YAML config from r.
+func LoadConfigReader(r io.Reader) (*Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return parseConfig(data)
+}
+
+// fetchTimeout bounds fetching the config from a URL.
+const fetchTimeout = 10 * time.Second
+
+// fetchConfig downloads a config from a config service.
+func fetchConfig(url string) ([]byte, error) {
+	client := &http.Client{Timeout: fetchTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
 
+// parseConfig parses YAML, applies defaults and validates.
+func parseConfig(data []byte) (*Config, error) {
 	var cfg Config
 	// Set defaults before unmarshaling?
 	// Zero values might be tricky, but let's parse first
-	
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
 		return nil, fmt.Errorf("failed to parse yaml: %w", err)
 	}
+	// Monitors are decoded one by one below, so lenient mode can skip a
+	// malformed entry without losing the rest
+	monitors := detachMonitors(&root)
+	if !root.IsZero() {
+		if err := root.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse yaml: %w", err)
+		}
+	}
+	switch cfg.Global.ConfigMode {
+	case "", "strict", "lenient":
+	default:
+		return nil, fmt.Errorf("global.config_mode: unknown mode %q (want strict or lenient)", cfg.Global.ConfigMode)
+	}
 
 	// Validate / Set Defaults
 	if cfg.Global.CheckInterval == "" {
 		cfg.Global.CheckInterval = "60s"
 	}
+	if err := parseInterval(cfg.Global.CheckInterval); err != nil {
+		return nil, fmt.Errorf("global.check_interval: %w", err)
+	}
 	if cfg.Global.HistoryDays == 0 {
 		cfg.Global.HistoryDays = 90
 	}
+	if _, err := cfg.Global.Location(); err != nil {
+		return nil, fmt.Errorf("global.timezone: %w", err)
+	}
+	if _, err := ParseTimeLayout(cfg.Global.TimestampFormat); err != nil {
+		return nil, fmt.Errorf("global.timestamp_format: %w", err)
+	}
+	switch cfg.Global.StartupCheck {
+	case "", "report", "strict":
+	default:
+		return nil, fmt.Errorf("global.startup_check: unknown mode %q (want report or strict)", cfg.Global.StartupCheck)
+	}
+	switch strings.ToLower(cfg.Global.Database.JournalMode) {
+	case "", "wal", "delete", "truncate", "persist":
+	default:
+		return nil, fmt.Errorf("global.database.journal_mode: unsupported mode %q", cfg.Global.Database.JournalMode)
+	}
+	if ci := cfg.Global.Database.CheckpointInterval; ci != "" {
+		if _, err := parseDuration(ci); err != nil {
+			return nil, fmt.Errorf("global.database.checkpoint_interval: %w", err)
+		}
+	}
+	if cfg.Global.HTTPPool != nil {
+		if err := cfg.Global.HTTPPool.resolve(); err != nil {
+			return nil, fmt.Errorf("global.http_pool: %w", err)
+		}
+	}
+	if vi := cfg.Global.Database.VacuumInterval; vi != "" {
+		if err := parseInterval(vi); err != nil {
+			return nil, fmt.Errorf("global.database.vacuum_interval: %w", err)
+		}
+	}
+	if cfg.Global.StoreEvery < 0 {
+		return nil, fmt.Errorf("global.store_every must not be negative")
+	}
+	if cfg.Global.StaleAfter < 0 {
+		return nil, fmt.Errorf("global.stale_after must not be negative")
+	}
+	if cfg.Global.ShutdownTimeout == "" {
+		cfg.Global.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if _, err := ParsePositiveDuration(cfg.Global.ShutdownTimeout); err != nil {
+		return nil, fmt.Errorf("global.shutdown_timeout: %w", err)
+	}
+	if cfg.Global.FlapWindow == "" {
+		cfg.Global.FlapWindow = DefaultFlapWindow
+	}
+	if fw, err := parseDuration(cfg.Global.FlapWindow); err != nil || fw <= 0 {
+		return nil, fmt.Errorf("global.flap_window: invalid duration %q", cfg.Global.FlapWindow)
+	}
+	if err := validateFlapThreshold(cfg.Global.FlapThreshold); err != nil {
+		return nil, fmt.Errorf("global.flap_threshold: %w", err)
+	}
+	if nc := cfg.Global.NotificationCooldown; nc != "" {
+		if d, err := parseDuration(nc); err != nil || d < 0 {
+			return nil, fmt.Errorf("global.notification_cooldown: invalid duration %q", nc)
+		}
+	}
+	if cfg.Global.HostRateLimit < 0 {
+		return nil, fmt.Errorf("global.host_rate_limit must not be negative")
+	}
+	if cfg.Global.MaxResponseBytes == 0 {
+		cfg.Global.MaxResponseBytes = DefaultMaxResponseBytes
+	}
+	if cfg.Global.DashboardRefresh == "" {
+		cfg.Global.DashboardRefresh = "30s"
+	}
+	if refresh, err := parseDuration(cfg.Global.DashboardRefresh); err != nil || refresh < 0 {
+		return nil, fmt.Errorf("global.dashboard_refresh: invalid duration %q", cfg.Global.DashboardRefresh)
+	}
+	if cfg.Global.DashboardSort == "" {
+		cfg.Global.DashboardSort = "config"
+	}
+	if !slices.Contains(DashboardSorts, cfg.Global.DashboardSort) {
+		return nil, fmt.Errorf("global.dashboard_sort: unknown order %q (want %s)", cfg.Global.DashboardSort, strings.Join(DashboardSorts, ", "))
+	}
+	if err := cfg.Global.TLS.resolve(); err != nil {
+		return nil, fmt.Errorf("global.tls: %w", err)
+	}
+	if len(cfg.Global.LatencyBuckets) == 0 {
+		cfg.Global.LatencyBuckets = DefaultLatencyBuckets
+	}
+	for i := 1; i < len(cfg.Global.LatencyBuckets); i++ {
+		if cfg.Global.LatencyBuckets[i] <= cfg.Global.LatencyBuckets[i-1] {
+			return nil, fmt.Errorf("global.latency_buckets must be strictly ascending")
+		}
+	}
+	if cfg.Global.IngestSecretFile != "" {
+		if cfg.Global.IngestSecret != "" {
+			return nil, fmt.Errorf("global: both ingest_secret and ingest_secret_file are set")
+		}
+		secret, err := readSecretFile(cfg.Global.IngestSecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("global.ingest_secret_file: %w", err)
+		}
+		cfg.Global.IngestSecret = secret
+	}
+	if cfg.Global.CheckOnStart == nil {
+		checkOnStart := true
+		cfg.Global.CheckOnStart = &checkOnStart
+	}
+	if cfg.Global.CheckOnReload == nil {
+		checkOnReload := true
+		cfg.Global.CheckOnReload = &checkOnReload
+	}
 
-	for i := range cfg.Monitors {
-		m := &cfg.Monitors[i]
-		if m.Type == "" {
-			// Infer type
-			if m.URL != "" {
-				m.Type = "http"
-			} else if m.Host != "" && m.Port != 0 {
-				m.Type = "tcp"
-			} else if m.Host != "" {
-				m.Type = "icmp"
+	for i := range cfg.Notifications {
+		n := &cfg.Notifications[i]
+		if err := n.loadSecretFiles(); err != nil {
+			return nil, fmt.Errorf("notification %d (%s): %w", i, n.Type, err)
+		}
+		if n.RateLimit < 0 || n.RateBurst < 0 || n.MaxConcurrent < 0 {
+			return nil, fmt.Errorf("notification %d (%s): rate_limit, rate_burst and max_concurrent must not be negative", i, n.Type)
+		}
+		for _, ev := range n.Events {
+			if !slices.Contains(NotificationEvents, ev) {
+				return nil, fmt.Errorf("notification %d (%s): unknown event %q (want %s)", i, n.Type, ev, strings.Join(NotificationEvents, ", "))
+			}
+		}
+		if n.MessageTemplate != "" {
+			if _, err := template.New(n.Type).Parse(n.MessageTemplate); err != nil {
+				return nil, fmt.Errorf("notification %d (%s): invalid message_template: %w", i, n.Type, err)
 			}
 		}
-		if m.Method == "" {
-			m.Method = "GET"
+	}
+
+	strict := cfg.Global.ConfigMode != "lenient"
+	for i, node := range monitors {
+		var m MonitorConfig
+		err := node.Decode(&m)
+		if err != nil {
+			err = fmt.Errorf("monitor %d (%s): %w", i, m.Name, err)
+		} else {
+			err = cfg.resolveMonitor(&m)
 		}
-		if m.ExpectStatus == 0 {
-			m.ExpectStatus = 200
+		if err != nil {
+			if strict {
+				return nil, err
+			}
+			cfg.Skipped = append(cfg.Skipped, err)
+			continue
 		}
+		cfg.Monitors = append(cfg.Monitors, m)
+	}
+
+	if !strict {
+		cfg.skipBrokenReferences()
+	}
+	if err := validateAggregates(cfg.Monitors); err != nil {
+		return nil, err
+	}
+	if err := validateDependencies(cfg.Monitors); err != nil {
+		return nil, err
+	}
+	if err := validateViews(cfg.Views, cfg.Monitors); err != nil {
+		return nil, err
 	}
 
 	return &cfg, nil
 }
 
-// Helper to parse duration string
+// resolveMonitor applies defaults to a monitor and validates it on its own,
+// see validateAggregates and validateDependencies for the checks across
+// monitors.
+func (cfg *Config) resolveMonitor(m *MonitorConfig) error {
+	if m.Type == "" {
+		// Infer type
+		if len(m.Children) > 0 {
+			m.Type = "aggregate"
+		} else if m.URL != "" {
+			m.Type = "http"
+		} else if m.Host != "" && m.Port != 0 {
+			m.Type = "tcp"
+		} else if m.Host != "" {
+			m.Type = "icmp"
+		}
+	}
+	if m.Method == "" {
+		m.Method = "GET"
+	}
+	if m.Type == "smtp" && m.Port == 0 {
+		m.Port = 25
+	}
+	if err := validateStatusMap(m.StatusMap); err != nil {
+		return fmt.Errorf("monitor %q: %w", m.Name, err)
+	}
+	if m.ExpectStatus == 0 && len(m.ExpectNotStatus) == 0 && len(m.StatusMap) == 0 && m.ExpectExpr == "" {
+		m.ExpectStatus = 200
+	}
+	if m.UserAgent == "" {
+		m.UserAgent = cfg.Global.UserAgent
+	}
+	if m.Resolver == "" {
+		m.Resolver = cfg.Global.Resolver
+	}
+	if err := validateResolver(m.Resolver); err != nil {
+		return fmt.Errorf("monitor %q: resolver: %w", m.Name, err)
+	}
+	if m.MaxRedirects == 0 {
+		m.MaxRedirects = DefaultMaxRedirects
+	}
+	if m.MaxResponseBytes == 0 {
+		m.MaxResponseBytes = cfg.Global.MaxResponseBytes
+	}
+	for key := range m.Labels {
+		if !labelNameRe.MatchString(key) || strings.HasPrefix(key, "__") || key == "monitor" {
+			return fmt.Errorf("monitor %q: invalid label name %q", m.Name, key)
+		}
+	}
+	switch m.TCPMode {
+	case "", "connect":
+		if m.ExpectData != "" {
+			return fmt.Errorf("monitor %q: expect_data requires tcp_mode: read", m.Name)
+		}
+	case "read":
+		if m.Inverted && m.Type == "tcp" {
+			return fmt.Errorf("monitor %q: an inverted tcp monitor only checks that the port is closed, tcp_mode: read doesn't apply", m.Name)
+		}
+	default:
+		return fmt.Errorf("monitor %q: unknown tcp_mode %q (want connect or read)", m.Name, m.TCPMode)
+	}
+	if m.SourceIP != "" && net.ParseIP(m.SourceIP) == nil {
+		return fmt.Errorf("monitor %q: source_ip %q is not an IP address", m.Name, m.SourceIP)
+	}
+	if m.Retries < 0 {
+		return fmt.Errorf("monitor %q: retries must not be negative", m.Name)
+	}
+	if m.Retries > 0 && !slices.Contains([]string{"GET", "HEAD", "OPTIONS"}, strings.ToUpper(m.Method)) {
+		return fmt.Errorf("monitor %q: retries only work with GET, HEAD or OPTIONS requests", m.Name)
+	}
+	if m.RetryDelay != "" {
+		if _, err := parseDuration(m.RetryDelay); err != nil {
+			return fmt.Errorf("monitor %q: retry_delay: %w", m.Name, err)
+		}
+	}
+	if m.Type == "exec" {
+		if !cfg.Global.AllowExec {
+			return fmt.Errorf("monitor %q: exec monitors are disabled, set global.allow_exec to enable them", m.Name)
+		}
+		if len(m.Command) == 0 {
+			return fmt.Errorf("monitor %q: exec monitors need a command", m.Name)
+		}
+	}
+	if m.ExecTimeout != "" {
+		if _, err := parseDuration(m.ExecTimeout); err != nil {
+			return fmt.Errorf("monitor %q: exec_timeout: %w", m.Name, err)
+		}
+	}
+	if m.ExpectCertFingerprint != "" {
+		fp, err := normalizeFingerprint(m.ExpectCertFingerprint)
+		if err != nil {
+			return fmt.Errorf("monitor %q: expect_cert_fingerprint: %w", m.Name, err)
+		}
+		m.ExpectCertFingerprint = fp
+	}
+	minTLS, err := ParseTLSVersion(m.MinTLSVersion)
+	if err != nil {
+		return fmt.Errorf("monitor %q: min_tls_version: %w", m.Name, err)
+	}
+	maxTLS, err := ParseTLSVersion(m.MaxTLSVersion)
+	if err != nil {
+		return fmt.Errorf("monitor %q: max_tls_version: %w", m.Name, err)
+	}
+	if minTLS != 0 && maxTLS != 0 && minTLS > maxTLS {
+		return fmt.Errorf("monitor %q: min_tls_version is above max_tls_version", m.Name)
+	}
+	if m.Auth != nil {
+		if m.Type != "http" {
+			return fmt.Errorf("monitor %q: auth only works with http monitors", m.Name)
+		}
+		if err := m.Auth.resolve(); err != nil {
+			return fmt.Errorf("monitor %q: auth: %w", m.Name, err)
+		}
+	}
+	if m.StoreEvery < 0 {
+		return fmt.Errorf("monitor %q: store_every must not be negative", m.Name)
+	}
+	if m.StoreEvery == 0 {
+		m.StoreEvery = cfg.Global.StoreEvery
+	}
+	if err := validateFlapThreshold(m.FlapThreshold); err != nil {
+		return fmt.Errorf("monitor %q: flap_threshold: %w", m.Name, err)
+	}
+	if m.FlapThreshold == 0 {
+		m.FlapThreshold = cfg.Global.FlapThreshold
+	}
+	if nc := m.NotificationCooldown; nc != "" {
+		if d, err := parseDuration(nc); err != nil || d < 0 {
+			return fmt.Errorf("monitor %q: notification_cooldown: invalid duration %q", m.Name, nc)
+		}
+	}
+	if m.RetentionDays < 0 {
+		return fmt.Errorf("monitor %q: retention_days must not be negative", m.Name)
+	}
+	if m.SLO != nil {
+		retention := cmp.Or(m.RetentionDays, cfg.Global.HistoryDays)
+		if err := m.SLO.resolve(retention); err != nil {
+			return fmt.Errorf("monitor %q: slo: %w", m.Name, err)
+		}
+	}
+	if m.Inverted && (m.Type == "aggregate" || m.Type == "push") {
+		return fmt.Errorf("monitor %q: inverted doesn't apply to %s monitors", m.Name, m.Type)
+	}
+	if m.Inverted && m.LatencyAnomaly != nil {
+		return fmt.Errorf("monitor %q: latency_anomaly doesn't apply to inverted monitors", m.Name)
+	}
+	if m.LatencyAnomaly != nil {
+		if err := m.LatencyAnomaly.resolve(); err != nil {
+			return fmt.Errorf("monitor %q: latency_anomaly: %w", m.Name, err)
+		}
+	}
+	if m.MaxRedirects < 0 || m.MaxResponseBytes < 0 {
+		return fmt.Errorf("monitor %q: max_redirects and max_response_bytes must not be negative", m.Name)
+	}
+	if m.CheckOnStart == nil {
+		m.CheckOnStart = cfg.Global.CheckOnStart
+	}
+	if m.Weight != nil && *m.Weight < 0 {
+		return fmt.Errorf("monitor %q: weight must not be negative", m.Name)
+	}
+	if m.MaxBodySize > 0 && m.MinBodySize > m.MaxBodySize {
+		return fmt.Errorf("monitor %q: min_body_size is larger than max_body_size", m.Name)
+	}
+	if m.ExpectBodyRegex != "" {
+		if _, err := regexp.Compile(m.ExpectBodyRegex); err != nil {
+			return fmt.Errorf("monitor %q: invalid expect_body_regex: %w", m.Name, err)
+		}
+	}
+	if m.ExpectExpr != "" {
+		if m.Type != "http" {
+			return fmt.Errorf("monitor %q: expect_expr only works with http monitors", m.Name)
+		}
+		if _, err := expr.Parse(m.ExpectExpr); err != nil {
+			return fmt.Errorf("monitor %q: expect_expr: %w", m.Name, err)
+		}
+	}
+	if m.Interval != "" {
+		if err := parseInterval(m.Interval); err != nil {
+			return fmt.Errorf("monitor %q: interval: %w", m.Name, err)
+		}
+	}
+	if m.DownInterval != "" {
+		if err := parseInterval(m.DownInterval); err != nil {
+			return fmt.Errorf("monitor %q: down_interval: %w", m.Name, err)
+		}
+	}
+	if m.Cron != "" {
+		if m.Interval != "" || m.DownInterval != "" {
+			return fmt.Errorf("monitor %q: interval/down_interval and cron are mutually exclusive", m.Name)
+		}
+		if _, err := cron.Parse(m.Cron); err != nil {
+			return fmt.Errorf("monitor %q: %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+// DefaultFlapWindow is the flap_window when it isn't set.
+const DefaultFlapWindow = "1h"
+
+// DefaultShutdownTimeout is the shutdown_timeout when it isn't set.
+const DefaultShutdownTimeout = "5s"
+
+// validateFlapThreshold rejects thresholds that can't mean flapping: it
+// takes a change and its reversal.
+func validateFlapThreshold(n int) error {
+	if n < 0 || n == 1 {
+		return fmt.Errorf("must be 0 (no alert) or at least 2")
+	}
+	return nil
+}
+
+// validateResolver checks a resolver is a DNS server address or an https
+// URL of a DNS-over-HTTPS endpoint.
+func validateResolver(r string) error {
+	if !strings.Contains(r, "://") {
+		return nil
+	}
+	u, err := url.Parse(r)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%q is not a DNS server or https:// DNS-over-HTTPS URL", r)
+	}
+	return nil
+}
+
+// validateAggregates checks that aggregate monitors reference existing,
+// non-aggregate children and have a sensible aggregation mode.
+func validateAggregates(monitors []MonitorConfig) error {
+	types := monitorTypes(monitors)
+	for i := range monitors {
+		if err := validateAggregate(&monitors[i], types); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// monitorTypes maps monitor names to their types.
+func monitorTypes(monitors []MonitorConfig) map[string]string {
+	types := make(map[string]string, len(monitors))
+	for _, m := range monitors {
+		types[m.Name] = m.Type
+	}
+	return types
+}
+
+// validateAggregate checks one monitor of validateAggregates, types maps
+// every monitor's name to its type.
+func validateAggregate(m *MonitorConfig, types map[string]string) error {
+	if m.Type != "aggregate" {
+		return nil
+	}
+	if len(m.Children) == 0 {
+		return fmt.Errorf("monitor %q: aggregate needs at least one child", m.Name)
+	}
+	for _, child := range m.Children {
+		t, ok := types[child]
+		switch {
+		case !ok:
+			return fmt.Errorf("monitor %q: unknown child monitor %q", m.Name, child)
+		case t == "aggregate":
+			return fmt.Errorf("monitor %q: child %q is an aggregate, nesting is not supported", m.Name, child)
+		}
+	}
+
+	switch m.Aggregation {
+	case "":
+		m.Aggregation = "all"
+	case "all", "any":
+	case "quorum":
+		if m.Quorum < 1 || m.Quorum > len(m.Children) {
+			return fmt.Errorf("monitor %q: quorum must be between 1 and %d", m.Name, len(m.Children))
+		}
+	default:
+		return fmt.Errorf("monitor %q: unknown aggregation %q (want all, any or quorum)", m.Name, m.Aggregation)
+	}
+	return nil
+}
+
+// loadSecretFiles resolves the *_file fields into their inline counterparts.
+func (n *NotificationConfig) loadSecretFiles() error {
+	secrets := []struct {
+		name   string
+		inline *string
+		path   string
+	}{
+		{"token", &n.Token, n.TokenFile},
+		{"chat_id", &n.ChatID, n.ChatIDFile},
+		{"webhook_url", &n.WebhookURL, n.WebhookURLFile},
+		{"api_key", &n.APIKey, n.APIKeyFile},
+	}
+
+	for _, sec := range secrets {
+		if sec.path == "" {
+			continue
+		}
+		if *sec.inline != "" {
+			return fmt.Errorf("both %s and %s_file are set", sec.name, sec.name)
+		}
+		val, err := readSecretFile(sec.path)
+		if err != nil {
+			return fmt.Errorf("%s_file: %w", sec.name, err)
+		}
+		*sec.inline = val
+	}
+	return nil
+}
+
+// readSecretFile reads a secret, dropping the trailing newline editors and
+// `echo` like to add.
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// validateDependencies checks depends_on only names other, existing monitors.
+func validateDependencies(monitors []MonitorConfig) error {
+	types := monitorTypes(monitors)
+	for _, m := range monitors {
+		if err := validateDependsOn(m, types); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateDependsOn checks one monitor of validateDependencies, types maps
+// every monitor's name to its type.
+func validateDependsOn(m MonitorConfig, types map[string]string) error {
+	for _, dep := range m.DependsOn {
+		if dep == m.Name {
+			return fmt.Errorf("monitor %q: cannot depend on itself", m.Name)
+		}
+		if _, ok := types[dep]; !ok {
+			return fmt.Errorf("monitor %q: unknown dependency %q", m.Name, dep)
+		}
+	}
+	return nil
+}
+
+// RetentionOverrides maps monitor names to their retention_days, for
+// monitors that set one.
+func (c *Config) RetentionOverrides() map[string]int {
+	out := make(map[string]int)
+	for _, m := range c.Monitors {
+		if m.RetentionDays > 0 {
+			out[m.Name] = m.RetentionDays
+		}
+	}
+	return out
+}
+
+// CooldownOverrides maps monitor names to their notification_cooldown,
+// for monitors that set one.
+func (c *Config) CooldownOverrides() map[string]time.Duration {
+	out := make(map[string]time.Duration)
+	for _, m := range c.Monitors {
+		if m.NotificationCooldown != "" {
+			out[m.Name] = ParseDuration(m.NotificationCooldown)
+		}
+	}
+	return out
+}
+
+// ParseDuration parses a duration LoadConfig has already validated, see
+// parseDuration. The 60s fallback for invalid values only matters for
+// configs that bypassed LoadConfig.
 func ParseDuration(d string) time.Duration {
-	dur, err := time.ParseDuration(d)
+	dur, err := parseDuration(d)
 	if err != nil {
 		return 60 * time.Second
 	}
 	return dur
 }
+
+// parseDuration accepts Go durations ("90s", "5m", "1h30m") and bare
+// whole numbers, which are seconds ("30").
+func parseDuration(d string) (time.Duration, error) {
+	d = strings.TrimSpace(d)
+	if secs, err := strconv.Atoi(d); err == nil {
+		return time.Duration(secs) * time.Second, nil
+	}
+	dur, err := time.ParseDuration(d)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q (use e.g. 30s, 5m or 1h)", d)
+	}
+	return dur, nil
+}
+
+// ParsePositiveDuration parses a duration the way the config does, Go
+// durations or bare seconds, and rejects zero and negative ones. For
+// settings that can also come from the environment.
+func ParsePositiveDuration(d string) (time.Duration, error) {
+	dur, err := parseDuration(d)
+	if err != nil {
+		return 0, err
+	}
+	if dur <= 0 {
+		return 0, fmt.Errorf("duration %q must be positive", d)
+	}
+	return dur, nil
+}
+
+// parseInterval is parseDuration for check intervals, which must be positive.
+func parseInterval(d string) error {
+	dur, err := parseDuration(d)
+	if err != nil {
+		return err
+	}
+	if dur <= 0 {
+		return fmt.Errorf("interval %q must be positive", d)
+	}
+	return nil
+}
@@ -0,0 +1,363 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecretFiles(t *testing.T) {
+	token := secretFile(t, "123:abc\n")
+	chatID := secretFile(t, "-1001\r\n")
+
+	tests := []struct {
+		name       string
+		src        string
+		wantToken  string
+		wantChatID string
+		wantErr    string
+	}{
+		{
+			name:       "read from files",
+			src:        "notifications:\n  - {type: telegram, token_file: " + token + ", chat_id_file: " + chatID + "}\n",
+			wantToken:  "123:abc",
+			wantChatID: "-1001",
+		},
+		{
+			name:       "file and inline mixed",
+			src:        "notifications:\n  - {type: telegram, token_file: " + token + ", chat_id: \"42\"}\n",
+			wantToken:  "123:abc",
+			wantChatID: "42",
+		},
+		{
+			name:    "both set",
+			src:     "notifications:\n  - {type: telegram, token: inline, token_file: " + token + ", chat_id: \"42\"}\n",
+			wantErr: "both token and token_file are set",
+		},
+		{
+			name:    "missing file",
+			src:     "notifications:\n  - {type: telegram, token_file: /nonexistent/token, chat_id: \"42\"}\n",
+			wantErr: "token_file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parse(t, tt.src)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := cfg.Notifications[0]
+			if n.Token != tt.wantToken || n.ChatID != tt.wantChatID {
+				t.Errorf("token %q, chat_id %q; want %q, %q", n.Token, n.ChatID, tt.wantToken, tt.wantChatID)
+			}
+		})
+	}
+}
+
+func TestIngestSecretFile(t *testing.T) {
+	path := secretFile(t, "s3cret\n")
+	cfg, err := parse(t, "global: {ingest_secret_file: "+path+"}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Global.IngestSecret != "s3cret" {
+		t.Errorf("ingest_secret = %q, want %q", cfg.Global.IngestSecret, "s3cret")
+	}
+
+	if _, err := parse(t, "global: {ingest_secret: inline, ingest_secret_file: "+path+"}\n"); err == nil {
+		t.Error("ingest_secret and ingest_secret_file together were accepted")
+	}
+}
+
+func TestLabelNames(t *testing.T) {
+	tests := []struct {
+		labels  string
+		wantErr bool
+	}{
+		{"{team: payments, runbook_url: x, _private: y}", false},
+		{"{team-name: x}", true},
+		{"{1st: x}", true},
+		{"{__name__: x}", true},
+		{"{monitor: x}", true}, // taken by the metrics' own label
+	}
+	for _, tt := range tests {
+		_, err := parse(t, "monitors:\n  - {name: api, type: tcp, host: 127.0.0.1, port: 1, labels: "+tt.labels+"}\n")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("labels %s: err = %v, want error %v", tt.labels, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTCPMode(t *testing.T) {
+	tests := []struct {
+		opts    string
+		wantErr string
+	}{
+		{"tcp_mode: connect", ""},
+		{"tcp_mode: read, expect_data: PONG", ""},
+		{"expect_data: PONG", "expect_data requires tcp_mode: read"},
+		{"tcp_mode: write", `unknown tcp_mode "write"`},
+	}
+	for _, tt := range tests {
+		_, err := parse(t, "monitors:\n  - {name: db, type: tcp, host: 127.0.0.1, port: 1, "+tt.opts+"}\n")
+		if (tt.wantErr == "") != (err == nil) || err != nil && !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.opts, err, tt.wantErr)
+		}
+	}
+}
+
+func TestExecNeedsOptIn(t *testing.T) {
+	tests := []struct {
+		src     string
+		wantErr string
+	}{
+		{"global: {allow_exec: true}\nmonitors: [{name: probe, type: exec, command: [\"true\"], exec_timeout: 5s}]", ""},
+		{"monitors: [{name: probe, type: exec, command: [\"true\"]}]", "exec monitors are disabled"},
+		{"global: {allow_exec: true}\nmonitors: [{name: probe, type: exec}]", "need a command"},
+		{"global: {allow_exec: true}\nmonitors: [{name: probe, type: exec, command: [\"true\"], exec_timeout: soon}]", "exec_timeout"},
+	}
+	for _, tt := range tests {
+		_, err := parse(t, tt.src)
+		if (tt.wantErr == "") != (err == nil) || err != nil && !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.src, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "30", want: 30 * time.Second},
+		{in: " 30 ", want: 30 * time.Second},
+		{in: "5m", want: 5 * time.Minute},
+		{in: "1h30m", want: 90 * time.Minute},
+		{in: "0", want: 0},
+		{in: "5 minutes", wantErr: true},
+		{in: "1.5", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseDuration(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseDuration(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestIntervals(t *testing.T) {
+	tests := []struct {
+		name    string
+		global  string
+		monitor string
+		want    time.Duration // the monitor's interval
+		wantErr string
+	}{
+		{name: "bare seconds", global: "check_interval: 30", want: 30 * time.Second},
+		{name: "go duration", global: "check_interval: 5m", want: 5 * time.Minute},
+		{name: "monitor override", global: "check_interval: 5m", monitor: ", interval: 45", want: 45 * time.Second},
+		{name: "bad global", global: "check_interval: often", wantErr: "global.check_interval"},
+		{name: "zero global", global: "check_interval: 0", wantErr: "must be positive"},
+		{name: "bad monitor", global: "check_interval: 30", monitor: ", interval: 5x", wantErr: `monitor "api": interval`},
+		{name: "bad down_interval", global: "check_interval: 30", monitor: ", down_interval: -10", wantErr: "down_interval"},
+		{name: "bad retry_delay", global: "check_interval: 30", monitor: ", retry_delay: soon", wantErr: "retry_delay"},
+		{name: "bad dashboard_refresh", global: "check_interval: 30, dashboard_refresh: fast", wantErr: "dashboard_refresh"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parse(t, "global: {"+tt.global+"}\nmonitors:\n  - {name: api, type: tcp, host: 127.0.0.1, port: 1"+tt.monitor+"}\n")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ParseDuration(cmp.Or(cfg.Monitors[0].Interval, cfg.Global.CheckInterval)); got != tt.want {
+				t.Errorf("interval %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTLSVersions(t *testing.T) {
+	tests := []struct {
+		opts    string
+		wantErr string
+	}{
+		{opts: `min_tls_version: "1.2"`},
+		{opts: `min_tls_version: "1.2", max_tls_version: "1.3"`},
+		{opts: `min_tls_version: "1.4"`, wantErr: "min_tls_version"},
+		{opts: `max_tls_version: "TLSv1.2"`, wantErr: "max_tls_version"},
+		{opts: `min_tls_version: "1.3", max_tls_version: "1.2"`, wantErr: "above max_tls_version"},
+	}
+	for _, tt := range tests {
+		_, err := parse(t, "monitors:\n  - {name: api, type: http, url: \"https://127.0.0.1/\", "+tt.opts+"}\n")
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: %v", tt.opts, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: err = %v, want it to mention %q", tt.opts, err, tt.wantErr)
+		}
+	}
+}
+
+func TestHTTPPoolConfig(t *testing.T) {
+	tests := []struct {
+		pool    string
+		want    HTTPPoolConfig
+		wantErr string
+	}{
+		{pool: "{}", want: HTTPPoolConfig{MaxIdleConns: 100, MaxIdleConnsPerHost: 10, IdleConnTimeout: "90s"}},
+		{pool: "{max_idle_conns_per_host: 4, idle_conn_timeout: 30}", want: HTTPPoolConfig{MaxIdleConns: 100, MaxIdleConnsPerHost: 4, IdleConnTimeout: "30"}},
+		{pool: "{max_idle_conns: -1}", wantErr: "must not be negative"},
+		{pool: "{idle_conn_timeout: 0s}", wantErr: "idle_conn_timeout"},
+	}
+	for _, tt := range tests {
+		cfg, err := parse(t, "global: {http_pool: "+tt.pool+"}\n")
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want it to mention %q", tt.pool, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.pool, err)
+		}
+		if got := *cfg.Global.HTTPPool; got != tt.want {
+			t.Errorf("%s: %+v, want %+v", tt.pool, got, tt.want)
+		}
+	}
+}
+
+func TestInverted(t *testing.T) {
+	tests := []struct {
+		monitor string
+		wantErr string
+	}{
+		{monitor: `{name: m, type: http, url: "http://127.0.0.1/", inverted: true}`},
+		{monitor: `{name: m, type: push, inverted: true}`, wantErr: "inverted doesn't apply to push monitors"},
+		{monitor: `{name: m, type: http, url: "http://127.0.0.1/", inverted: true, latency_anomaly: {}}`, wantErr: "latency_anomaly doesn't apply"},
+		{monitor: `{name: m, type: tcp, host: 127.0.0.1, port: 22, inverted: true}`},
+		{monitor: `{name: m, type: tcp, host: 127.0.0.1, port: 22, inverted: true, tcp_mode: read}`, wantErr: "tcp_mode: read doesn't apply"},
+	}
+	for _, tt := range tests {
+		_, err := parse(t, "monitors:\n  - "+tt.monitor+"\n")
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: %v", tt.monitor, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: err = %v, want it to mention %q", tt.monitor, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCertFingerprint(t *testing.T) {
+	const fp = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		in      string
+		wantErr bool
+	}{
+		{in: fp},
+		{in: strings.ToUpper(fp)},
+		{in: "01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF"},
+		{in: fp[:40], wantErr: true}, // SHA-1 length
+		{in: "zz" + fp[2:], wantErr: true},
+	}
+	for _, tt := range tests {
+		cfg, err := parse(t, "monitors:\n  - {name: api, type: http, url: \"https://127.0.0.1/\", expect_cert_fingerprint: \""+tt.in+"\"}\n")
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "expect_cert_fingerprint") {
+				t.Errorf("%s: err = %v, want a fingerprint error", tt.in, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.in, err)
+		}
+		if got := cfg.Monitors[0].ExpectCertFingerprint; got != fp {
+			t.Errorf("%s: normalized to %s, want %s", tt.in, got, fp)
+		}
+	}
+}
+
+func TestNotificationEvents(t *testing.T) {
+	const base = "notifications:\n  - {type: slack, webhook_url: \"https://hooks.example.com/x\", events: %s}\n"
+	cfg, err := parse(t, fmt.Sprintf(base, "[down, slo_burn]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Notifications[0].Events; fmt.Sprint(got) != "[down slo_burn]" {
+		t.Errorf("events %v, want [down slo_burn]", got)
+	}
+	if _, err := parse(t, fmt.Sprintf(base, "[down, recovered]")); err == nil || !strings.Contains(err.Error(), `unknown event "recovered"`) {
+		t.Errorf("unknown event: err = %v", err)
+	}
+}
+
+func TestCooldownOverrides(t *testing.T) {
+	cfg, err := parse(t, `
+global: {notification_cooldown: 15m}
+monitors:
+  - {name: api, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: db, type: tcp, host: 127.0.0.1, port: 1, notification_cooldown: 1m}
+  - {name: cache, type: tcp, host: 127.0.0.1, port: 1, notification_cooldown: "0"}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]time.Duration{"db": time.Minute, "cache": 0}
+	if got := cfg.CooldownOverrides(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("CooldownOverrides() = %v, want %v", got, want)
+	}
+
+	for _, src := range []string{
+		"global: {notification_cooldown: soon}\n",
+		"global: {notification_cooldown: -1m}\n",
+		"monitors:\n  - {name: m, type: tcp, host: 127.0.0.1, port: 1, notification_cooldown: 5 minutes}\n",
+	} {
+		if _, err := parse(t, src); err == nil || !strings.Contains(err.Error(), "notification_cooldown") {
+			t.Errorf("%s: err = %v, want a notification_cooldown error", src, err)
+		}
+	}
+}
+
+func TestHealthWeight(t *testing.T) {
+	tests := []struct {
+		weight  string
+		want    float64
+		wantErr bool
+	}{
+		{weight: "", want: 1},
+		{weight: ", weight: 0", want: 0},
+		{weight: ", weight: 2.5", want: 2.5},
+		{weight: ", weight: -1", wantErr: true},
+	}
+	for _, tt := range tests {
+		cfg, err := parse(t, "monitors:\n  - {name: m, type: tcp, host: 127.0.0.1, port: 1"+tt.weight+"}\n")
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "weight must not be negative") {
+				t.Errorf("%q: err = %v, want a weight error", tt.weight, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := cfg.Monitors[0].HealthWeight(); got != tt.want {
+			t.Errorf("%q: HealthWeight() = %v, want %v", tt.weight, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OverlayPath returns the overlay for an environment next to the base
+// config: monitors.yaml with env "prod" gives monitors.prod.yaml. Works
+// for files and URLs alike.
+func OverlayPath(base, env string) string {
+	ext := path.Ext(base)
+	return strings.TrimSuffix(base, ext) + "." + env + ext
+}
+
+// LoadConfigWithOverlay loads the base config with an environment overlay
+// merged on top, then parses and validates the result as a whole.
+//
+// Merge rules: mappings merge key by key, recursively, so an overlay only
+// needs the keys it changes. The monitors list is keyed by name: an
+// overlay monitor with the name of a base monitor is merged into it,
+// others are appended. Every other list (notifications, children,
+// expect_body_all...) is replaced outright by the overlay's.
+func LoadConfigWithOverlay(basePath, overlayPath string) (*Config, error) {
+	if basePath == "-" {
+		return nil, fmt.Errorf("config overlays need a config file or URL, not stdin")
+	}
+	base, err := readYAMLMap(basePath)
+	if err != nil {
+		return nil, err
+	}
+	overlay, err := readYAMLMap(overlayPath)
+	if err != nil {
+		return nil, fmt.Errorf("overlay: %w", err)
+	}
+
+	merged, err := mergeConfig(base, overlay)
+	if err != nil {
+		return nil, fmt.Errorf("overlay %s: %w", overlayPath, err)
+	}
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(data)
+}
+
+func readYAMLMap(path string) (map[string]any, error) {
+	data, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]any{}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// mergeConfig applies overlay onto base, see LoadConfigWithOverlay.
+func mergeConfig(base, overlay map[string]any) (map[string]any, error) {
+	out := mergeMaps(base, overlay)
+	if ov, ok := overlay["monitors"]; ok {
+		monitors, err := mergeMonitors(base["monitors"], ov)
+		if err != nil {
+			return nil, err
+		}
+		out["monitors"] = monitors
+	}
+	return out, nil
+}
+
+// mergeMaps returns base with overlay's keys merged in recursively.
+func mergeMaps(base, overlay map[string]any) map[string]any {
+	out := make(map[string]any, len(base)+len(overlay))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range overlay {
+		bm, bok := out[k].(map[string]any)
+		om, ook := v.(map[string]any)
+		if bok && ook {
+			out[k] = mergeMaps(bm, om)
+		} else {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// mergeMonitors merges two monitors lists by monitor name, keeping the
+// base order and appending new monitors in overlay order.
+func mergeMonitors(base, overlay any) ([]any, error) {
+	baseList, _ := base.([]any)
+	overlayList, ok := overlay.([]any)
+	if !ok {
+		return nil, fmt.Errorf("monitors must be a list")
+	}
+
+	out := make([]any, len(baseList))
+	copy(out, baseList)
+	index := make(map[string]int, len(out))
+	for i, m := range out {
+		if name, ok := monitorName(m); ok {
+			index[name] = i
+		}
+	}
+
+	for _, m := range overlayList {
+		name, ok := monitorName(m)
+		if !ok {
+			return nil, fmt.Errorf("every monitor in an overlay needs a name")
+		}
+		if i, found := index[name]; found {
+			out[i] = mergeMaps(out[i].(map[string]any), m.(map[string]any))
+			continue
+		}
+		index[name] = len(out)
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+func monitorName(m any) (string, bool) {
+	mm, ok := m.(map[string]any)
+	if !ok {
+		return "", false
+	}
+	name, ok := mm["name"].(string)
+	return name, ok && name != ""
+}
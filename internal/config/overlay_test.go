@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOverlayPath(t *testing.T) {
+	tests := []struct{ base, env, want string }{
+		{"monitors.yaml", "prod", "monitors.prod.yaml"},
+		{"/etc/zen/monitors.yml", "staging", "/etc/zen/monitors.staging.yml"},
+		{"https://cfg.example.com/zen/monitors.yaml", "prod", "https://cfg.example.com/zen/monitors.prod.yaml"},
+		{"monitors", "dev", "monitors.dev"},
+	}
+	for _, tt := range tests {
+		if got := OverlayPath(tt.base, tt.env); got != tt.want {
+			t.Errorf("OverlayPath(%q, %q) = %q, want %q", tt.base, tt.env, got, tt.want)
+		}
+	}
+}
+
+const overlayBase = `
+global: {check_interval: 1m, history_days: 30, dashboard_refresh: 10s}
+notifications:
+  - {type: slack, webhook_url: "https://hooks.example.com/dev"}
+monitors:
+  - {name: api, url: "http://api.dev.internal/health", interval: 30s, expect_body_all: [ok, db]}
+  - {name: db, type: tcp, host: db.dev.internal, port: 5432}
+`
+
+func TestLoadConfigWithOverlay(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	base := write("monitors.yaml", overlayBase)
+
+	t.Run("merged", func(t *testing.T) {
+		cfg, err := LoadConfigWithOverlay(base, write("monitors.prod.yaml", `
+global: {history_days: 365}
+notifications:
+  - {type: telegram, token: "1:x", chat_id: "42"}
+monitors:
+  - {name: api, url: "https://api.example.com/health", expect_body_all: [ok]}
+  - {name: cache, type: tcp, host: cache.internal, port: 6379}
+`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Mappings merge key by key
+		if g := cfg.Global; g.HistoryDays != 365 || g.CheckInterval != "1m" || g.DashboardRefresh != "10s" {
+			t.Errorf("global %+v, want history_days from the overlay, the rest from the base", g)
+		}
+		// Lists other than monitors are replaced
+		if len(cfg.Notifications) != 1 || cfg.Notifications[0].Type != "telegram" {
+			t.Errorf("notifications %+v, want just the overlay's", cfg.Notifications)
+		}
+		var names []string
+		for _, m := range cfg.Monitors {
+			names = append(names, m.Name)
+		}
+		if !slices.Equal(names, []string{"api", "db", "cache"}) {
+			t.Fatalf("monitors %v, want base order with new ones appended", names)
+		}
+		api := cfg.Monitors[0]
+		if api.URL != "https://api.example.com/health" || api.Interval != "30s" || !slices.Equal(api.ExpectBodyAll, []string{"ok"}) {
+			t.Errorf("api %+v, want the overlay's url and expect_body_all, the base's interval", api)
+		}
+		if cfg.Monitors[1].Host != "db.dev.internal" {
+			t.Errorf("db changed although the overlay doesn't mention it")
+		}
+	})
+
+	errTests := []struct {
+		name    string
+		overlay string
+		wantErr string
+	}{
+		{"monitor without a name", "monitors: [{interval: 5m}]", "needs a name"},
+		{"monitors not a list", "monitors: {api: {interval: 5m}}", "monitors must be a list"},
+		{"invalid result", "global: {check_interval: soon}", "global.check_interval"},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfigWithOverlay(base, write("bad.yaml", tt.overlay))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+	t.Run("missing overlay", func(t *testing.T) {
+		_, err := LoadConfigWithOverlay(base, filepath.Join(dir, "monitors.nope.yaml"))
+		if err == nil || !strings.HasPrefix(err.Error(), "overlay:") {
+			t.Errorf("err = %v, want an overlay error", err)
+		}
+	})
+}
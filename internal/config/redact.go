@@ -0,0 +1,103 @@
+package config
+
+import (
+	"maps"
+	"net/url"
+)
+
+// RedactedValue replaces secrets in Redacted output.
+const RedactedValue = "REDACTED"
+
+// Redacted returns a copy of the config with secrets masked, safe to show
+// over the API. Anything that can carry credentials must be handled here.
+func (c *Config) Redacted() *Config {
+	out := *c
+	out.Global.IngestSecret = mask(c.Global.IngestSecret)
+	out.Global.AdminToken = mask(c.Global.AdminToken)
+
+	out.Notifications = make([]NotificationConfig, len(c.Notifications))
+	for i, n := range c.Notifications {
+		n.Token = mask(n.Token)
+		n.ChatID = mask(n.ChatID)
+		n.WebhookURL = mask(n.WebhookURL)
+		n.APIKey = mask(n.APIKey)
+		out.Notifications[i] = n
+	}
+
+	out.Monitors = make([]MonitorConfig, len(c.Monitors))
+	for i, m := range c.Monitors {
+		m.URL = redactURL(m.URL)
+		m.Children = append([]string(nil), m.Children...)
+		m.DependsOn = append([]string(nil), m.DependsOn...)
+		m.Labels = maps.Clone(m.Labels)
+		if len(m.Command) > 0 {
+			// Arguments may carry tokens, the program name is enough to see
+			cmd := make([]string, len(m.Command))
+			cmd[0] = m.Command[0]
+			for j := 1; j < len(cmd); j++ {
+				cmd[j] = RedactedValue
+			}
+			m.Command = cmd
+		}
+		if m.SLO != nil {
+			slo := *m.SLO
+			m.SLO = &slo
+		}
+		if m.LatencyAnomaly != nil {
+			la := *m.LatencyAnomaly
+			m.LatencyAnomaly = &la
+		}
+		if m.Auth != nil {
+			// Login bodies and headers are full of credentials
+			auth := *m.Auth
+			auth.URL = redactURL(auth.URL)
+			auth.Body = mask(auth.Body)
+			auth.Headers = maps.Clone(auth.Headers)
+			for k, v := range auth.Headers {
+				auth.Headers[k] = mask(v)
+			}
+			m.Auth = &auth
+		}
+		out.Monitors[i] = m
+	}
+
+	out.Views = make([]ViewConfig, len(c.Views))
+	for i, v := range c.Views {
+		out.Views[i] = cloneView(v)
+	}
+
+	return &out
+}
+
+func mask(s string) string {
+	if s == "" {
+		return ""
+	}
+	return RedactedValue
+}
+
+// redactURL masks the password in user:pass@host URLs and all query
+// values, which often hold tokens.
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		// Can't tell what's in it, hide the lot
+		return RedactedValue
+	}
+	if u.User != nil {
+		if _, hasPass := u.User.Password(); hasPass {
+			u.User = url.UserPassword(u.User.Username(), RedactedValue)
+		}
+	}
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			q.Set(k, RedactedValue)
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
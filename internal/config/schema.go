@@ -0,0 +1,76 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Schema returns a JSON Schema for monitors.yaml, derived from the config
+// structs so it can't drift from what LoadConfig accepts. Property names
+// come from the yaml tags; a `schema:"required,enum=a|b"` tag marks
+// required fields and allowed values. Unknown keys are rejected, which is
+// what catches typos in editors.
+func Schema() map[string]any {
+	s := schemaFor(reflect.TypeOf(Config{}))
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["title"] = "ZenMonitor configuration (monitors.yaml)"
+	return s
+}
+
+func schemaFor(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaFor(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem())}
+	case reflect.Struct:
+		props := map[string]any{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			prop := schemaFor(f.Type)
+			for _, opt := range strings.Split(f.Tag.Get("schema"), ",") {
+				switch {
+				case opt == "required":
+					required = append(required, name)
+				case strings.HasPrefix(opt, "enum="):
+					enum := strings.Split(strings.TrimPrefix(opt, "enum="), "|")
+					if items, ok := prop["items"].(map[string]any); ok {
+						items["enum"] = enum
+					} else {
+						prop["enum"] = enum
+					}
+				}
+			}
+			props[name] = prop
+		}
+		s := map[string]any{
+			"type":                 "object",
+			"properties":           props,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	}
+	return map[string]any{}
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// schemaAt follows a path of property names through a schema, "[]" stepping
+// into array items.
+func schemaAt(t *testing.T, s map[string]any, path string) map[string]any {
+	t.Helper()
+	for _, step := range strings.Split(path, ".") {
+		var next any
+		if step == "[]" {
+			next = s["items"]
+		} else {
+			props, _ := s["properties"].(map[string]any)
+			next = props[step]
+		}
+		var ok bool
+		if s, ok = next.(map[string]any); !ok {
+			t.Fatalf("schema has no %s (at %s)", path, step)
+		}
+	}
+	return s
+}
+
+func TestSchema(t *testing.T) {
+	s := Schema()
+	tests := []struct {
+		path     string
+		typ      string
+		required []string
+		enum     []string // must include these
+	}{
+		{path: "global", typ: "object"},
+		{path: "global.check_interval", typ: "string"},
+		{path: "global.history_days", typ: "integer"},
+		{path: "global.public", typ: "boolean"},
+		{path: "global.host_rate_limit", typ: "number"},
+		{path: "global.dashboard_sort", typ: "string", enum: []string{"config", "name", "status", "uptime"}},
+		{path: "notifications.[]", typ: "object", required: []string{"type"}},
+		{path: "notifications.[].type", typ: "string", enum: []string{"telegram", "slack", "opsgenie"}},
+		{path: "monitors", typ: "array"},
+		{path: "monitors.[]", typ: "object", required: []string{"name"}},
+		{path: "monitors.[].type", typ: "string", enum: []string{"http", "tcp", "smtp", "icmp", "aggregate", "push"}},
+		{path: "monitors.[].port", typ: "integer"},
+		{path: "monitors.[].children", typ: "array"},
+		{path: "monitors.[].expect_headers", typ: "object"},
+		{path: "monitors.[].auth", typ: "object", required: []string{"url"}},
+		{path: "views.[]", typ: "object", required: []string{"name"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			prop := schemaAt(t, s, tt.path)
+			if prop["type"] != tt.typ {
+				t.Errorf("type %v, want %s", prop["type"], tt.typ)
+			}
+			required, _ := prop["required"].([]string)
+			for _, r := range tt.required {
+				if !slices.Contains(required, r) {
+					t.Errorf("required %v, want %s in it", required, r)
+				}
+			}
+			enum, _ := prop["enum"].([]string)
+			for _, v := range tt.enum {
+				if !slices.Contains(enum, v) {
+					t.Errorf("enum %v, want %s in it", enum, v)
+				}
+			}
+			if tt.typ == "object" && prop["additionalProperties"] != false {
+				if _, isMap := prop["additionalProperties"].(map[string]any); !isMap {
+					t.Errorf("unknown keys are allowed")
+				}
+			}
+		})
+	}
+	if s["$schema"] == nil {
+		t.Errorf("no $schema")
+	}
+}
+
+// checkKeys reports keys of a YAML document the schema doesn't know.
+func checkKeys(s map[string]any, doc any, path string) []string {
+	var unknown []string
+	switch v := doc.(type) {
+	case map[string]any:
+		props, _ := s["properties"].(map[string]any)
+		for k, child := range v {
+			if props == nil {
+				if sub, ok := s["additionalProperties"].(map[string]any); ok {
+					unknown = append(unknown, checkKeys(sub, child, path+"."+k)...)
+				}
+				continue
+			}
+			sub, ok := props[k].(map[string]any)
+			if !ok {
+				unknown = append(unknown, path+"."+k)
+				continue
+			}
+			unknown = append(unknown, checkKeys(sub, child, path+"."+k)...)
+		}
+	case []any:
+		items, _ := s["items"].(map[string]any)
+		for i, child := range v {
+			unknown = append(unknown, checkKeys(items, child, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return unknown
+}
+
+func TestExampleConfig(t *testing.T) {
+	if _, err := parse(t, ExampleConfig); err != nil {
+		t.Fatalf("example config doesn't load: %v", err)
+	}
+	var doc any
+	if err := yaml.Unmarshal([]byte(ExampleConfig), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if unknown := checkKeys(Schema(), doc, ""); len(unknown) > 0 {
+		t.Errorf("example uses keys missing from the schema: %v", unknown)
+	}
+}
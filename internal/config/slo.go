@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// SLOConfig is an uptime objective for a monitor. Alerts fire on error
+// budget burn: when the failure rate over burn_window would use up the
+// window's budget burn_rate times faster than sustainable.
+type SLOConfig struct {
+	Target float64 `yaml:"target" schema:"required"` // uptime in percent, e.g. 99.9
+	// Period the error budget applies to, default 720h (30 days). Must
+	// fit in the monitor's retained history.
+	Window string `yaml:"window,omitempty"`
+	// Recent period the burn rate is measured over, default 1h
+	BurnWindow string `yaml:"burn_window,omitempty"`
+	// Burn rate that alerts, default 14.4 (2% of a 30 day budget in an hour)
+	BurnRate float64 `yaml:"burn_rate,omitempty"`
+}
+
+// Defaults for SLOConfig.
+const (
+	DefaultSLOWindow     = "720h"
+	DefaultSLOBurnWindow = "1h"
+	DefaultSLOBurnRate   = 14.4
+)
+
+// resolve validates the SLO and fills in its defaults. retentionDays is
+// how much history the monitor keeps.
+func (s *SLOConfig) resolve(retentionDays int) error {
+	if s.Target <= 0 || s.Target >= 100 {
+		return fmt.Errorf("target must be between 0 and 100 (exclusive)")
+	}
+	if s.Window == "" {
+		s.Window = DefaultSLOWindow
+	}
+	if s.BurnWindow == "" {
+		s.BurnWindow = DefaultSLOBurnWindow
+	}
+	if s.BurnRate == 0 {
+		s.BurnRate = DefaultSLOBurnRate
+	}
+	if s.BurnRate < 0 {
+		return fmt.Errorf("burn_rate must not be negative")
+	}
+
+	window, err := parseDuration(s.Window)
+	if err != nil {
+		return fmt.Errorf("window: %w", err)
+	}
+	burnWindow, err := parseDuration(s.BurnWindow)
+	if err != nil {
+		return fmt.Errorf("burn_window: %w", err)
+	}
+	if burnWindow <= 0 || burnWindow > window {
+		return fmt.Errorf("burn_window must be positive and no longer than window")
+	}
+	if retention := time.Duration(retentionDays) * 24 * time.Hour; retention > 0 && window > retention {
+		return fmt.Errorf("window %s is longer than the %d days of history kept", s.Window, retentionDays)
+	}
+	return nil
+}
+
+// ErrorBudget is the fraction of checks allowed to fail, e.g. 0.001 for 99.9%.
+func (s SLOConfig) ErrorBudget() float64 {
+	return (100 - s.Target) / 100
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSLOConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		slo     string
+		extra   string // more monitor settings
+		want    SLOConfig
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			slo:  "target: 99.9",
+			want: SLOConfig{Target: 99.9, Window: "720h", BurnWindow: "1h", BurnRate: 14.4},
+		},
+		{
+			name: "explicit",
+			slo:  "target: 99, window: 24h, burn_window: 5m, burn_rate: 6",
+			want: SLOConfig{Target: 99, Window: "24h", BurnWindow: "5m", BurnRate: 6},
+		},
+		{name: "target 100", slo: "target: 100", wantErr: "target must be between"},
+		{name: "no target", slo: "window: 24h", wantErr: "target must be between"},
+		{name: "negative burn rate", slo: "target: 99, burn_rate: -1", wantErr: "burn_rate must not be negative"},
+		{name: "bad window", slo: "target: 99, window: soon", wantErr: "window:"},
+		{name: "burn window longer than window", slo: "target: 99, window: 1h, burn_window: 2h", wantErr: "no longer than window"},
+		{name: "window past retention", slo: "target: 99, window: 720h", extra: ", retention_days: 7", wantErr: "longer than the 7 days"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parse(t, "monitors:\n  - {name: api, type: tcp, host: 127.0.0.1, port: 1"+tt.extra+", slo: {"+tt.slo+"}}\n")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := *cfg.Monitors[0].SLO; got != tt.want {
+				t.Errorf("slo %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const sourceConfig = `
+monitors:
+  - {name: api, type: tcp, host: 127.0.0.1, port: 1}
+`
+
+func TestLoadConfigReader(t *testing.T) {
+	cfg, err := LoadConfigReader(strings.NewReader(sourceConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Monitors) != 1 || cfg.Monitors[0].Name != "api" {
+		t.Fatalf("monitors %+v, want api", cfg.Monitors)
+	}
+	// Validation applies as for a file
+	_, err = LoadConfigReader(strings.NewReader("global: {check_interval: soon}\n" + sourceConfig))
+	if err == nil || !strings.Contains(err.Error(), "global.check_interval") {
+		t.Errorf("invalid config from a reader: err = %v", err)
+	}
+}
+
+func TestLoadConfigURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/monitors.yaml":
+			w.Write([]byte(sourceConfig))
+		case "/invalid.yaml":
+			w.Write([]byte("monitors: [{"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path    string
+		wantErr string
+	}{
+		{"/monitors.yaml", ""},
+		{"/missing.yaml", "404 Not Found"},
+		{"/invalid.yaml", "yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			cfg, err := LoadConfig(srv.URL + tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cfg.Monitors) != 1 || cfg.Monitors[0].Name != "api" {
+				t.Errorf("monitors %+v, want api", cfg.Monitors)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Monitor states a status_map can assign.
+const (
+	StateUp       = "up"
+	StateDegraded = "degraded"
+	StateDown     = "down"
+)
+
+// StatusState returns the state status_map assigns to an HTTP status code.
+// When ranges overlap the narrowest one wins, so "401": up can carve an
+// exception out of "400-599": down. Codes no range covers are down.
+func (m MonitorConfig) StatusState(code int) string {
+	state, width := StateDown, -1
+	for key, s := range m.StatusMap {
+		lo, hi, err := parseStatusRange(key)
+		if err != nil || code < lo || code > hi {
+			continue
+		}
+		if width < 0 || hi-lo < width {
+			state, width = s, hi-lo
+		}
+	}
+	return state
+}
+
+// validateStatusMap checks every key is a code or range and every value a
+// known state.
+func validateStatusMap(statusMap map[string]string) error {
+	for key, state := range statusMap {
+		if _, _, err := parseStatusRange(key); err != nil {
+			return err
+		}
+		switch state {
+		case StateUp, StateDegraded, StateDown:
+		default:
+			return fmt.Errorf("status_map %q: unknown state %q (want up, degraded or down)", key, state)
+		}
+	}
+	return nil
+}
+
+// parseStatusRange parses "404" or "200-299".
+func parseStatusRange(s string) (lo, hi int, err error) {
+	from, to, isRange := strings.Cut(s, "-")
+	lo, err = strconv.Atoi(strings.TrimSpace(from))
+	if err != nil {
+		return 0, 0, fmt.Errorf("status_map %q: not a status code or range", s)
+	}
+	hi = lo
+	if isRange {
+		hi, err = strconv.Atoi(strings.TrimSpace(to))
+		if err != nil {
+			return 0, 0, fmt.Errorf("status_map %q: not a status code or range", s)
+		}
+	}
+	if lo < 100 || hi > 599 || lo > hi {
+		return 0, 0, fmt.Errorf("status_map %q: range must be within 100-599", s)
+	}
+	return lo, hi, nil
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatusState(t *testing.T) {
+	m := MonitorConfig{StatusMap: map[string]string{
+		"200-299": StateUp,
+		"300-399": StateDegraded,
+		"400-599": StateDown,
+		"401":     StateUp, // narrower than 400-599
+		"429":     StateDegraded,
+	}}
+	tests := []struct {
+		code int
+		want string
+	}{
+		{200, StateUp},
+		{299, StateUp},
+		{301, StateDegraded},
+		{400, StateDown},
+		{401, StateUp},
+		{429, StateDegraded},
+		{599, StateDown},
+		{100, StateDown}, // not mapped
+	}
+	for _, tt := range tests {
+		if got := m.StatusState(tt.code); got != tt.want {
+			t.Errorf("StatusState(%d) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestValidateStatusMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{"codes and ranges", `{"200": up, "500 - 503": degraded}`, ""},
+		{"unknown state", `{"200": fine}`, `unknown state "fine"`},
+		{"not a code", `{ok: up}`, "not a status code or range"},
+		{"open range", `{"500-": down}`, "not a status code or range"},
+		{"out of bounds", `{"200-600": up}`, "within 100-599"},
+		{"backwards", `{"299-200": up}`, "within 100-599"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(t, "monitors:\n  - {name: api, type: http, url: \"http://127.0.0.1/\", status_map: "+tt.src+"}\n")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("err = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
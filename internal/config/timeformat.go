@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timeLayouts are the timestamp_format names; anything else is taken as a
+// Go layout.
+var timeLayouts = map[string]string{
+	"rfc3339":  time.RFC3339,
+	"rfc1123":  time.RFC1123,
+	"rfc1123z": time.RFC1123Z,
+	"rfc822":   time.RFC822,
+	"datetime": time.DateTime,
+	"kitchen":  time.Kitchen,
+}
+
+// ParseTimeLayout turns a timestamp_format value into a time layout.
+// Empty means RFC 3339. A custom layout must contain at least one element
+// of Go's reference time (Mon Jan 2 15:04:05 MST 2006).
+func ParseTimeLayout(format string) (string, error) {
+	if format == "" {
+		return time.RFC3339, nil
+	}
+	if layout, ok := timeLayouts[strings.ToLower(format)]; ok {
+		return layout, nil
+	}
+	// A layout without reference elements formats every time the same
+	ref := time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)
+	other := time.Date(2017, time.November, 25, 8, 27, 49, 0, time.UTC)
+	if ref.Format(format) == other.Format(format) {
+		return "", fmt.Errorf("%q is neither a known format nor a Go time layout (e.g. \"2006-01-02 15:04 MST\")", format)
+	}
+	return format, nil
+}
+
+// TimeLayout returns the layout of global.timestamp_format.
+func (g GlobalConfig) TimeLayout() string {
+	// Validated in LoadConfig
+	layout, err := ParseTimeLayout(g.TimestampFormat)
+	if err != nil {
+		return time.RFC3339
+	}
+	return layout
+}
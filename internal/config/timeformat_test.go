@@ -0,0 +1,32 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeLayout(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{format: "", want: time.RFC3339},
+		{format: "rfc1123", want: time.RFC1123},
+		{format: "DateTime", want: time.DateTime},
+		{format: "2006-01-02 15:04 MST", want: "2006-01-02 15:04 MST"},
+		{format: "Jan 2", want: "Jan 2"},
+		{format: "yyyy-mm-dd", wantErr: true},
+		{format: "iso", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimeLayout(tt.format)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseTimeLayout(%q) = %q, %v; want %q, error %v", tt.format, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	if _, err := parse(t, "global: {timestamp_format: yyyy-mm-dd}\n"); err == nil {
+		t.Errorf("config with an invalid timestamp_format loaded")
+	}
+}
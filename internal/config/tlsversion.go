@@ -0,0 +1,27 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// tlsVersions maps the min_tls_version/max_tls_version values to versions.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion turns "1.2" into tls.VersionTLS12. Empty gives 0, which
+// leaves crypto/tls defaults in place.
+func ParseTLSVersion(v string) (uint16, error) {
+	if v == "" {
+		return 0, nil
+	}
+	version, ok := tlsVersions[v]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q (want 1.0, 1.1, 1.2 or 1.3)", v)
+	}
+	return version, nil
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+)
+
+// ViewConfig is a dashboard page showing a subset of the monitors, served
+// at /view/{name}. A monitor is on it when it's listed by name or carries
+// all of the view's labels.
+type ViewConfig struct {
+	Name     string            `yaml:"name" schema:"required"` // used in the URL
+	Title    string            `yaml:"title,omitempty"`        // page heading, defaults to the name
+	Monitors []string          `yaml:"monitors,omitempty"`
+	Labels   map[string]string `yaml:"labels,omitempty"` // e.g. team: payments
+}
+
+// viewNameRe keeps view names safe to use in URLs.
+var viewNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Includes reports whether a monitor is shown on the view.
+func (v ViewConfig) Includes(m MonitorConfig) bool {
+	if slices.Contains(v.Monitors, m.Name) {
+		return true
+	}
+	if len(v.Labels) == 0 {
+		return false
+	}
+	for k, want := range v.Labels {
+		if got, ok := m.Labels[k]; !ok || got != want {
+			return false
+		}
+	}
+	return true
+}
+
+// validateViews checks view names are unique and URL safe, and that every
+// view selects something and only names monitors that exist.
+func validateViews(views []ViewConfig, monitors []MonitorConfig) error {
+	known := make(map[string]bool, len(monitors))
+	for _, m := range monitors {
+		known[m.Name] = true
+	}
+	seen := make(map[string]bool, len(views))
+	for _, v := range views {
+		if !viewNameRe.MatchString(v.Name) {
+			return fmt.Errorf("view %q: name may only contain letters, digits, - and _", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("view %q: defined twice", v.Name)
+		}
+		seen[v.Name] = true
+		if len(v.Monitors) == 0 && len(v.Labels) == 0 {
+			return fmt.Errorf("view %q: select monitors by name or labels", v.Name)
+		}
+		for _, name := range v.Monitors {
+			if !known[name] {
+				return fmt.Errorf("view %q: unknown monitor %q", v.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// View returns the view with the given name, or nil.
+func (c *Config) View(name string) *ViewConfig {
+	for i := range c.Views {
+		if c.Views[i].Name == name {
+			return &c.Views[i]
+		}
+	}
+	return nil
+}
+
+// cloneView copies a view's slices and maps, see Redacted.
+func cloneView(v ViewConfig) ViewConfig {
+	v.Monitors = slices.Clone(v.Monitors)
+	v.Labels = maps.Clone(v.Labels)
+	return v
+}
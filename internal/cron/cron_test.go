@@ -0,0 +1,69 @@
+package cron
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(s string) time.Time {
+		ts, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	local := func(s string) time.Time {
+		ts, err := time.ParseInLocation("2006-01-02 15:04", s, berlin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+
+	tests := []struct {
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{"*/5 * * * *", utc("2026-10-16T10:02:30Z"), utc("2026-10-16T10:05:00Z")},
+		{"*/5 * * * *", utc("2026-10-16T10:05:00Z"), utc("2026-10-16T10:10:00Z")},
+		{"0 3 * * *", utc("2026-10-16T03:00:00Z"), utc("2026-10-17T03:00:00Z")},
+		{"@hourly", utc("2026-10-16T23:59:00Z"), utc("2026-10-17T00:00:00Z")},
+		{"0 9 * * 1-5", utc("2026-10-16T10:00:00Z"), utc("2026-10-19T09:00:00Z")}, // Friday to Monday
+		{"0 0 1 * *", utc("2026-12-15T00:00:00Z"), utc("2027-01-01T00:00:00Z")},
+		{"0 0 29 2 *", utc("2026-03-01T00:00:00Z"), utc("2028-02-29T00:00:00Z")},
+		{"0 0 13 * 5", utc("2026-10-16T01:00:00Z"), utc("2026-10-23T00:00:00Z")}, // 13th or a Friday
+		{"0 0 30 2 *", utc("2026-01-01T00:00:00Z"), time.Time{}},
+		// Evaluated in the location of the time passed in
+		{"0 3 * * *", local("2026-10-16 12:00"), local("2026-10-17 03:00")},
+		{"30 2 * * *", local("2026-03-28 12:00"), local("2026-03-30 02:30")}, // no 02:30 on the 29th
+		{"0 * * * *", local("2026-03-29 01:30"), local("2026-03-29 03:00")},  // 02:00 doesn't exist
+		{"0 12 * * *", local("2026-10-24 13:00"), local("2026-10-25 12:00")}, // 24h + 1 across the change
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr+" after "+tt.after.Format(time.RFC3339), func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := s.Next(tt.after)
+			if !got.Equal(tt.want) {
+				t.Errorf("Next = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "@sometimes"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expr)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package expr
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Env is what an expression is evaluated against: the observed fields of
+// an HTTP check.
+type Env struct {
+	Status  int
+	Latency time.Duration
+	Body    string
+	Size    int64
+	Header  http.Header
+}
+
+// Expr is a parsed and type checked boolean expression like
+//
+//	status == 200 && latency < 500ms && body contains "ok"
+//
+// Operands are the variables status, latency, size and body, header("Name"),
+// and number, duration (500ms, 2s) and "string" literals. Operators, by
+// increasing precedence: ||, &&, !, then the comparisons == != < <= > >=
+// and, for strings, contains and matches (a regexp literal).
+type Expr struct {
+	src  string
+	eval func(*Env) value
+}
+
+// Parse parses and type checks an expression, see Expr.
+func Parse(src string) (*Expr, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %s at offset %d", t, t.pos)
+	}
+	if n.typ != typBool {
+		return nil, fmt.Errorf("expression is a %s, not true or false", n.typ)
+	}
+	return &Expr{src: src, eval: n.eval}, nil
+}
+
+// Eval reports whether the expression holds for env.
+func (e *Expr) Eval(env *Env) bool {
+	return e.eval(env).b
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+type typ int
+
+const (
+	typBool typ = iota
+	typNum
+	typDur
+	typStr
+)
+
+func (t typ) String() string {
+	return [...]string{"boolean", "number", "duration", "string"}[t]
+}
+
+// value is the result of evaluating a node, n holds numbers and durations
+// (in nanoseconds) alike.
+type value struct {
+	b bool
+	n float64
+	s string
+}
+
+// node is a type checked, compiled piece of an expression.
+type node struct {
+	typ  typ
+	eval func(*Env) value
+}
+
+// variables are the identifiers an expression can use.
+var variables = map[string]node{
+	"status":  {typNum, func(env *Env) value { return value{n: float64(env.Status)} }},
+	"latency": {typDur, func(env *Env) value { return value{n: float64(env.Latency)} }},
+	"size":    {typNum, func(env *Env) value { return value{n: float64(env.Size)} }},
+	"body":    {typStr, func(env *Env) value { return value{s: env.Body} }},
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(kind tokKind, text string) error {
+	if t := p.next(); t.kind != kind || (text != "" && t.text != text) {
+		return fmt.Errorf("expected %q, got %s at offset %d", text, t, t.pos)
+	}
+	return nil
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return node{}, err
+	}
+	for p.peek().is("||") {
+		op := p.next()
+		right, err := p.and()
+		if err != nil {
+			return node{}, err
+		}
+		if err := bothBool(op, left, right); err != nil {
+			return node{}, err
+		}
+		l, r := left.eval, right.eval
+		left = node{typBool, func(env *Env) value { return value{b: l(env).b || r(env).b} }}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.not()
+	if err != nil {
+		return node{}, err
+	}
+	for p.peek().is("&&") {
+		op := p.next()
+		right, err := p.not()
+		if err != nil {
+			return node{}, err
+		}
+		if err := bothBool(op, left, right); err != nil {
+			return node{}, err
+		}
+		l, r := left.eval, right.eval
+		left = node{typBool, func(env *Env) value { return value{b: l(env).b && r(env).b} }}
+	}
+	return left, nil
+}
+
+func (p *parser) not() (node, error) {
+	if !p.peek().is("!") {
+		return p.comparison()
+	}
+	op := p.next()
+	n, err := p.not()
+	if err != nil {
+		return node{}, err
+	}
+	if n.typ != typBool {
+		return node{}, fmt.Errorf("! needs a boolean, got a %s at offset %d", n.typ, op.pos)
+	}
+	return node{typBool, func(env *Env) value { return value{b: !n.eval(env).b} }}, nil
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.operand()
+	if err != nil {
+		return node{}, err
+	}
+	op := p.peek()
+	switch {
+	case op.is("==", "!=", "<", "<=", ">", ">="):
+		p.next()
+		right, err := p.operand()
+		if err != nil {
+			return node{}, err
+		}
+		return compare(op, left, right)
+	case op.kind == tokIdent && (op.text == "contains" || op.text == "matches"):
+		p.next()
+		if left.typ != typStr {
+			return node{}, fmt.Errorf("%s needs a string on the left, got a %s at offset %d", op.text, left.typ, op.pos)
+		}
+		lit := p.next()
+		if lit.kind != tokString {
+			return node{}, fmt.Errorf("%s needs a string literal, got %s at offset %d", op.text, lit, lit.pos)
+		}
+		l := left.eval
+		if op.text == "contains" {
+			return node{typBool, func(env *Env) value { return value{b: strings.Contains(l(env).s, lit.text)} }}, nil
+		}
+		re, err := regexp.Compile(lit.text)
+		if err != nil {
+			return node{}, fmt.Errorf("invalid regexp at offset %d: %w", lit.pos, err)
+		}
+		return node{typBool, func(env *Env) value { return value{b: re.MatchString(l(env).s)} }}, nil
+	}
+	return left, nil
+}
+
+// compare builds a comparison of two operands of the same type.
+func compare(op token, left, right node) (node, error) {
+	if left.typ != right.typ {
+		hint := ""
+		if left.typ == typDur || right.typ == typDur {
+			hint = " (durations need a unit, e.g. 500ms)"
+		}
+		return node{}, fmt.Errorf("can't compare a %s with a %s at offset %d%s", left.typ, right.typ, op.pos, hint)
+	}
+	ordered := op.text != "==" && op.text != "!="
+	if ordered && left.typ != typNum && left.typ != typDur {
+		return node{}, fmt.Errorf("%s needs numbers or durations, got a %s at offset %d", op.text, left.typ, op.pos)
+	}
+
+	l, r := left.eval, right.eval
+	var test func(a, b value) bool
+	switch op.text {
+	case "==":
+		test = func(a, b value) bool { return a == b }
+	case "!=":
+		test = func(a, b value) bool { return a != b }
+	case "<":
+		test = func(a, b value) bool { return a.n < b.n }
+	case "<=":
+		test = func(a, b value) bool { return a.n <= b.n }
+	case ">":
+		test = func(a, b value) bool { return a.n > b.n }
+	default:
+		test = func(a, b value) bool { return a.n >= b.n }
+	}
+	return node{typBool, func(env *Env) value { return value{b: test(l(env), r(env))} }}, nil
+}
+
+func bothBool(op token, left, right node) error {
+	if left.typ != typBool || right.typ != typBool {
+		return fmt.Errorf("%s needs booleans on both sides at offset %d", op.text, op.pos)
+	}
+	return nil
+}
+
+func (p *parser) operand() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return node{}, fmt.Errorf("invalid number %q at offset %d", t.text, t.pos)
+		}
+		return constant(typNum, value{n: n}), nil
+	case tokDuration:
+		d, err := time.ParseDuration(t.text)
+		if err != nil {
+			return node{}, fmt.Errorf("invalid duration %q at offset %d", t.text, t.pos)
+		}
+		return constant(typDur, value{n: float64(d)}), nil
+	case tokString:
+		return constant(typStr, value{s: t.text}), nil
+	case tokIdent:
+		switch t.text {
+		case "true", "false":
+			return constant(typBool, value{b: t.text == "true"}), nil
+		case "header":
+			if err := p.expect(tokPunct, "("); err != nil {
+				return node{}, err
+			}
+			name := p.next()
+			if name.kind != tokString {
+				return node{}, fmt.Errorf("header needs a quoted name, got %s at offset %d", name, name.pos)
+			}
+			if err := p.expect(tokPunct, ")"); err != nil {
+				return node{}, err
+			}
+			return node{typStr, func(env *Env) value { return value{s: env.Header.Get(name.text)} }}, nil
+		}
+		if v, ok := variables[t.text]; ok {
+			return v, nil
+		}
+		return node{}, fmt.Errorf("unknown variable %q at offset %d (want status, latency, size, body or header(\"Name\"))", t.text, t.pos)
+	case tokPunct:
+		if t.text == "(" {
+			n, err := p.or()
+			if err != nil {
+				return node{}, err
+			}
+			if err := p.expect(tokPunct, ")"); err != nil {
+				return node{}, err
+			}
+			return n, nil
+		}
+	}
+	return node{}, fmt.Errorf("unexpected %s at offset %d", t, t.pos)
+}
+
+func constant(t typ, v value) node {
+	return node{t, func(*Env) value { return v }}
+}
@@ -0,0 +1,85 @@
+package expr
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEval(t *testing.T) {
+	env := &Env{
+		Status:  200,
+		Latency: 120 * time.Millisecond,
+		Body:    `{"status": "ok", "version": "2.1"}`,
+		Size:    34,
+		Header:  http.Header{"Content-Type": {"application/json"}},
+	}
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{`status == 200`, true},
+		{`status != 200`, false},
+		{`status >= 200 && status < 300`, true},
+		{`latency < 500ms`, true},
+		{`latency <= 100ms`, false},
+		{`latency > 0.1s`, true},
+		{`size > 1000`, false},
+		{`body contains "ok"`, true},
+		{`body contains "error"`, false},
+		{`body matches "\"version\": \"2\\.[0-9]+\""`, true},
+		{`header("content-type") == "application/json"`, true},
+		{`header("X-Missing") == ""`, true},
+		{`status == 200 && latency < 500ms && body contains "ok"`, true},
+		{`status == 500 || body contains "ok"`, true},
+		{`!(status == 200)`, false},
+		{`!!true`, true},
+		// && binds tighter than ||
+		{`true || false && false`, true},
+		{`(true || false) && false`, false},
+		{`(status == 200) == true`, true},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		if err != nil {
+			t.Errorf("Parse(%s): %v", tt.src, err)
+			continue
+		}
+		if got := e.Eval(env); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.src, got, tt.want)
+		}
+		if e.String() != tt.src {
+			t.Errorf("String() = %q, want %q", e.String(), tt.src)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src     string
+		wantErr string
+	}{
+		{`status`, "is a number, not true or false"},
+		{`latency < 500`, "durations need a unit"},
+		{`status == "200"`, "can't compare a number with a string"},
+		{`body < "z"`, "< needs numbers or durations"},
+		{`status contains "2"`, "contains needs a string on the left"},
+		{`body contains ok`, "needs a string literal"},
+		{`body matches "("`, "invalid regexp"},
+		{`!status`, "! needs a boolean"},
+		{`status == 200 && 1`, "&& needs booleans"},
+		{`code == 200`, `unknown variable "code"`},
+		{`header(Name) == ""`, "header needs a quoted name"},
+		{`(status == 200`, `expected ")"`},
+		{`status == 200 )`, "unexpected"},
+		{`status == 200 == true`, "unexpected \"==\""},
+		{`body contains "ok`, ""},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Parse(%s): err = %v, want it to mention %q", tt.src, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package expr
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokNumber
+	tokDuration
+	tokString
+	tokPunct
+)
+
+type token struct {
+	kind tokKind
+	text string // unquoted for strings
+	pos  int
+}
+
+// is reports whether t is one of the given operators or parentheses.
+func (t token) is(punct ...string) bool {
+	return t.kind == tokPunct && slices.Contains(punct, t.text)
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// operators, two character ones first so they win over their prefixes.
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(src) && src[end] != '"' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			s, err := strconv.Unquote(src[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d: %w", i, err)
+			}
+			toks = append(toks, token{tokString, s, i})
+			i = end + 1
+		case isDigit(c):
+			end := i
+			for end < len(src) && (isDigit(src[end]) || src[end] == '.') {
+				end++
+			}
+			kind := tokNumber
+			// A unit makes it a duration: 500ms, 1.5s, 1m30s
+			for end < len(src) && (isLetter(src[end]) || isDigit(src[end]) || src[end] == '.') {
+				kind = tokDuration
+				end++
+			}
+			toks = append(toks, token{kind, src[i:end], i})
+			i = end
+		case isLetter(c) || c == '_':
+			end := i
+			for end < len(src) && (isLetter(src[end]) || isDigit(src[end]) || src[end] == '_') {
+				end++
+			}
+			toks = append(toks, token{tokIdent, src[i:end], i})
+			i = end
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at offset %d", c, i)
+			}
+			toks = append(toks, token{tokPunct, op, i})
+			i += len(op)
+		}
+	}
+	return append(toks, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
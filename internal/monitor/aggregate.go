@@ -0,0 +1,86 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// updateAggregates recomputes every aggregate monitor that has child as one
+// of its children. Called after each check of child.
+func (e *Engine) updateAggregates(child string) {
+	e.mu.RLock()
+	parents := e.parents[child]
+	e.mu.RUnlock()
+	for _, parent := range parents {
+		e.evaluateAggregate(parent)
+	}
+}
+
+// evaluateAggregate derives a parent's status from its children's current
+// state. Nothing is recorded until every child has been checked at least
+// once, otherwise startup would produce a bogus DOWN -> UP transition.
+func (e *Engine) evaluateAggregate(m config.MonitorConfig) {
+	e.aggMu.Lock()
+	defer e.aggMu.Unlock()
+
+	now := time.Now()
+
+	e.mu.RLock()
+	var down []string
+	up := 0
+	for _, child := range m.Children {
+		st, ok := e.states[child]
+		if !ok || !st.checked {
+			e.mu.RUnlock()
+			return
+		}
+		if st.isUp {
+			up++
+		} else {
+			down = append(down, child)
+		}
+	}
+	prev, hasPrev := e.states[m.Name]
+	changed := !hasPrev || !prev.checked || prev.isUp != aggregateUp(m, up)
+	recent := hasPrev && now.Sub(prev.lastCheck) < aggregateInterval(e.Config(), m)
+	e.mu.RUnlock()
+
+	// Children report far more often than we want rows for the parent;
+	// record on changes and otherwise at the parent's own interval.
+	if !changed && recent {
+		return
+	}
+
+	result := CheckResult{
+		MonitorName: m.Name,
+		Timestamp:   now,
+		Status:      aggregateUp(m, up),
+	}
+	if len(down) > 0 {
+		result.Error = fmt.Sprintf("%d/%d children up, down: %s", up, len(m.Children), strings.Join(down, ", "))
+	}
+	e.recordResult(m, result)
+}
+
+// aggregateUp applies the monitor's aggregation mode to the number of
+// children that are up.
+func aggregateUp(m config.MonitorConfig, up int) bool {
+	switch m.Aggregation {
+	case "any":
+		return up > 0
+	case "quorum":
+		return up >= m.Quorum
+	default: // "all"
+		return up == len(m.Children)
+	}
+}
+
+func aggregateInterval(cfg *config.Config, m config.MonitorConfig) time.Duration {
+	if m.Interval != "" {
+		return config.ParseDuration(m.Interval)
+	}
+	return config.ParseDuration(cfg.Global.CheckInterval)
+}
@@ -0,0 +1,66 @@
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestAggregateStatus(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	tests := []struct {
+		name        string
+		aggregation string
+		children    []string // "up..." children are reachable, "down..." ones aren't
+		want        bool
+	}{
+		{"all up", "all", []string{"up1", "up2"}, true},
+		{"all, one down", "all", []string{"up1", "down1"}, false},
+		{"any, one up", "any", []string{"up1", "down1"}, true},
+		{"any, none up", "any", []string{"down1", "down2"}, false},
+		{"quorum met", "quorum, quorum: 2", []string{"up1", "up2", "down1"}, true},
+		{"quorum missed", "quorum, quorum: 2", []string{"up1", "down1", "down2"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var src strings.Builder
+			src.WriteString("global: {check_interval: 1h}\nmonitors:\n")
+			for _, child := range tt.children {
+				childPort := port
+				if strings.HasPrefix(child, "down") {
+					childPort = 1
+				}
+				fmt.Fprintf(&src, "  - {name: %s, type: tcp, host: 127.0.0.1, port: %d}\n", child, childPort)
+			}
+			fmt.Fprintf(&src, "  - {name: svc, type: aggregate, children: [%s], aggregation: %s}\n",
+				strings.Join(tt.children, ", "), tt.aggregation)
+			st := &memStore{}
+			e := NewEngine(testConfig(t, src.String()), st, nil)
+
+			for i, child := range tt.children {
+				if _, err := e.CheckNow(child); err != nil {
+					t.Fatal(err)
+				}
+				// Nothing to say until every child has been checked
+				if i < len(tt.children)-1 && e.State("svc").Checked {
+					t.Fatalf("parent has a status after %d of %d children were checked", i+1, len(tt.children))
+				}
+			}
+			if s := e.State("svc"); !s.Checked || s.IsUp != tt.want {
+				t.Errorf("parent checked %v, up %v; want up %v", s.Checked, s.IsUp, tt.want)
+			}
+			if checks := st.checks("svc"); len(checks) != 1 {
+				t.Errorf("%d parent results stored, want 1", len(checks))
+			} else if down := strings.Contains(checks[0].Error, "down:"); down != strings.Contains(strings.Join(tt.children, ","), "down") {
+				t.Errorf("parent error %q doesn't match the children", checks[0].Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// anomalyBaselineTTL is how long a monitor's latency baseline is reused
+// before it's queried from the store again.
+const anomalyBaselineTTL = time.Minute
+
+// LatencyStats computes latency percentiles over stored checks. It's
+// optional like RuntimeStore: latency_anomaly does nothing when the
+// engine's Store doesn't implement it.
+type LatencyStats interface {
+	LatencyPercentile(monitorName string, since time.Time, percentile float64) (time.Duration, int, error)
+}
+
+// latencyBaseline is a cached LatencyStats answer.
+type latencyBaseline struct {
+	latency time.Duration
+	samples int
+	at      time.Time
+}
+
+// checkLatencyAnomaly marks an UP result degraded or down, as configured,
+// when it took more than latency_anomaly.multiplier times the monitor's
+// baseline percentile. Results that are already degraded are left alone.
+func (e *Engine) checkLatencyAnomaly(m config.MonitorConfig, result *CheckResult) {
+	la := m.LatencyAnomaly
+	if la == nil || !result.Status || result.Degraded {
+		return
+	}
+	stats, ok := e.Store.(LatencyStats)
+	if !ok {
+		return
+	}
+	base, ok := e.latencyBaseline(stats, m, result.Timestamp)
+	if !ok || base.samples < la.MinSamples || base.latency <= 0 {
+		return
+	}
+	ratio := float64(result.Latency) / float64(base.latency)
+	if ratio <= la.Multiplier {
+		return
+	}
+
+	msg := fmt.Sprintf("latency %s is %.1fx the p%g of the last %s (%s)",
+		result.Latency.Round(time.Millisecond), ratio, la.Percentile, la.Window, base.latency.Round(time.Millisecond))
+	if la.State == config.StateDown {
+		result.Status = false
+	} else {
+		result.Degraded = true
+	}
+	result.Error = msg
+}
+
+// latencyBaseline returns the monitor's baseline, from the cache while
+// it's fresh. ok is false when it can't be computed.
+func (e *Engine) latencyBaseline(stats LatencyStats, m config.MonitorConfig, now time.Time) (latencyBaseline, bool) {
+	e.mu.RLock()
+	var cached *latencyBaseline
+	if st, ok := e.states[m.Name]; ok {
+		cached = st.baseline
+	}
+	e.mu.RUnlock()
+	if cached != nil && now.Sub(cached.at) < anomalyBaselineTTL {
+		return *cached, true
+	}
+
+	la := m.LatencyAnomaly
+	latency, samples, err := stats.LatencyPercentile(m.Name, now.Add(-config.ParseDuration(la.Window)), la.Percentile)
+	if err != nil {
+		log.Printf("Monitor %s: failed to compute latency baseline: %v", m.Name, err)
+		return latencyBaseline{}, false
+	}
+	base := latencyBaseline{latency: latency, samples: samples, at: now}
+
+	e.mu.Lock()
+	e.stateFor(m.Name).baseline = &base
+	e.mu.Unlock()
+	return base, true
+}
@@ -0,0 +1,80 @@
+package monitor
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// latencyStore is a memStore that computes latency percentiles the way
+// the SQLite store does (nearest rank over UP checks).
+type latencyStore struct {
+	memStore
+}
+
+func (s *latencyStore) LatencyPercentile(monitorName string, since time.Time, percentile float64) (time.Duration, int, error) {
+	var latencies []time.Duration
+	for _, r := range s.checks(monitorName) {
+		if r.Status && !r.Timestamp.Before(since) {
+			latencies = append(latencies, r.Latency)
+		}
+	}
+	if len(latencies) == 0 {
+		return 0, 0, nil
+	}
+	slices.Sort(latencies)
+	rank := int(math.Ceil(percentile / 100 * float64(len(latencies))))
+	return latencies[max(rank-1, 0)], len(latencies), nil
+}
+
+func TestLatencyAnomaly(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    string
+		baseline int // UP checks of 10ms, 11ms, ... before the one checked
+		result   CheckResult
+		wantUp   bool
+		wantDeg  bool
+		wantErr  string
+	}{
+		// p95 of 10..29ms is 28ms, twice that 56ms
+		{"within the multiplier", "", 20, CheckResult{Status: true, Latency: 50 * time.Millisecond}, true, false, ""},
+		{"spike", "", 20, CheckResult{Status: true, Latency: 60 * time.Millisecond}, true, true, "latency 60ms is 2.1x the p95 of the last 24h (28ms)"},
+		{"spike counts as down", "state: down", 20, CheckResult{Status: true, Latency: 60 * time.Millisecond}, false, false, "2.1x the p95"},
+		{"too little history", "", 10, CheckResult{Status: true, Latency: time.Second}, true, false, ""},
+		{"already down", "", 20, CheckResult{Status: false, Latency: time.Second, Error: "timeout"}, false, false, "timeout"},
+		{"already degraded", "", 20, CheckResult{Status: true, Degraded: true, Latency: time.Second, Error: "status 203"}, true, true, "status 203"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t, `
+monitors:
+  - {name: api, type: tcp, host: 127.0.0.1, port: 1, latency_anomaly: {`+tt.state+`}}
+`)
+			m := cfg.Monitors[0]
+			e := NewEngine(cfg, &latencyStore{}, nil)
+			now := time.Now()
+			for i := range tt.baseline {
+				e.recordResult(m, CheckResult{
+					MonitorName: "api",
+					Timestamp:   now.Add(time.Duration(i-tt.baseline) * time.Minute),
+					Status:      true,
+					Latency:     time.Duration(10+i) * time.Millisecond,
+				})
+			}
+
+			res := tt.result
+			res.MonitorName = "api"
+			res.Timestamp = now
+			e.checkLatencyAnomaly(m, &res)
+			if res.Status != tt.wantUp || res.Degraded != tt.wantDeg {
+				t.Errorf("up %v, degraded %v; want %v, %v", res.Status, res.Degraded, tt.wantUp, tt.wantDeg)
+			}
+			if !strings.Contains(res.Error, tt.wantErr) || (tt.wantErr == "" && res.Error != "") {
+				t.Errorf("error %q, want %q", res.Error, tt.wantErr)
+			}
+		})
+	}
+}
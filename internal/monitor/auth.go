@@ -0,0 +1,121 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// maxAuthBodyBytes caps how much of a login response is read.
+const maxAuthBodyBytes = 1 << 20
+
+// authenticate runs the monitor's auth step and adds the token it yields
+// to req. Errors are prefixed with "auth:" so a failed login is easy to
+// tell apart from a failing endpoint.
+func authenticate(client *http.Client, req *http.Request, a *config.AuthConfig) error {
+	token, err := fetchToken(req.Context(), client, req.Header.Get("User-Agent"), a)
+	if err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+	if a.InjectCookie != "" {
+		req.AddCookie(&http.Cookie{Name: a.InjectCookie, Value: token})
+	} else {
+		req.Header.Set(a.InjectHeader, strings.ReplaceAll(a.InjectFormat, config.TokenPlaceholder, token))
+	}
+	return nil
+}
+
+func fetchToken(ctx context.Context, client *http.Client, userAgent string, a *config.AuthConfig) (string, error) {
+	var body io.Reader
+	if a.Body != "" {
+		body = strings.NewReader(a.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, a.Method, a.URL, body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	for k, v := range a.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("login returned status code %d", resp.StatusCode)
+	}
+
+	var token string
+	switch {
+	case a.TokenHeader != "":
+		token = resp.Header.Get(a.TokenHeader)
+		if token == "" {
+			return "", fmt.Errorf("header %s missing", a.TokenHeader)
+		}
+	case a.TokenCookie != "":
+		for _, c := range resp.Cookies() {
+			if c.Name == a.TokenCookie {
+				token = c.Value
+			}
+		}
+		if token == "" {
+			return "", fmt.Errorf("cookie %s missing", a.TokenCookie)
+		}
+	default:
+		data, err := readBody(resp, maxAuthBodyBytes)
+		if err != nil {
+			return "", fmt.Errorf("failed to read body: %w", err)
+		}
+		token, err = lookupJSON(data, a.TokenJSON)
+		if err != nil {
+			return "", err
+		}
+	}
+	return token, nil
+}
+
+// lookupJSON follows a dotted path ("data.tokens.0.value") through a JSON
+// document. The value found must be a string or a number.
+func lookupJSON(data []byte, path string) (string, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return "", fmt.Errorf("body is not JSON: %w", err)
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			next, ok := node[key]
+			if !ok {
+				return "", fmt.Errorf("%s not found in body", path)
+			}
+			v = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", fmt.Errorf("%s not found in body", path)
+			}
+			v = node[i]
+		default:
+			return "", fmt.Errorf("%s not found in body", path)
+		}
+	}
+	switch t := v.(type) {
+	case string:
+		if t == "" {
+			return "", fmt.Errorf("%s is empty", path)
+		}
+		return t, nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("%s is not a string", path)
+}
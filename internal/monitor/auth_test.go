@@ -0,0 +1,68 @@
+package monitor
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// authServer issues tokens on /login and only serves /resource with one
+// of them.
+func authServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"user":"zen"}` || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-Token", "header-token")
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "cookie-token"})
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"access_token": "json-token", "ids": []any{7}}})
+	})
+	mux.HandleFunc("GET /resource", func(w http.ResponseWriter, r *http.Request) {
+		cookie, _ := r.Cookie("session")
+		switch {
+		case r.Header.Get("Authorization") == "Bearer json-token":
+		case r.Header.Get("X-Api-Key") == "Token header-token":
+		case r.Header.Get("X-Id") == "7":
+		case cookie != nil && cookie.Value == "cookie-token":
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHTTPAuth(t *testing.T) {
+	srv := authServer(t)
+	login := `url: "` + srv.URL + `/login", body: '{"user":"zen"}', headers: {Content-Type: application/json}`
+	tests := []struct {
+		name    string
+		auth    string
+		wantUp  bool
+		wantErr string
+	}{
+		{"JSON token as bearer", login + ", token_json: data.access_token", true, ""},
+		{"header token with a format", login + `, token_header: X-Token, inject_header: X-Api-Key, inject_format: "Token {token}"`, true, ""},
+		{"number from a JSON array", login + ", token_json: data.ids.0, inject_header: X-Id, inject_format: '{token}'", true, ""},
+		{"cookie passed on", login + ", token_cookie: session", true, ""},
+		{"wrong token", login + ", token_header: X-Token", false, "status code 401"},
+		{"token missing", login + ", token_json: data.refresh_token", false, "auth: data.refresh_token not found in body"},
+		{"login rejected", `url: "` + srv.URL + `/login", body: nope, token_json: data.access_token`, false, "auth: login returned status code 401"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := runHTTP(t, srv.URL+"/resource", ", auth: {"+tt.auth+"}")
+			if res.Status != tt.wantUp || !strings.Contains(res.Error, tt.wantErr) {
+				t.Errorf("up %v (%q), want %v (%q)", res.Status, res.Error, tt.wantUp, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package monitor
+
+import (
+	"log"
+	"time"
+)
+
+// clockSkewTolerance is how far timestamps may be off before it's reported.
+const clockSkewTolerance = 5 * time.Second
+
+// checkClock looks for results whose timestamp doesn't fit the clock: the
+// wall clock jumping since the previous check (caught by comparing it with
+// the monotonic clock), a timestamp older than the previous one, or one in
+// the future, e.g. from a pushing agent with a bad clock. Anomalies are
+// logged and counted; future timestamps are clamped to now, since they'd
+// sort after every real check.
+func (e *Engine) checkClock(result *CheckResult, prev time.Time) {
+	now := time.Now()
+	ts := result.Timestamp
+
+	// Round(0) drops the monotonic reading, leaving wall clock time
+	switch {
+	case ts.Round(0).After(now.Round(0).Add(clockSkewTolerance)):
+		e.clockAnomalies.Add(1)
+		log.Printf("Monitor %s: result timestamp %s is in the future, using now instead", result.MonitorName, ts.Format(time.RFC3339))
+		result.Timestamp = now
+	case prev.IsZero():
+	default:
+		wall := ts.Round(0).Sub(prev.Round(0))
+		// Sub uses the monotonic clock when both times have one, so
+		// this is how far the wall clock moved on its own
+		if jump := wall - ts.Sub(prev); jump > clockSkewTolerance || jump < -clockSkewTolerance {
+			e.clockAnomalies.Add(1)
+			log.Printf("Monitor %s: system clock jumped by %s since the previous check", result.MonitorName, jump.Round(time.Second))
+		} else if wall < -clockSkewTolerance {
+			e.clockAnomalies.Add(1)
+			log.Printf("Monitor %s: result timestamp %s is %s older than the previous one", result.MonitorName, ts.Format(time.RFC3339), (-wall).Round(time.Second))
+		}
+	}
+}
+
+// ClockAnomalies returns how many results had a suspicious timestamp.
+func (e *Engine) ClockAnomalies() uint64 {
+	return e.clockAnomalies.Load()
+}
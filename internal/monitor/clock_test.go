@@ -0,0 +1,53 @@
+package monitor
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckClock(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	now := time.Now()
+	tests := []struct {
+		name    string
+		prev    time.Time
+		ts      time.Time
+		wantLog string // empty for no anomaly
+		clamped bool
+	}{
+		{name: "first check", ts: now},
+		{name: "in order", prev: now.Add(-time.Minute), ts: now},
+		{name: "slightly behind", prev: now.Round(0), ts: now.Round(0).Add(-time.Second)},
+		{name: "out of order", prev: now.Round(0), ts: now.Round(0).Add(-time.Hour), wantLog: "1h0m0s older than the previous one"},
+		{name: "future", prev: now.Add(-time.Minute), ts: now.Add(time.Hour), wantLog: "is in the future", clamped: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			e := NewEngine(testConfig(t, "global: {check_interval: 1h}\n"), &memStore{}, nil)
+			result := CheckResult{MonitorName: "api", Timestamp: tt.ts}
+			e.checkClock(&result, tt.prev)
+
+			want := uint64(0)
+			if tt.wantLog != "" {
+				want = 1
+			}
+			if got := e.ClockAnomalies(); got != want {
+				t.Errorf("ClockAnomalies() = %d, want %d", got, want)
+			}
+			if !strings.Contains(buf.String(), tt.wantLog) || (tt.wantLog == "" && buf.Len() > 0) {
+				t.Errorf("logged %q, want %q", buf.String(), tt.wantLog)
+			}
+			if clamped := !result.Timestamp.Equal(tt.ts); clamped != tt.clamped {
+				t.Errorf("timestamp changed to %s, want clamped %v", result.Timestamp, tt.clamped)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package monitor
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// Errors returned by CheckNow.
+var (
+	ErrUnknownMonitor = errors.New("no such monitor")
+	ErrNotCheckable   = errors.New("monitor can't be checked on demand")
+)
+
+// RuntimeState is the operator-controlled state of a monitor, set through
+// the API rather than the config file.
+type RuntimeState struct {
+	MonitorName string
+	Paused      bool      // no checks are run
+	MutedUntil  time.Time // alerts are swallowed until then; zero = not muted
+}
+
+// RuntimeStore persists RuntimeState across restarts. It's optional: the
+// engine uses it when its Store happens to implement it.
+type RuntimeStore interface {
+	SaveRuntimeState(rs RuntimeState) error
+	LoadRuntimeStates() ([]RuntimeState, error)
+}
+
+// loadRuntimeStates restores paused/muted monitors saved by a previous run.
+// Entries for monitors that are no longer configured and mutes that have
+// expired in the meantime are ignored. Only the first call does anything.
+func (e *Engine) loadRuntimeStates() {
+	e.runtimeOnce.Do(e.doLoadRuntimeStates)
+}
+
+func (e *Engine) doLoadRuntimeStates() {
+	rs, ok := e.Store.(RuntimeStore)
+	if !ok {
+		return
+	}
+	saved, err := rs.LoadRuntimeStates()
+	if err != nil {
+		log.Printf("Failed to load runtime monitor state: %v", err)
+		return
+	}
+
+	cfg := e.Config()
+	known := make(map[string]bool, len(cfg.Monitors))
+	for _, m := range cfg.Monitors {
+		known[m.Name] = true
+	}
+
+	now := time.Now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range saved {
+		if !known[s.MonitorName] {
+			continue
+		}
+		st := e.stateFor(s.MonitorName)
+		st.paused = s.Paused
+		if s.MutedUntil.After(now) {
+			st.mutedUntil = s.MutedUntil
+		}
+		if st.paused {
+			log.Printf("Monitor %s: paused (restored from previous run)", s.MonitorName)
+		}
+		if !st.mutedUntil.IsZero() {
+			log.Printf("Monitor %s: muted until %s (restored from previous run)", s.MonitorName, st.mutedUntil.Format(time.RFC3339))
+		}
+	}
+}
+
+// Pause stops checks of a monitor until Resume is called.
+func (e *Engine) Pause(name string) error {
+	return e.updateRuntimeState(name, func(st *monitorState) { st.paused = true })
+}
+
+// Resume undoes Pause.
+func (e *Engine) Resume(name string) error {
+	return e.updateRuntimeState(name, func(st *monitorState) { st.paused = false })
+}
+
+// Mute swallows a monitor's alerts until the given time. Checks keep running.
+func (e *Engine) Mute(name string, until time.Time) error {
+	return e.updateRuntimeState(name, func(st *monitorState) { st.mutedUntil = until })
+}
+
+// Unmute undoes Mute.
+func (e *Engine) Unmute(name string) error {
+	return e.updateRuntimeState(name, func(st *monitorState) { st.mutedUntil = time.Time{} })
+}
+
+// CheckNow probes a monitor right away, e.g. after deploying a fix, and
+// records the result like a scheduled check: stored, state updated and
+// alerts sent. It waits for a check of the monitor already in progress.
+// Works on paused monitors too. Aggregate and push monitors can't be
+// checked, they have nothing to probe.
+func (e *Engine) CheckNow(name string) (CheckResult, error) {
+	var m *config.MonitorConfig
+	cfg := e.Config()
+	for i := range cfg.Monitors {
+		if cfg.Monitors[i].Name == name {
+			m = &cfg.Monitors[i]
+			break
+		}
+	}
+	if m == nil {
+		return CheckResult{}, ErrUnknownMonitor
+	}
+	if m.Type == "aggregate" || m.Type == "push" {
+		return CheckResult{}, ErrNotCheckable
+	}
+	return e.performCheck(*m), nil
+}
+
+// updateRuntimeState applies a change to a monitor's runtime state and
+// persists the result. The in-memory change sticks even if saving fails.
+func (e *Engine) updateRuntimeState(name string, apply func(st *monitorState)) error {
+	e.mu.Lock()
+	st := e.stateFor(name)
+	apply(st)
+	saved := RuntimeState{
+		MonitorName: name,
+		Paused:      st.paused,
+		MutedUntil:  st.mutedUntil,
+	}
+	e.mu.Unlock()
+
+	rs, ok := e.Store.(RuntimeStore)
+	if !ok {
+		return nil
+	}
+	return rs.SaveRuntimeState(saved)
+}
+
+func (e *Engine) isPaused(name string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	st, ok := e.states[name]
+	return ok && st.paused
+}
+
+func (e *Engine) isMuted(name string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	st, ok := e.states[name]
+	return ok && time.Now().Before(st.mutedUntil)
+}
@@ -0,0 +1,66 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// dialTimeout bounds connection setup for TCP and HTTP checks.
+const dialTimeout = 10 * time.Second
+
+// newDialer returns the dialer used for a monitor's connections, honoring
+// per-monitor network settings such as a custom DNS resolver.
+func newDialer(m config.MonitorConfig) *net.Dialer {
+	d := &net.Dialer{Timeout: dialTimeout}
+	switch {
+	case isDoH(m.Resolver):
+		d.Resolver = newDoHResolver(m.Resolver)
+	case m.Resolver != "":
+		d.Resolver = newResolver(m.Resolver)
+	}
+	if m.SourceIP != "" {
+		// Validated in LoadConfig
+		d.LocalAddr = &net.TCPAddr{IP: net.ParseIP(m.SourceIP)}
+	}
+	return d
+}
+
+// dialContext dials like newDialer(m) would, explaining failures caused by
+// a source_ip that isn't assigned to this host.
+func dialContext(m config.MonitorConfig) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := newDialer(m)
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := d.DialContext(ctx, network, addr)
+		if err != nil && m.SourceIP != "" && errors.Is(err, syscall.EADDRNOTAVAIL) {
+			return nil, fmt.Errorf("source_ip %s is not usable on this host: %w", m.SourceIP, err)
+		}
+		return conn, err
+	}
+}
+
+// newResolver builds a resolver that sends all queries to the given DNS
+// server ("10.0.0.53" or "10.0.0.53:5353") instead of the system one.
+func newResolver(server string) *net.Resolver {
+	addr := resolverAddr(server)
+	return &net.Resolver{
+		PreferGo: true, // the cgo resolver would ignore Dial
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// resolverAddr adds the default DNS port when none is given.
+func resolverAddr(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(server, "53")
+}
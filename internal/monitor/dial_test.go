@@ -0,0 +1,204 @@
+package monitor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// dnsAnswer answers a DNS query for an A record with ip. Other queries get
+// an empty answer.
+func dnsAnswer(query []byte, ip net.IP) []byte {
+	// Skip the header and the question's name to its type and class
+	end := 12
+	for end < len(query) && query[end] != 0 {
+		end += int(query[end]) + 1
+	}
+	end += 5
+	if end > len(query) {
+		return nil
+	}
+	isA := binary.BigEndian.Uint16(query[end-4:]) == 1
+
+	resp := append([]byte(nil), query[:end]...)
+	binary.BigEndian.PutUint16(resp[2:], 0x8180) // response, recursion available
+	binary.BigEndian.PutUint16(resp[4:], 1)      // one question
+	binary.BigEndian.PutUint32(resp[8:], 0)      // no authority or additional records
+	if !isA {
+		binary.BigEndian.PutUint16(resp[6:], 0)
+		return resp
+	}
+	binary.BigEndian.PutUint16(resp[6:], 1)
+	resp = append(resp, 0xc0, 12) // the name from the question
+	resp = binary.BigEndian.AppendUint16(resp, 1)
+	resp = binary.BigEndian.AppendUint16(resp, 1)
+	resp = binary.BigEndian.AppendUint32(resp, 60)
+	resp = binary.BigEndian.AppendUint16(resp, 4)
+	return append(resp, ip.To4()...)
+}
+
+// queryName returns the name a DNS query asks about.
+func queryName(query []byte) string {
+	var labels []string
+	for i := 12; i < len(query) && query[i] != 0; i += int(query[i]) + 1 {
+		labels = append(labels, string(query[i+1:i+1+int(query[i])]))
+	}
+	return strings.Join(labels, ".")
+}
+
+// fakeDNS serves DNS over UDP, resolving every name to ip. It returns its
+// address and a function listing the names queried so far.
+func fakeDNS(t *testing.T, ip string) (addr string, queried func() []string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	var mu sync.Mutex
+	var names []string
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			names = append(names, queryName(buf[:n]))
+			mu.Unlock()
+			conn.WriteTo(dnsAnswer(buf[:n], net.ParseIP(ip)), from)
+		}
+	}()
+	return conn.LocalAddr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), names...)
+	}
+}
+
+func TestResolver(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	tests := []struct {
+		name   string
+		ip     string // what the DNS server resolves to
+		wantUp bool
+	}{
+		{"resolves to the server", "127.0.0.1", true},
+		{"resolves elsewhere", "127.0.0.2", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dns, queried := fakeDNS(t, tt.ip)
+			res := runHTTP(t, "http://service.zenmonitor.test:"+port+"/", fmt.Sprintf(", resolver: %q", dns))
+			if res.Status != tt.wantUp {
+				t.Errorf("status %v (%s), want %v", res.Status, res.Error, tt.wantUp)
+			}
+			names := queried()
+			if len(names) == 0 || names[0] != "service.zenmonitor.test" {
+				t.Errorf("DNS server was asked about %q, want service.zenmonitor.test", names)
+			}
+		})
+	}
+}
+
+func TestDoHResolver(t *testing.T) {
+	var hits atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits.Add(1) }))
+	defer target.Close()
+	_, port, _ := net.SplitHostPort(target.Listener.Addr().String())
+
+	tests := []struct {
+		name     string
+		ip       string // what the DoH server resolves to, "" to fail
+		wantUp   bool
+		wantErr  string
+		wantHits int32
+	}{
+		{"resolves to the server", "127.0.0.1", true, "", 1},
+		{"resolves elsewhere", "127.0.0.2", false, "", 0},
+		{"endpoint fails", "", false, "dns-over-https", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var names []string
+			doh := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query, _ := io.ReadAll(r.Body)
+				if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" {
+					t.Errorf("DoH request %s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+				}
+				mu.Lock()
+				names = append(names, queryName(query))
+				mu.Unlock()
+				if tt.ip == "" {
+					http.Error(w, "no", http.StatusBadGateway)
+					return
+				}
+				w.Header().Set("Content-Type", "application/dns-message")
+				w.Write(dnsAnswer(query, net.ParseIP(tt.ip)))
+			}), trustedCerts[0], nil)
+
+			hits.Store(0)
+			res := runHTTP(t, "http://service.zenmonitor.test:"+port+"/", fmt.Sprintf(", resolver: %q", doh.URL+"/dns-query"))
+			if res.Status != tt.wantUp || !strings.Contains(res.Error, tt.wantErr) {
+				t.Errorf("status %v (%q), want %v (%q)", res.Status, res.Error, tt.wantUp, tt.wantErr)
+			}
+			if got := hits.Load(); got != tt.wantHits {
+				t.Errorf("target got %d requests, want %d", got, tt.wantHits)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(names) == 0 || names[0] != "service.zenmonitor.test" {
+				t.Errorf("DoH server was asked about %q, want service.zenmonitor.test", names)
+			}
+		})
+	}
+}
+
+func TestSourceIP(t *testing.T) {
+	var remote atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		remote.Store(host)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		sourceIP string
+		want     string // address the server saw
+		wantErr  string
+	}{
+		{"default", "", "127.0.0.1", ""},
+		{"loopback alias", "127.0.0.2", "127.0.0.2", ""},
+		{"not on this host", "192.0.2.1", "", "source_ip 192.0.2.1 is not usable on this host"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote.Store("")
+			opts := ""
+			if tt.sourceIP != "" {
+				opts = fmt.Sprintf(", source_ip: %q", tt.sourceIP)
+			}
+			res := runHTTP(t, srv.URL, opts)
+			if res.Status != (tt.wantErr == "") || !strings.Contains(res.Error, tt.wantErr) {
+				t.Fatalf("status %v (%q), want error %q", res.Status, res.Error, tt.wantErr)
+			}
+			if got := remote.Load(); got != tt.want {
+				t.Errorf("connection from %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dohClient sends DNS-over-HTTPS queries. The DoH server's own name is
+// looked up with the system resolver, so locked-down hosts may want an IP
+// in the URL (https://1.1.1.1/dns-query).
+var dohClient = &http.Client{Timeout: dialTimeout}
+
+// isDoH reports whether a resolver setting names a DNS-over-HTTPS endpoint
+// rather than a DNS server.
+func isDoH(resolver string) bool {
+	return strings.HasPrefix(resolver, "https://")
+}
+
+// newDoHResolver builds a resolver that sends all queries to the given
+// DNS-over-HTTPS endpoint (RFC 8484, POST). Go's resolver does the rest:
+// it writes TCP framed DNS messages to the conn from Dial, which relays
+// each one as a request.
+func newDoHResolver(endpoint string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true, // the cgo resolver would ignore Dial
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return &dohConn{ctx: ctx, endpoint: endpoint}, nil
+		},
+	}
+}
+
+// dohConn is the net.Conn the resolver talks DNS over TCP framing to.
+// Not a net.PacketConn, so the resolver uses the 2 byte length prefix of
+// DNS over TCP, which tells where a message ends.
+type dohConn struct {
+	ctx      context.Context
+	endpoint string
+
+	mu       sync.Mutex
+	deadline time.Time
+	out      bytes.Buffer // query being written
+	in       bytes.Buffer // answers not read yet
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.out.Write(b)
+	for c.out.Len() >= 2 {
+		n := int(binary.BigEndian.Uint16(c.out.Bytes()))
+		if c.out.Len() < 2+n {
+			break
+		}
+		msg := make([]byte, n)
+		copy(msg, c.out.Bytes()[2:])
+		c.out.Next(2 + n)
+		answer, err := c.exchange(msg)
+		if err != nil {
+			return 0, err
+		}
+		binary.Write(&c.in, binary.BigEndian, uint16(len(answer)))
+		c.in.Write(answer)
+	}
+	return len(b), nil
+}
+
+// exchange sends one DNS message to the endpoint and returns the answer.
+func (c *dohConn) exchange(msg []byte) ([]byte, error) {
+	// Only the lookup's cancellation carries over: values such as the
+	// check's httptrace hooks would time this request (concurrently, A and
+	// AAAA queries run in parallel) as if it were the check's own.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := context.AfterFunc(c.ctx, cancel)
+	defer stop()
+	if !c.deadline.IsZero() {
+		var cancelDeadline context.CancelFunc
+		ctx, cancelDeadline = context.WithDeadline(ctx, c.deadline)
+		defer cancelDeadline()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := dohClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("dns-over-https: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("dns-over-https: %s returned %s", c.endpoint, resp.Status)
+	}
+	// A DNS message can't be longer than its length prefix allows
+	answer, err := io.ReadAll(io.LimitReader(resp.Body, 0xffff+1))
+	if err != nil {
+		return nil, fmt.Errorf("dns-over-https: %w", err)
+	}
+	if len(answer) > 0xffff {
+		return nil, fmt.Errorf("dns-over-https: answer from %s is too long", c.endpoint)
+	}
+	return answer, nil
+}
+
+func (c *dohConn) Read(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.in.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.in.Read(b)
+}
+
+func (c *dohConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	return nil
+}
+
+func (c *dohConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *dohConn) SetWriteDeadline(t time.Time) error { return c.SetDeadline(t) }
+func (c *dohConn) Close() error                       { return nil }
+func (c *dohConn) LocalAddr() net.Addr                { return dohAddr(c.endpoint) }
+func (c *dohConn) RemoteAddr() net.Addr               { return dohAddr(c.endpoint) }
+
+// dohAddr is the address of a dohConn: its endpoint.
+type dohAddr string
+
+func (a dohAddr) Network() string { return "https" }
+func (a dohAddr) String() string  { return string(a) }
@@ -0,0 +1,206 @@
+package monitor
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// EventType tells what an Event is about.
+type EventType int
+
+const (
+	// EventCheck is published for every recorded result.
+	EventCheck EventType = iota
+	// EventTransition is published when a monitor flips UP <-> DOWN and
+	// an alert goes out, i.e. after dependency suppression and muting.
+	EventTransition
+	// EventFlapping is published when a monitor starts or stops flapping
+	// and the alert isn't muted.
+	EventFlapping
+	// EventSLO is published when a monitor's error budget burn crosses
+	// its threshold and the alert isn't muted.
+	EventSLO
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventCheck:
+		return "check"
+	case EventTransition:
+		return "transition"
+	case EventFlapping:
+		return "flapping"
+	case EventSLO:
+		return "slo"
+	}
+	return "unknown"
+}
+
+// Event is what observers and subscribers receive.
+type Event struct {
+	Type    EventType
+	Monitor config.MonitorConfig
+	Result  CheckResult // EventCheck and EventTransition
+	WasUp   bool        // EventTransition only
+	Flap    FlapAlert   // EventFlapping only
+	SLO     SLOAlert    // EventSLO only
+}
+
+// Observer sees events in the order they happen, on a goroutine of its own
+// that Observe starts. Like a subscriber it has a bounded queue, so a slow
+// observer misses events rather than holding up checks.
+type Observer interface {
+	Observe(ev Event)
+}
+
+// ObserverFunc adapts a function to Observer.
+type ObserverFunc func(ev Event)
+
+func (f ObserverFunc) Observe(ev Event) { f(ev) }
+
+// observerBuffer is how many events an observer may fall behind by.
+const observerBuffer = 256
+
+// eventBus hands events to the engine's own sinks, then fans them out to
+// observers and subscribers without ever blocking the engine: each has a
+// bounded buffer and events that don't fit are dropped (and counted).
+//
+// Only the sinks, the store, the notifier and the metrics (see NewEngine),
+// see every event. They run inline on the check's goroutine and must not
+// block for long: the store gives up after a few retries and the notifier
+// only queues its alerts.
+type eventBus struct {
+	sinks   []func(Event) // set by NewEngine, never changed
+	mu      sync.RWMutex
+	subs    map[*subscription]struct{}
+	dropped atomic.Uint64
+}
+
+type subscription struct {
+	ch chan Event
+}
+
+// Observe registers an observer. It sees events published from then on,
+// until the engine stops.
+func (e *Engine) Observe(o Observer) {
+	sub := e.events.add(observerBuffer)
+	go func() {
+		for {
+			select {
+			case ev := <-sub.ch:
+				o.Observe(ev)
+			case <-e.stopCh:
+				e.events.remove(sub)
+				return
+			}
+		}
+	}()
+}
+
+// Subscribe registers a subscriber with room for buffer pending events.
+// The returned cancel func unsubscribes and closes the channel.
+func (e *Engine) Subscribe(buffer int) (<-chan Event, func()) {
+	if buffer < 1 {
+		buffer = 1
+	}
+	b := &e.events
+	sub := b.add(buffer)
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.remove(sub)
+			close(sub.ch)
+		})
+	}
+	return sub.ch, cancel
+}
+
+// add registers a subscription with room for buffer events.
+func (b *eventBus) add(buffer int) *subscription {
+	sub := &subscription{ch: make(chan Event, buffer)}
+	b.mu.Lock()
+	if b.subs == nil {
+		b.subs = make(map[*subscription]struct{})
+	}
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+// remove unregisters a subscription. Once it returns, publish no longer
+// sends to it.
+func (b *eventBus) remove(sub *subscription) {
+	b.mu.Lock()
+	delete(b.subs, sub)
+	b.mu.Unlock()
+}
+
+// publish hands an event to every sink, then to every observer and
+// subscriber that has room for it.
+func (e *Engine) publish(ev Event) {
+	b := &e.events
+	for _, sink := range b.sinks {
+		sink(ev)
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		select {
+		case sub.ch <- ev:
+		default:
+			if n := b.dropped.Add(1); n == 1 || n%100 == 0 {
+				log.Printf("Event observer or subscriber too slow, %d events dropped so far", n)
+			}
+		}
+	}
+}
+
+// DroppedEvents returns how many events slow observers and subscribers
+// missed.
+func (e *Engine) DroppedEvents() uint64 {
+	return e.events.dropped.Load()
+}
+
+// storeResults is the store's sink: it persists every check result,
+// sampled by store_every.
+func (e *Engine) storeResults(ev Event) {
+	if ev.Type != EventCheck || e.Store == nil {
+		return
+	}
+	for _, r := range e.sampleForStore(ev.Monitor, ev.Result) {
+		e.logCheck(r)
+	}
+}
+
+// notify is the notifier's sink: it sends transition, flapping and
+// SLO alerts through whichever of them the notifier supports.
+func (e *Engine) notify(ev Event) {
+	switch n := e.Notifier; ev.Type {
+	case EventTransition:
+		if n != nil {
+			n.Notify(ev.Result, ev.WasUp)
+		}
+	case EventFlapping:
+		if fn, ok := n.(FlapNotifier); ok {
+			fn.NotifyFlapping(ev.Flap)
+		}
+	case EventSLO:
+		if sn, ok := n.(SLONotifier); ok {
+			sn.NotifySLO(ev.SLO)
+		}
+	}
+}
+
+// observeMetrics is the metrics' sink: it feeds the latency histograms
+// behind /metrics. Aggregates have no latency of their own.
+func (e *Engine) observeMetrics(ev Event) {
+	if ev.Type != EventCheck || ev.Monitor.Type == "aggregate" {
+		return
+	}
+	e.observeLatency(ev.Result.MonitorName, ev.Result.Latency)
+}
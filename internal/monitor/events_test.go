@@ -0,0 +1,191 @@
+package monitor
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+const eventsConfig = `
+global: {check_interval: 1h}
+monitors:
+  - {name: api, type: tcp, host: 127.0.0.1, port: 1}
+`
+
+// eventLog is an Observer that remembers the events it saw.
+type eventLog struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (l *eventLog) Observe(ev Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, ev)
+}
+
+func (l *eventLog) types() []EventType {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []EventType
+	for _, ev := range l.events {
+		out = append(out, ev.Type)
+	}
+	return out
+}
+
+func TestObserversSeeChecksAndTransitions(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []bool
+		events     []EventType
+		stored     int
+		notified   int
+		latencyObs uint64
+	}{
+		{
+			name:       "first check",
+			statuses:   []bool{true},
+			events:     []EventType{EventCheck},
+			stored:     1,
+			latencyObs: 1,
+		},
+		{
+			name:       "steady",
+			statuses:   []bool{true, true, true},
+			events:     []EventType{EventCheck, EventCheck, EventCheck},
+			stored:     3,
+			latencyObs: 3,
+		},
+		{
+			name:       "down and back up",
+			statuses:   []bool{true, false, true},
+			events:     []EventType{EventCheck, EventCheck, EventTransition, EventCheck, EventTransition},
+			stored:     3,
+			notified:   2,
+			latencyObs: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t, eventsConfig)
+			st := &memStore{}
+			n := &recordingNotifier{}
+			e := NewEngine(cfg, st, n)
+			var seen eventLog
+			e.Observe(&seen)
+
+			m := cfg.Monitors[0]
+			start := time.Now().Add(-time.Hour)
+			for i, up := range tt.statuses {
+				e.recordResult(m, CheckResult{
+					MonitorName: m.Name,
+					Timestamp:   start.Add(time.Duration(i) * time.Minute),
+					Status:      up,
+					Latency:     time.Millisecond,
+				})
+			}
+
+			// Observers catch up on their own goroutine
+			waitFor(t, func() bool { return len(seen.types()) >= len(tt.events) })
+			if got := seen.types(); !slices.Equal(got, tt.events) {
+				t.Errorf("events %v, want %v", got, tt.events)
+			}
+			if got := len(st.checks(m.Name)); got != tt.stored {
+				t.Errorf("%d results stored, want %d", got, tt.stored)
+			}
+			if got := len(n.notifications()); got != tt.notified {
+				t.Errorf("%d notifications, want %d", got, tt.notified)
+			}
+			if h := e.State(m.Name).Latency; h == nil || h.Count != tt.latencyObs {
+				t.Errorf("latency histogram %+v, want %d observations", h, tt.latencyObs)
+			}
+		})
+	}
+}
+
+// A subscriber that never reads loses events but doesn't hold up checks,
+// and the store still sees every result.
+func TestSlowSubscriberDoesNotBlock(t *testing.T) {
+	cfg := testConfig(t, eventsConfig)
+	st := &memStore{}
+	e := NewEngine(cfg, st, nil)
+	events, cancel := e.Subscribe(1)
+	defer cancel()
+
+	const checks = 50
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m := cfg.Monitors[0]
+		start := time.Now().Add(-time.Hour)
+		for i := range checks {
+			e.recordResult(m, CheckResult{
+				MonitorName: m.Name,
+				Timestamp:   start.Add(time.Duration(i) * time.Second),
+				Status:      i%2 == 0,
+			})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("checks blocked on a subscriber that doesn't read")
+	}
+
+	if got := len(st.checks("api")); got != checks {
+		t.Errorf("%d results stored, want %d", got, checks)
+	}
+	if e.DroppedEvents() == 0 {
+		t.Errorf("no events dropped for a subscriber with room for one")
+	}
+	if ev := <-events; ev.Type != EventCheck {
+		t.Errorf("first buffered event is %v, want %v", ev.Type, EventCheck)
+	}
+}
+
+// An observer that hangs loses events once its queue is full but doesn't
+// hold up checks, and the sinks still see every result.
+func TestSlowObserverDoesNotBlock(t *testing.T) {
+	cfg := testConfig(t, eventsConfig)
+	st := &memStore{}
+	e := NewEngine(cfg, st, nil)
+	defer e.Stop(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+	var seen eventLog
+	e.Observe(ObserverFunc(func(ev Event) {
+		seen.Observe(ev)
+		<-release
+	}))
+
+	checks := observerBuffer + 50
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m := cfg.Monitors[0]
+		start := time.Now().Add(-time.Hour)
+		for i := range checks {
+			e.recordResult(m, CheckResult{
+				MonitorName: m.Name,
+				Timestamp:   start.Add(time.Duration(i) * time.Second),
+				Status:      true,
+			})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("checks blocked on an observer that doesn't return")
+	}
+
+	if got := len(st.checks("api")); got != checks {
+		t.Errorf("%d results stored, want %d", got, checks)
+	}
+	if e.DroppedEvents() == 0 {
+		t.Errorf("no events dropped for an observer that hangs")
+	}
+	waitFor(t, func() bool { return len(seen.types()) == 1 })
+}
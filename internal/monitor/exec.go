@@ -0,0 +1,60 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// defaultExecTimeout bounds exec checks without an exec_timeout.
+const defaultExecTimeout = 30 * time.Second
+
+// maxExecOutput caps how much command output ends up in the error.
+const maxExecOutput = 1024
+
+// checkExec runs the monitor's command; exit code 0 is UP. On failure the
+// (trimmed) stdout and stderr become the error, that's usually where a
+// probe script explains itself. LoadConfig refuses exec monitors unless
+// global.allow_exec is set.
+func checkExec(m config.MonitorConfig) (bool, error) {
+	if len(m.Command) == 0 {
+		return false, fmt.Errorf("no command configured")
+	}
+	timeout := defaultExecTimeout
+	if m.ExecTimeout != "" {
+		timeout = config.ParseDuration(m.ExecTimeout)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, m.Command[0], m.Command[1:]...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	// Don't let a grandchild holding the pipes open outlive the timeout
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if err == nil {
+		return true, nil
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", timeout)
+	} else if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) {
+		err = fmt.Errorf("exit code %d", exitErr.ExitCode())
+	}
+	if output := strings.TrimSpace(out.String()); output != "" {
+		if len(output) > maxExecOutput {
+			output = output[:maxExecOutput] + "..."
+		}
+		// Keep the error on one line for logs and the dashboard tooltip
+		err = fmt.Errorf("%w: %s", err, strings.ReplaceAll(output, "\n", "; "))
+	}
+	return false, err
+}
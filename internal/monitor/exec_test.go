@@ -0,0 +1,48 @@
+package monitor
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestExec(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	tests := []struct {
+		name    string
+		script  string
+		timeout string
+		wantUp  bool
+		wantErr string
+	}{
+		{name: "exit 0", script: "echo all good", wantUp: true},
+		{name: "exit 1 with output", script: "echo replication lag 120s; exit 1", wantErr: "exit code 1: replication lag 120s"},
+		{name: "stderr on one line", script: "echo first >&2; echo second >&2; exit 3", wantErr: "exit code 3: first; second"},
+		{name: "no output", script: "exit 2", wantErr: "exit code 2"},
+		{name: "long output cut", script: "head -c 5000 /dev/zero | tr '\\0' x; exit 1", wantErr: strings.Repeat("x", 1024) + "..."},
+		{name: "timeout", script: "sleep 5", timeout: "100ms", wantErr: "timed out after 100ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := ""
+			if tt.timeout != "" {
+				opts = ", exec_timeout: " + tt.timeout
+			}
+			cfg := testConfig(t, fmt.Sprintf(`
+global: {allow_exec: true}
+monitors:
+  - {name: probe, type: exec, command: [sh, -c, %q]%s}
+`, tt.script, opts))
+			res := RunCheck(cfg.Monitors[0])
+			if res.Status != tt.wantUp {
+				t.Errorf("up %v (%q), want %v", res.Status, res.Error, tt.wantUp)
+			}
+			if tt.wantUp && res.Error != "" || !strings.HasSuffix(res.Error, tt.wantErr) {
+				t.Errorf("error %q, want it to end in %q", res.Error, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"log"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// TransitionStore lists stored UP <-> DOWN changes. It's optional like
+// StateSinceStore; without it the flap count starts over on every restart.
+type TransitionStore interface {
+	Transitions(monitorName string, since time.Time) ([]time.Time, error)
+}
+
+// FlapAlert reports that a monitor started or stopped flapping.
+type FlapAlert struct {
+	MonitorName string
+	Flapping    bool // false once the changes dropped back to half the threshold
+	Changes     int  // within Window
+	Threshold   int
+	Window      time.Duration
+	Timestamp   time.Time
+	Labels      map[string]string
+}
+
+// FlapNotifier is implemented by notifiers that deliver flapping alerts.
+type FlapNotifier interface {
+	NotifyFlapping(alert FlapAlert)
+}
+
+// flapWindow is the period flaps are counted over, global.flap_window.
+func (e *Engine) flapWindow() time.Duration {
+	return config.ParseDuration(e.Config().Global.FlapWindow)
+}
+
+// countFlaps records a state change at t when changed is set, forgets
+// changes older than window and updates the flapping alert state against
+// threshold (0 for none). It reports whether that state flipped. The
+// alert stops only at half the threshold, so a monitor hovering around it
+// doesn't alert on every change. Caller must hold e.mu.
+func (st *monitorState) countFlaps(changed bool, t time.Time, window time.Duration, threshold int) bool {
+	if changed {
+		st.flaps = append(st.flaps, t)
+	}
+	cutoff := t.Add(-window)
+	n := 0
+	for n < len(st.flaps) && st.flaps[n].Before(cutoff) {
+		n++
+	}
+	st.flaps = st.flaps[n:]
+
+	switch {
+	case threshold > 0 && !st.flapping && len(st.flaps) >= threshold:
+		st.flapping = true
+	case st.flapping && len(st.flaps) <= threshold/2:
+		st.flapping = false
+	default:
+		return false
+	}
+	return true
+}
+
+// flapCount is how many of the monitor's changes are within window of now.
+// Caller must hold e.mu.
+func (st *monitorState) flapCount(now time.Time, window time.Duration) int {
+	n := 0
+	for _, t := range st.flaps {
+		if !t.Before(now.Add(-window)) {
+			n++
+		}
+	}
+	return n
+}
+
+// alertFlapping logs and notifies a monitor starting or stopping to flap.
+func (e *Engine) alertFlapping(m config.MonitorConfig, a FlapAlert) {
+	if a.Flapping {
+		log.Printf("Monitor %s: flapping, %d state changes in %s (threshold %d)", m.Name, a.Changes, a.Window, a.Threshold)
+	} else {
+		log.Printf("Monitor %s: stopped flapping, %d state changes in %s", m.Name, a.Changes, a.Window)
+	}
+	if e.isMuted(m.Name) {
+		log.Printf("Monitor %s: alert not sent, monitor is muted", m.Name)
+		return
+	}
+	e.publish(Event{Type: EventFlapping, Monitor: m, Flap: a})
+}
@@ -0,0 +1,126 @@
+package monitor
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCountFlaps(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	type step struct {
+		at           time.Duration
+		changed      bool
+		wantFlips    bool
+		wantFlapping bool
+		wantCount    int
+	}
+	tests := []struct {
+		name      string
+		threshold int
+		steps     []step
+	}{
+		{"reaches the threshold", 4, []step{
+			{0, true, false, false, 1},
+			{time.Minute, true, false, false, 2},
+			{2 * time.Minute, true, false, false, 3},
+			{3 * time.Minute, true, true, true, 4},
+			{4 * time.Minute, true, false, true, 5},
+		}},
+		{"stops at half the threshold", 4, []step{
+			{0, true, false, false, 1},
+			{time.Minute, true, false, false, 2},
+			{2 * time.Minute, true, false, false, 3},
+			{3 * time.Minute, true, true, true, 4},
+			// The first change leaves the window, 3 are above half
+			{61 * time.Minute, false, false, true, 3},
+			{62 * time.Minute, false, true, false, 2},
+		}},
+		{"no threshold", 0, []step{
+			{0, true, false, false, 1},
+			{time.Minute, true, false, false, 2},
+			{2 * time.Minute, true, false, false, 3},
+			{3 * time.Minute, false, false, false, 3},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &monitorState{}
+			for i, s := range tt.steps {
+				flipped := st.countFlaps(s.changed, start.Add(s.at), time.Hour, tt.threshold)
+				if flipped != s.wantFlips || st.flapping != s.wantFlapping || len(st.flaps) != s.wantCount {
+					t.Errorf("step %d: flipped %v, flapping %v, %d changes; want %v, %v, %d",
+						i, flipped, st.flapping, len(st.flaps), s.wantFlips, s.wantFlapping, s.wantCount)
+				}
+			}
+		})
+	}
+}
+
+// flapNotifier records flapping alerts.
+type flapNotifier struct {
+	recordingNotifier
+	mu     sync.Mutex
+	alerts []FlapAlert
+}
+
+func (n *flapNotifier) NotifyFlapping(alert FlapAlert) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.alerts = append(n.alerts, alert)
+}
+
+func (n *flapNotifier) flapAlerts() []FlapAlert {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]FlapAlert(nil), n.alerts...)
+}
+
+func TestFlapping(t *testing.T) {
+	cfg := testConfig(t, `
+global: {flap_window: 1h, flap_threshold: 4}
+monitors:
+  - {name: api, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: db, type: tcp, host: 127.0.0.1, port: 1, flap_threshold: 6}
+`)
+	n := &flapNotifier{}
+	e := NewEngine(cfg, &memStore{}, n)
+	now := time.Now()
+	record := func(m int, at time.Time, up bool) {
+		e.recordResult(cfg.Monitors[m], CheckResult{MonitorName: cfg.Monitors[m].Name, Timestamp: at, Status: up})
+	}
+
+	// UP DOWN UP DOWN UP a minute apart: four changes, api's threshold
+	start := now.Add(-90 * time.Minute)
+	for i := range 5 {
+		record(0, start.Add(time.Duration(i)*time.Minute), i%2 == 0)
+		record(1, now.Add(time.Duration(i-5)*time.Minute), i%2 == 0)
+	}
+	if s := e.State("db"); s.Flaps != 4 || s.Flapping {
+		t.Errorf("db: %d flaps, flapping %v; want 4, false", s.Flaps, s.Flapping)
+	}
+	alerts := n.flapAlerts()
+	if len(alerts) != 1 {
+		t.Fatalf("%d flapping alerts, want 1: %+v", len(alerts), alerts)
+	}
+	if a := alerts[0]; a.MonitorName != "api" || !a.Flapping || a.Changes != 4 || a.Threshold != 4 ||
+		a.Window != time.Hour || !a.Timestamp.Equal(start.Add(4*time.Minute)) {
+		t.Errorf("alert %+v", a)
+	}
+
+	// Still flapping: no repeat
+	record(0, start.Add(5*time.Minute), false)
+	if got := len(n.flapAlerts()); got != 1 {
+		t.Errorf("%d flapping alerts after another change, want 1", got)
+	}
+
+	// An hour on only the last change is within the window
+	record(0, now, true)
+	alerts = n.flapAlerts()
+	if len(alerts) != 2 || alerts[1].Flapping || alerts[1].Changes != 1 {
+		t.Fatalf("alerts %+v, want a second one that api stopped flapping", alerts)
+	}
+	if s := e.State("api"); s.Flapping || s.Flaps != 1 {
+		t.Errorf("api: %d flaps, flapping %v; want 1, false", s.Flaps, s.Flapping)
+	}
+}
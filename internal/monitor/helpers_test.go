@@ -0,0 +1,199 @@
+package monitor
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// testConfig loads a monitors.yaml from src, validated and resolved like
+// the real thing.
+func testConfig(t *testing.T, src string) *config.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "monitors.yaml")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	return cfg
+}
+
+// memStore keeps logged checks in memory.
+type memStore struct {
+	mu      sync.Mutex
+	results []CheckResult
+}
+
+func (s *memStore) LogCheck(result CheckResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = append(s.results, result)
+	return nil
+}
+
+// checks returns the logged checks of a monitor, oldest first.
+func (s *memStore) checks(name string) []CheckResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []CheckResult
+	for _, r := range s.results {
+		if r.MonitorName == name {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// notification is one Notify call.
+type notification struct {
+	result CheckResult
+	wasUp  bool
+}
+
+// recordingNotifier remembers what it was asked to send.
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []notification
+}
+
+func (n *recordingNotifier) Notify(result CheckResult, wasUp bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, notification{result, wasUp})
+}
+
+func (n *recordingNotifier) notifications() []notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]notification(nil), n.sent...)
+}
+
+// waitFor polls cond until it holds, failing the test after 5 seconds.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// runHTTP checks url with an http monitor, opts are extra monitor settings
+// in YAML flow style, e.g. ", expect_status: 204".
+func runHTTP(t *testing.T, url, opts string) CheckResult {
+	t.Helper()
+	cfg := testConfig(t, fmt.Sprintf(`
+monitors:
+  - {name: web, type: http, url: %q%s}
+`, url, opts))
+	return RunCheck(cfg.Monitors[0])
+}
+
+// trustedCerts are self-signed certificates the system trusts during the
+// tests (see TestMain), unlike the one of httptest's TLS servers.
+var trustedCerts [2]tls.Certificate
+
+// TestMain generates trustedCerts and adds them to the system roots.
+func TestMain(m *testing.M) {
+	var roots []byte
+	for i := range trustedCerts {
+		cert, err := selfSignedCert()
+		if err != nil {
+			log.Fatal(err)
+		}
+		trustedCerts[i] = cert
+		roots = append(roots, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})...)
+	}
+
+	dir, err := os.MkdirTemp("", "zenmonitor-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	path := filepath.Join(dir, "roots.pem")
+	if err := os.WriteFile(path, roots, 0o644); err != nil {
+		log.Fatal(err)
+	}
+	os.Setenv("SSL_CERT_FILE", path)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// selfSignedCert makes a certificate for 127.0.0.1 and localhost.
+func selfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "zenmonitor test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		DNSNames:              []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// newTLSServer starts an HTTPS server presenting cert, configured further
+// by the optional tlsConfig.
+func newTLSServer(t *testing.T, handler http.Handler, cert tls.Certificate, tlsConfig *tls.Config) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(handler)
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
+	srv.TLS = tlsConfig
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// toggleServer serves 200 while up is set and 500 otherwise.
+func toggleServer(t *testing.T, up *atomic.Bool) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
@@ -0,0 +1,487 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+	"github.com/pronzzz/zenmonitor/internal/expr"
+)
+
+// errBodyTooLarge is returned by readBody when the body exceeds its cap.
+var errBodyTooLarge = errors.New("response body too large")
+
+// httpTimeout bounds an HTTP check as a whole: the auth step, every
+// retry and the backoff between them, and reading the body.
+const httpTimeout = 10 * time.Second
+
+// checkHTTP fills in the HTTP specific fields of res (status code etc.)
+func checkHTTP(m config.MonitorConfig, res *CheckResult) (bool, error) {
+	start := time.Now()
+	client, pooled := newHTTPClient(m)
+	if !pooled {
+		defer client.CloseIdleConnections()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), httpTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, m.Method, m.URL, nil)
+	if err != nil {
+		return false, err
+	}
+	ua := m.UserAgent
+	if ua == "" {
+		ua = DefaultUserAgent()
+	}
+	req.Header.Set("User-Agent", ua)
+	// Ask for compression explicitly so we see what real clients see. Go's
+	// transport then leaves decoding to us (see readBody).
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	if m.Auth != nil {
+		if err := authenticate(client, req, m.Auth); err != nil {
+			return false, err
+		}
+	}
+
+	resp, err := doRequest(client, req, m, res)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	res.StatusCode = resp.StatusCode
+	// Before anything can fail the check, so the backoff isn't lost
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		res.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+	if err := checkTLSVersion(resp, m); err != nil {
+		return false, err
+	}
+	if err := checkCertFingerprint(resp, m); err != nil {
+		return false, err
+	}
+
+	limit := m.MaxResponseBytes
+	if limit <= 0 {
+		limit = config.DefaultMaxResponseBytes
+	}
+	body, err := readBody(resp, limit)
+	if errors.Is(err, errBodyTooLarge) {
+		return false, fmt.Errorf("response body exceeds %d bytes (max_response_bytes)", limit)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read body: %w", err)
+	}
+	// Size and content assertions work on the decoded body
+	res.BodySize = int64(len(body))
+
+	if len(m.StatusMap) > 0 {
+		switch m.StatusState(resp.StatusCode) {
+		case config.StateDown:
+			return false, fmt.Errorf("status code %d maps to down", resp.StatusCode)
+		case config.StateDegraded:
+			res.Degraded = true
+			res.Error = fmt.Sprintf("status code %d maps to degraded", resp.StatusCode)
+		}
+	}
+	if m.ExpectStatus != 0 && resp.StatusCode != m.ExpectStatus {
+		return false, fmt.Errorf("status code %d, expected %d", resp.StatusCode, m.ExpectStatus)
+	}
+	for _, forbidden := range m.ExpectNotStatus {
+		if resp.StatusCode == forbidden {
+			return false, fmt.Errorf("status code %d is in expect_not_status", resp.StatusCode)
+		}
+	}
+	if err := checkFinalURL(resp.Request.URL.String(), m); err != nil {
+		return false, err
+	}
+	if err := checkHeaders(resp.Header, m.ExpectHeaders); err != nil {
+		return false, err
+	}
+	if m.ExpectContentType != "" {
+		ct, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+		ct = strings.ToLower(strings.TrimSpace(ct))
+		if !strings.HasPrefix(ct, strings.ToLower(m.ExpectContentType)) {
+			if ct == "" {
+				ct = "missing"
+			}
+			return false, fmt.Errorf("content type %s, expected %s", ct, m.ExpectContentType)
+		}
+	}
+	if m.MinBodySize > 0 && res.BodySize < m.MinBodySize {
+		return false, fmt.Errorf("body size %d bytes, expected at least %d", res.BodySize, m.MinBodySize)
+	}
+	if m.MaxBodySize > 0 && res.BodySize > m.MaxBodySize {
+		return false, fmt.Errorf("body size %d bytes, expected at most %d", res.BodySize, m.MaxBodySize)
+	}
+	if err := checkBody(body, m); err != nil {
+		return false, err
+	}
+	if m.ExpectExpr != "" {
+		e, err := expr.Parse(m.ExpectExpr) // validated in LoadConfig
+		if err != nil {
+			return false, fmt.Errorf("expect_expr: %w", err)
+		}
+		env := &expr.Env{
+			Status:  resp.StatusCode,
+			Latency: time.Since(start),
+			Body:    string(body),
+			Size:    res.BodySize,
+			Header:  resp.Header,
+		}
+		if !e.Eval(env) {
+			return false, fmt.Errorf("expect_expr is false (status %d, latency %s, %d byte body)",
+				env.Status, env.Latency.Round(time.Millisecond), env.Size)
+		}
+	}
+	return true, nil
+}
+
+// checkBody verifies the expect_body* assertions. A missing expect_body_all
+// keyword is reported together with any others that are missing.
+func checkBody(body []byte, m config.MonitorConfig) error {
+	all := m.ExpectBodyAll
+	if m.ExpectBody != "" {
+		all = append([]string{m.ExpectBody}, all...)
+	}
+	var missing []string
+	for _, kw := range all {
+		if !bytes.Contains(body, []byte(kw)) {
+			missing = append(missing, strconv.Quote(kw))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("body does not contain %s", strings.Join(missing, ", "))
+	}
+
+	if len(m.ExpectBodyAny) > 0 && !slices.ContainsFunc(m.ExpectBodyAny, func(kw string) bool {
+		return bytes.Contains(body, []byte(kw))
+	}) {
+		quoted := make([]string, len(m.ExpectBodyAny))
+		for i, kw := range m.ExpectBodyAny {
+			quoted[i] = strconv.Quote(kw)
+		}
+		return fmt.Errorf("body contains none of %s", strings.Join(quoted, ", "))
+	}
+
+	if m.ExpectBodyRegex != "" {
+		re, err := regexp.Compile(m.ExpectBodyRegex)
+		if err != nil {
+			// Validated in LoadConfig, so this shouldn't happen
+			return fmt.Errorf("invalid expect_body_regex: %w", err)
+		}
+		if !re.Match(body) {
+			return fmt.Errorf("body does not match %s", m.ExpectBodyRegex)
+		}
+	}
+	return nil
+}
+
+// doRequest sends the request, retrying transient connection failures (see
+// transientError) up to m.Retries times with exponential backoff. Anything
+// that produced a response, whatever its status, is never retried. No
+// retry starts that the deadline of req's context wouldn't leave time for.
+// Timings are those of the last attempt.
+func doRequest(client *http.Client, req *http.Request, m config.MonitorConfig, res *CheckResult) (*http.Response, error) {
+	delay := defaultRetryDelay
+	if m.RetryDelay != "" {
+		delay = config.ParseDuration(m.RetryDelay)
+	}
+
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		res.Timings = HTTPTimings{}
+		resp, err := client.Do(req.WithContext(httptrace.WithClientTrace(ctx, traceTimings(&res.Timings))))
+		if err == nil || attempt >= m.Retries || !transientError(err) {
+			return resp, err
+		}
+		wait := retryBackoff(delay, attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+			return nil, err
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, err
+		case <-t.C:
+		}
+	}
+}
+
+const (
+	// defaultRetryDelay is the first backoff of retries when retry_delay isn't set.
+	defaultRetryDelay = time.Second
+	// maxRetryBackoff caps the doubling backoff between retries.
+	maxRetryBackoff = 30 * time.Second
+)
+
+// retryBackoff is the pause before retry number attempt+1: delay doubled
+// attempt times, at most maxRetryBackoff.
+func retryBackoff(delay time.Duration, attempt int) time.Duration {
+	for ; attempt > 0 && delay < maxRetryBackoff; attempt-- {
+		delay *= 2
+	}
+	return min(delay, maxRetryBackoff)
+}
+
+// transientError reports whether a request that failed without a response
+// is worth retrying: the connection couldn't be set up, or broke before
+// the response came. Timeouts, TLS verification failures and the
+// max_redirects limit would only fail the same way again.
+func transientError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return false
+	}
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &certErr) {
+		return false
+	}
+	// A name that doesn't exist won't a moment later
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return !dnsErr.IsNotFound
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// readBody reads the decoded response body, handling gzip and deflate
+// Content-Encoding. Bodies longer than limit bytes fail with errBodyTooLarge.
+func readBody(resp *http.Response, limit int64) ([]byte, error) {
+	var r io.Reader = resp.Body
+
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("bad gzip body: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	case "deflate":
+		// Per the RFC this is zlib-wrapped, but plenty of servers send raw
+		// deflate. Peek at the header to tell them apart.
+		br := bufio.NewReader(resp.Body)
+		if hdr, err := br.Peek(2); err == nil && isZlibHeader(hdr) {
+			zr, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, fmt.Errorf("bad deflate body: %w", err)
+			}
+			defer zr.Close()
+			r = zr
+		} else {
+			fr := flate.NewReader(br)
+			defer fr.Close()
+			r = fr
+		}
+	}
+
+	// The limit applies after decoding, so a compression bomb can't blow up memory
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, errBodyTooLarge
+	}
+	return body, nil
+}
+
+func isZlibHeader(h []byte) bool {
+	return h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0
+}
+
+// checkTLSVersion verifies the negotiated TLS version against the
+// monitor's range. The transport already refuses versions outside of it,
+// this catches anything that slipped through (e.g. a redirect to plain
+// http when a minimum is required).
+func checkTLSVersion(resp *http.Response, m config.MonitorConfig) error {
+	if m.MinTLSVersion == "" && m.MaxTLSVersion == "" {
+		return nil
+	}
+	if resp.TLS == nil {
+		return fmt.Errorf("response was not over TLS, expected TLS %s or later", cmp.Or(m.MinTLSVersion, "1.0"))
+	}
+	minTLS, _ := config.ParseTLSVersion(m.MinTLSVersion)
+	maxTLS, _ := config.ParseTLSVersion(m.MaxTLSVersion)
+	v := resp.TLS.Version
+	if (minTLS != 0 && v < minTLS) || (maxTLS != 0 && v > maxTLS) {
+		return fmt.Errorf("negotiated %s, outside the allowed TLS versions", tls.VersionName(v))
+	}
+	return nil
+}
+
+// checkCertFingerprint compares the SHA-256 fingerprint of the server's
+// leaf certificate with expect_cert_fingerprint (normalized by LoadConfig).
+func checkCertFingerprint(resp *http.Response, m config.MonitorConfig) error {
+	if m.ExpectCertFingerprint == "" {
+		return nil
+	}
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		return fmt.Errorf("response was not over TLS, can't check the certificate fingerprint")
+	}
+	sum := sha256.Sum256(resp.TLS.PeerCertificates[0].Raw)
+	if got := hex.EncodeToString(sum[:]); got != m.ExpectCertFingerprint {
+		return fmt.Errorf("certificate fingerprint %s doesn't match expect_cert_fingerprint", got)
+	}
+	return nil
+}
+
+// checkFinalURL verifies where redirects ended up.
+func checkFinalURL(final string, m config.MonitorConfig) error {
+	if m.ExpectFinalURL != "" && final != m.ExpectFinalURL {
+		return fmt.Errorf("redirected to %s, expected %s", final, m.ExpectFinalURL)
+	}
+	if m.ExpectFinalURLContains != "" && !strings.Contains(final, m.ExpectFinalURLContains) {
+		return fmt.Errorf("redirected to %s, expected a URL containing %q", final, m.ExpectFinalURLContains)
+	}
+	return nil
+}
+
+// checkHeaders verifies the expect_headers assertions, naming the first
+// offending header (in sorted order, so errors are stable between checks).
+func checkHeaders(h http.Header, expect map[string]string) error {
+	names := make([]string, 0, len(expect))
+	for name := range expect {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		values := h.Values(name)
+		if len(values) == 0 {
+			return fmt.Errorf("header %s missing", name)
+		}
+		want := expect[name]
+		if want == "" {
+			continue
+		}
+		if !slices.Contains(values, want) {
+			return fmt.Errorf("header %s is %q, expected %q", name, strings.Join(values, ", "), want)
+		}
+	}
+	return nil
+}
+
+// parseRetryAfter understands both forms of the header: delay-seconds
+// ("120") and an HTTP date. Returns 0 if absent or unparseable.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// traceTimings records how long each phase of the request took into t.
+// Phases that don't happen (DNS for an IP, TLS for plain http, reused
+// connections) stay zero.
+func traceTimings(t *HTTPTimings) *httptrace.ClientTrace {
+	var start, dnsStart, connectStart, tlsStart time.Time
+	start = time.Now()
+
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			if !dnsStart.IsZero() {
+				t.DNS = time.Since(dnsStart)
+			}
+		},
+		ConnectStart: func(string, string) { connectStart = time.Now() },
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil && !connectStart.IsZero() {
+				t.Connect = time.Since(connectStart)
+			}
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			if !tlsStart.IsZero() {
+				t.TLS = time.Since(tlsStart)
+			}
+		},
+		GotFirstResponseByte: func() { t.TTFB = time.Since(start) },
+	}
+}
+
+// newHTTPClient builds the client for a monitor's check. With
+// global.http_pool set its transport is shared with other checks (pooled
+// is true) and the connections it leaves open get reused.
+func newHTTPClient(m config.MonitorConfig) (client *http.Client, pooled bool) {
+	transport, pooled := httpPool.transport(m, func() *http.Transport { return newTransport(m) })
+	if !pooled {
+		transport = newTransport(m)
+	}
+
+	maxRedirects := m.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = config.DefaultMaxRedirects
+	}
+
+	return &http.Client{
+		Timeout:   httpTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects (max_redirects)", maxRedirects)
+			}
+			return nil
+		},
+	}, pooled
+}
+
+// newTransport builds the transport for a monitor's connections.
+func newTransport(m config.MonitorConfig) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialContext(m)
+	if m.MinTLSVersion != "" || m.MaxTLSVersion != "" {
+		// Validated in LoadConfig
+		minTLS, _ := config.ParseTLSVersion(m.MinTLSVersion)
+		maxTLS, _ := config.ParseTLSVersion(m.MaxTLSVersion)
+		transport.TLSClientConfig = &tls.Config{MinVersion: minTLS, MaxVersion: maxTLS}
+	}
+
+	if m.SocketPath != "" {
+		// Talk HTTP over a unix socket; the host part of the URL is ignored.
+		socket := m.SocketPath
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+	}
+	return transport
+}
@@ -0,0 +1,781 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+func TestTransientError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{"connection reset", &net.OpError{Op: "read", Err: syscall.ECONNRESET}, true},
+		{"EOF before the response", fmt.Errorf("Get \"http://x\": %w", io.EOF), true},
+		{"DNS failure", &net.OpError{Op: "dial", Err: &net.DNSError{Err: "server misbehaving", IsTemporary: true}}, true},
+		{"unknown host", &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", IsNotFound: true}}, false},
+		{"dial timeout", &net.OpError{Op: "dial", Err: &net.DNSError{Err: "i/o timeout", IsTimeout: true}}, false},
+		{"deadline", context.DeadlineExceeded, false},
+		{"TLS verification", &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}, false},
+		{"redirect limit", errors.New("stopped after 10 redirects (max_redirects)"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := transientError(tt.err); got != tt.want {
+				t.Errorf("transientError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		delay   time.Duration
+		attempt int
+		want    time.Duration
+	}{
+		{time.Second, 0, time.Second},
+		{time.Second, 3, 8 * time.Second},
+		{time.Second, 5, maxRetryBackoff},
+		{time.Second, 200, maxRetryBackoff},
+		{time.Minute, 0, maxRetryBackoff},
+	}
+	for _, tt := range tests {
+		if got := retryBackoff(tt.delay, tt.attempt); got != tt.want {
+			t.Errorf("retryBackoff(%s, %d) = %s, want %s", tt.delay, tt.attempt, got, tt.want)
+		}
+	}
+}
+
+// hangUp reads a request off every connection and closes it without
+// answering.
+func hangUp(t *testing.T, conns *atomic.Int32) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns.Add(1)
+			http.ReadRequest(bufio.NewReader(c))
+			c.Close()
+		}
+	}()
+	return "http://" + ln.Addr().String()
+}
+
+func TestHTTPRetries(t *testing.T) {
+	var redirects atomic.Int32
+	loop := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirects.Add(1)
+		http.Redirect(w, r, "/again", http.StatusFound)
+	}))
+	defer loop.Close()
+
+	var handshakes atomic.Int32
+	untrusted := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	untrusted.Config.ConnState = func(_ net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			handshakes.Add(1)
+		}
+	}
+	untrusted.StartTLS()
+	defer untrusted.Close()
+
+	var hangups atomic.Int32
+	tests := []struct {
+		name     string
+		url      string
+		extra    string
+		counter  *atomic.Int32
+		attempts int32
+	}{
+		{"hang-up is retried", hangUp(t, &hangups), "", &hangups, 3},
+		{"redirect limit isn't", loop.URL, ", max_redirects: 2", &redirects, 2},
+		{"untrusted certificate isn't", untrusted.URL, "", &handshakes, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t, fmt.Sprintf(`
+monitors:
+  - {name: web, type: http, url: %q, retries: 2, retry_delay: 1ms%s}
+`, tt.url, tt.extra))
+			res := RunCheck(cfg.Monitors[0])
+			if res.Status {
+				t.Fatalf("check passed, want a failure")
+			}
+			if got := tt.counter.Load(); got != tt.attempts {
+				t.Errorf("%d attempts (%s), want %d", got, res.Error, tt.attempts)
+			}
+		})
+	}
+}
+
+// A connection dropped once is retried and recorded as a single UP check.
+func TestHTTPRetryRecovers(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			c, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			c.Close()
+		}
+	}))
+	defer srv.Close()
+
+	st := &memStore{}
+	e := NewEngine(testConfig(t, `
+monitors:
+  - {name: web, type: http, url: "`+srv.URL+`", retries: 2, retry_delay: 1ms}
+`), st, nil)
+	res, err := e.CheckNow("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Status {
+		t.Errorf("check failed after a retry: %s", res.Error)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("%d requests, want 2", got)
+	}
+	checks := st.checks("web")
+	if len(checks) != 1 || !checks[0].Status {
+		t.Errorf("stored checks %+v, want a single UP", checks)
+	}
+}
+
+// A retry whose backoff would run past httpTimeout isn't waited for, the
+// check fails right away.
+func TestHTTPRetryDeadline(t *testing.T) {
+	var hangups atomic.Int32
+	cfg := testConfig(t, `
+monitors:
+  - {name: web, type: http, url: "`+hangUp(t, &hangups)+`", retries: 3, retry_delay: 20s}
+`)
+	start := time.Now()
+	res := RunCheck(cfg.Monitors[0])
+	if res.Status {
+		t.Fatal("check passed, want a failure")
+	}
+	if took := time.Since(start); took >= httpTimeout {
+		t.Errorf("check took %s, want less than %s", took, httpTimeout)
+	}
+	if got := hangups.Load(); got != 1 {
+		t.Errorf("%d attempts, want 1", got)
+	}
+}
+
+func TestHTTPRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header string
+		body   int // bytes
+		want   time.Duration
+	}{
+		{"seconds", http.StatusServiceUnavailable, "120", 10, 2 * time.Minute},
+		{"date", http.StatusTooManyRequests, time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), 10, time.Hour},
+		{"oversized body", http.StatusTooManyRequests, "120", 4096, 2 * time.Minute},
+		{"not rate limited", http.StatusInternalServerError, "120", 10, 0},
+		{"no header", http.StatusServiceUnavailable, "", 10, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.header != "" {
+					w.Header().Set("Retry-After", tt.header)
+				}
+				w.WriteHeader(tt.status)
+				w.Write(make([]byte, tt.body))
+			}))
+			defer srv.Close()
+
+			cfg := testConfig(t, fmt.Sprintf(`
+monitors:
+  - {name: web, type: http, url: %q, max_response_bytes: 1024}
+`, srv.URL))
+			res := RunCheck(cfg.Monitors[0])
+			if d := res.RetryAfter - tt.want; d < -time.Second || d > time.Second {
+				t.Errorf("RetryAfter = %s, want %s (%s)", res.RetryAfter, tt.want, res.Error)
+			}
+		})
+	}
+}
+
+func TestHTTPUserAgent(t *testing.T) {
+	tests := []struct {
+		name   string
+		global string
+		own    string
+		want   string
+	}{
+		{"default", "", "", DefaultUserAgent()},
+		{"global", "user_agent: fleet/1.0", "", "fleet/1.0"},
+		{"monitor override", "user_agent: fleet/1.0", ", user_agent: probe/2.0", "probe/2.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got atomic.Value
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got.Store(r.UserAgent())
+			}))
+			defer srv.Close()
+
+			cfg := testConfig(t, fmt.Sprintf(`
+global: {%s}
+monitors:
+  - {name: web, type: http, url: %q%s}
+`, tt.global, srv.URL, tt.own))
+			if res := RunCheck(cfg.Monitors[0]); !res.Status {
+				t.Fatalf("check failed: %s", res.Error)
+			}
+			if got.Load() != tt.want {
+				t.Errorf("User-Agent %q, want %q", got.Load(), tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPBodySize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		opts    string
+		wantUp  bool
+		wantErr string
+	}{
+		{"no bounds", "", true, ""},
+		{"in range", ", min_body_size: 50, max_body_size: 200", true, ""},
+		{"exact bounds", ", min_body_size: 100, max_body_size: 100", true, ""},
+		{"under size", ", min_body_size: 101", false, "expected at least 101"},
+		{"over size", ", max_body_size: 99", false, "expected at most 99"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := runHTTP(t, srv.URL, tt.opts)
+			if res.Status != tt.wantUp || !strings.Contains(res.Error, tt.wantErr) {
+				t.Errorf("status %v (%q), want %v (%q)", res.Status, res.Error, tt.wantUp, tt.wantErr)
+			}
+			if res.BodySize != 100 {
+				t.Errorf("BodySize = %d, want 100", res.BodySize)
+			}
+		})
+	}
+}
+
+func TestHTTPUnixSocket(t *testing.T) {
+	// Socket paths are limited to ~100 bytes, t.TempDir can be too long
+	dir, err := os.MkdirTemp("", "zm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "api.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	srv.Listener = ln
+	srv.Start()
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		socket string
+		wantUp bool
+	}{
+		{"listening socket", socket, true},
+		{"missing socket", filepath.Join(dir, "missing.sock"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := runHTTP(t, "http://unix/health", fmt.Sprintf(", socket_path: %q, expect_body: ok", tt.socket))
+			if res.Status != tt.wantUp {
+				t.Errorf("status %v (%s), want %v", res.Status, res.Error, tt.wantUp)
+			}
+		})
+	}
+}
+
+func TestHTTPTimings(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	})
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	secure := newTLSServer(t, handler, trustedCerts[0], nil)
+	dns, _ := fakeDNS(t, "127.0.0.1")
+	_, port, _ := net.SplitHostPort(plain.Listener.Addr().String())
+
+	tests := []struct {
+		name    string
+		url     string
+		opts    string
+		wantDNS bool
+		wantTLS bool
+	}{
+		{"plain by IP", plain.URL, "", false, false},
+		{"plain by name", "http://service.zenmonitor.test:" + port, fmt.Sprintf(", resolver: %q", dns), true, false},
+		{"TLS", secure.URL, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := runHTTP(t, tt.url, tt.opts)
+			if !res.Status {
+				t.Fatalf("check failed: %s", res.Error)
+			}
+			tm := res.Timings
+			if (tm.DNS > 0) != tt.wantDNS || (tm.TLS > 0) != tt.wantTLS {
+				t.Errorf("DNS %s, TLS %s; want them set: %v, %v", tm.DNS, tm.TLS, tt.wantDNS, tt.wantTLS)
+			}
+			if tm.Connect <= 0 {
+				t.Errorf("connect took %s, want it measured", tm.Connect)
+			}
+			// The handler's pause is in TTFB, which includes the earlier phases
+			if tm.TTFB < 20*time.Millisecond {
+				t.Errorf("TTFB %s, want at least the handler's 20ms", tm.TTFB)
+			}
+			if sum := tm.DNS + tm.Connect + tm.TLS; sum > tm.TTFB || tm.TTFB > res.Latency {
+				t.Errorf("DNS+connect+TLS %s, TTFB %s, latency %s; want them in ascending order", sum, tm.TTFB, res.Latency)
+			}
+		})
+	}
+}
+
+func TestHTTPExpectNotStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		opts    string
+		wantUp  bool
+		wantErr string
+	}{
+		{"forbidden code", http.StatusNotFound, ", expect_not_status: [404, 410]", false, "404 is in expect_not_status"},
+		{"other forbidden code", http.StatusGone, ", expect_not_status: [404, 410]", false, "410 is in expect_not_status"},
+		{"allowed code", http.StatusOK, ", expect_not_status: [404, 410]", true, ""},
+		{"allowed error code", http.StatusInternalServerError, ", expect_not_status: [404]", true, ""},
+		{"with expect_status", http.StatusNotFound, ", expect_status: 404, expect_not_status: [500]", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+			res := runHTTP(t, srv.URL, tt.opts)
+			if res.Status != tt.wantUp || !strings.Contains(res.Error, tt.wantErr) {
+				t.Errorf("status %v (%q), want %v (%q)", res.Status, res.Error, tt.wantUp, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPExpectHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Version", "2.1")
+		w.Header().Add("Vary", "Accept")
+		w.Header().Add("Vary", "Origin")
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		expect  string
+		wantUp  bool
+		wantErr string
+	}{
+		{"present with value", `{X-Version: "2.1"}`, true, ""},
+		{"present, any value", `{X-Version: ""}`, true, ""},
+		{"case-insensitive name", `{x-version: "2.1"}`, true, ""},
+		{"one of several values", `{Vary: Origin}`, true, ""},
+		{"absent", `{X-Request-Id: ""}`, false, "header X-Request-Id missing"},
+		{"mismatched", `{X-Version: "3.0"}`, false, `header X-Version is "2.1", expected "3.0"`},
+		{"first failure in name order", `{X-Version: "3.0", A-Missing: ""}`, false, "header A-Missing missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := runHTTP(t, srv.URL, ", expect_headers: "+tt.expect)
+			if res.Status != tt.wantUp || !strings.Contains(res.Error, tt.wantErr) {
+				t.Errorf("status %v (%q), want %v (%q)", res.Status, res.Error, tt.wantUp, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPExpectExpr(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Version", "2.1")
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"status": "ok"}`)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path    string
+		expr    string
+		wantUp  bool
+		wantErr string
+	}{
+		{"/", `status == 200 && latency < 5s && body contains "ok"`, true, ""},
+		{"/", `header("X-Version") == "2.1" && size == 16`, true, ""},
+		{"/", `body matches "\"status\": \"(ok|degraded)\""`, true, ""},
+		{"/", `status == 200 && latency < 1ns`, false, "expect_expr is false (status 200, "},
+		{"/", `body contains "error"`, false, "16 byte body"},
+		// Without expect_status 404 isn't a failure of its own
+		{"/missing", `status == 404`, true, ""},
+		{"/missing", `status == 200 || body contains "ok"`, false, "expect_expr is false (status 404"},
+	}
+	for _, tt := range tests {
+		res := runHTTP(t, srv.URL+tt.path, fmt.Sprintf(", expect_expr: %q", tt.expr))
+		if res.Status != tt.wantUp || !strings.Contains(res.Error, tt.wantErr) {
+			t.Errorf("%s on %s: status %v (%q), want %v (%q)", tt.expr, tt.path, res.Status, res.Error, tt.wantUp, tt.wantErr)
+		}
+	}
+}
+
+func TestHTTPCompressedBody(t *testing.T) {
+	const page = "<html>status: all systems operational</html>"
+	compress := map[string]func(io.Writer) io.WriteCloser{
+		"gzip":        func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"deflate":     func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		"raw deflate": func(w io.Writer) io.WriteCloser { fw, _ := flate.NewWriter(w, flate.DefaultCompression); return fw },
+	}
+	tests := []struct {
+		name     string
+		encoding string // Content-Encoding header
+		compress string // key of compress, none when empty
+		keyword  string
+		wantUp   bool
+	}{
+		{"gzip", "gzip", "gzip", "all systems operational", true},
+		{"gzip, keyword missing", "gzip", "gzip", "degraded", false},
+		{"deflate", "deflate", "deflate", "all systems operational", true},
+		{"raw deflate", "deflate", "raw deflate", "all systems operational", true},
+		{"identity", "", "", "all systems operational", true},
+		{"corrupt gzip", "gzip", "", "all systems operational", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sentEncoding atomic.Value
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sentEncoding.Store(r.Header.Get("Accept-Encoding"))
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				if tt.compress == "" {
+					w.Write([]byte(page))
+					return
+				}
+				cw := compress[tt.compress](w)
+				cw.Write([]byte(page))
+				cw.Close()
+			}))
+			defer srv.Close()
+
+			res := runHTTP(t, srv.URL, fmt.Sprintf(", expect_body: %q", tt.keyword))
+			if res.Status != tt.wantUp {
+				t.Errorf("status %v (%s), want %v", res.Status, res.Error, tt.wantUp)
+			}
+			if tt.wantUp && res.BodySize != int64(len(page)) {
+				t.Errorf("BodySize = %d, want the decoded %d", res.BodySize, len(page))
+			}
+			if got := sentEncoding.Load(); got != "gzip, deflate" {
+				t.Errorf("Accept-Encoding %q, want %q", got, "gzip, deflate")
+			}
+		})
+	}
+}
+
+func TestHTTPExpectBodyAllAny(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"db": "ok", "cache": "ok", "queue": "lagging"}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		opts    string
+		wantUp  bool
+		wantErr string
+	}{
+		{"all present", `, expect_body_all: [db, cache, queue]`, true, ""},
+		{"one missing in all", `, expect_body_all: [db, search]`, false, `body does not contain "search"`},
+		{"several missing in all", `, expect_body_all: [search, db, mail]`, false, `body does not contain "search", "mail"`},
+		{"expect_body counts towards all", `, expect_body: mail, expect_body_all: [db]`, false, `body does not contain "mail"`},
+		{"one present in any", `, expect_body_any: [healthy, lagging]`, true, ""},
+		{"none present in any", `, expect_body_any: [healthy, green]`, false, `body contains none of "healthy", "green"`},
+		{"all and any", `, expect_body_all: [db], expect_body_any: [lagging, down]`, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := runHTTP(t, srv.URL, tt.opts)
+			if res.Status != tt.wantUp || !strings.Contains(res.Error, tt.wantErr) {
+				t.Errorf("status %v (%q), want %v (%q)", res.Status, res.Error, tt.wantUp, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPBoundedResponses(t *testing.T) {
+	var redirects atomic.Int32
+	loop := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirects.Add(1)
+		http.Redirect(w, r, fmt.Sprintf("/hop/%d", redirects.Load()), http.StatusFound)
+	}))
+	defer loop.Close()
+
+	endless := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := bytes.Repeat([]byte("x"), 4096)
+		for {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer endless.Close()
+
+	tests := []struct {
+		name          string
+		url           string
+		opts          string
+		wantErr       string
+		wantRedirects int32 // requests, which like net/http includes the first one
+	}{
+		{"infinite redirects", loop.URL, "", fmt.Sprintf("stopped after %d redirects", config.DefaultMaxRedirects), config.DefaultMaxRedirects},
+		{"infinite redirects, own limit", loop.URL, ", max_redirects: 3", "stopped after 3 redirects", 3},
+		{"endless body", endless.URL, "", fmt.Sprintf("exceeds %d bytes", config.DefaultMaxResponseBytes), 0},
+		{"endless body, own limit", endless.URL, ", max_response_bytes: 10000", "exceeds 10000 bytes", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redirects.Store(0)
+			start := time.Now()
+			res := runHTTP(t, tt.url, tt.opts)
+			if res.Status || !strings.Contains(res.Error, tt.wantErr) {
+				t.Errorf("status %v (%q), want a failure with %q", res.Status, res.Error, tt.wantErr)
+			}
+			if took := time.Since(start); took > 5*time.Second {
+				t.Errorf("check took %s", took)
+			}
+			if got := redirects.Load(); got != tt.wantRedirects {
+				t.Errorf("%d requests to the redirect loop, want %d", got, tt.wantRedirects)
+			}
+		})
+	}
+}
+
+func TestHTTPExpectFinalURL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/login?next=%2Fhome", http.StatusMovedPermanently))
+	mux.Handle("/login", http.RedirectHandler("/home", http.StatusFound))
+	mux.HandleFunc("/home", func(w http.ResponseWriter, r *http.Request) {})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		opts    string
+		wantUp  bool
+		wantErr string
+	}{
+		{"chain ends where expected", "/old", `, expect_final_url: "` + srv.URL + `/home"`, true, ""},
+		{"chain ends elsewhere", "/old", `, expect_final_url: "` + srv.URL + `/dashboard"`, false, "redirected to " + srv.URL + "/home, expected " + srv.URL + "/dashboard"},
+		{"no redirect", "/home", `, expect_final_url: "` + srv.URL + `/home"`, true, ""},
+		{"contains", "/old", `, expect_final_url_contains: "/home"`, true, ""},
+		{"doesn't contain", "/old", `, expect_final_url_contains: "/login"`, false, `expected a URL containing "/login"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := runHTTP(t, srv.URL+tt.path, tt.opts)
+			if res.Status != tt.wantUp || !strings.Contains(res.Error, tt.wantErr) {
+				t.Errorf("status %v (%q), want %v (%q)", res.Status, res.Error, tt.wantUp, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPStatusMap(t *testing.T) {
+	const statusMap = `, status_map: {"200-299": up, "301": degraded, "404": up, "500-599": down}`
+	tests := []struct {
+		code         int
+		wantUp       bool
+		wantDegraded bool
+	}{
+		{http.StatusOK, true, false},
+		{http.StatusNoContent, true, false},
+		{http.StatusMovedPermanently, true, true},
+		{http.StatusNotFound, true, false},
+		{http.StatusBadGateway, false, false},
+		{http.StatusTeapot, false, false}, // not mapped
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.code), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.code == http.StatusMovedPermanently {
+					// Don't follow, the 301 itself is what's mapped
+					w.Header().Set("Location", "")
+				}
+				w.WriteHeader(tt.code)
+			}))
+			defer srv.Close()
+			res := runHTTP(t, srv.URL, statusMap)
+			if res.Status != tt.wantUp || res.Degraded != tt.wantDegraded {
+				t.Errorf("up %v, degraded %v (%s); want %v, %v", res.Status, res.Degraded, res.Error, tt.wantUp, tt.wantDegraded)
+			}
+			if res.StatusCode != tt.code {
+				t.Errorf("status code %d, want %d", res.StatusCode, tt.code)
+			}
+		})
+	}
+}
+
+func TestHTTPExpectContentType(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string // "" sends no Content-Type at all
+		expect  string
+		wantUp  bool
+		wantErr string
+	}{
+		{"exact", "application/json", "application/json", true, ""},
+		{"charset ignored", "application/json; charset=utf-8", "application/json", true, ""},
+		{"case-insensitive", "Application/JSON", "application/json", true, ""},
+		{"prefix", "application/problem+json", "application/", true, ""},
+		{"mismatched", "text/html; charset=utf-8", "application/json", false, "content type text/html, expected application/json"},
+		{"missing", "", "application/json", false, "content type missing, expected application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.header == "" {
+					// Keep net/http from sniffing one
+					w.Header()["Content-Type"] = nil
+				} else {
+					w.Header().Set("Content-Type", tt.header)
+				}
+				w.Write([]byte("{}"))
+			}))
+			defer srv.Close()
+			res := runHTTP(t, srv.URL, ", expect_content_type: "+tt.expect)
+			if res.Status != tt.wantUp || !strings.Contains(res.Error, tt.wantErr) {
+				t.Errorf("status %v (%q), want %v (%q)", res.Status, res.Error, tt.wantUp, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPTLSVersion(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tls12 := newTLSServer(t, ok, trustedCerts[0], &tls.Config{MinVersion: tls.VersionTLS12, MaxVersion: tls.VersionTLS12})
+	tls13 := newTLSServer(t, ok, trustedCerts[0], &tls.Config{MinVersion: tls.VersionTLS13})
+
+	tests := []struct {
+		name   string
+		url    string
+		opts   string
+		wantUp bool
+	}{
+		{"no constraint on 1.2", tls12.URL, "", true},
+		{"min 1.2 on 1.2", tls12.URL, `, min_tls_version: "1.2"`, true},
+		{"min 1.3 on 1.2", tls12.URL, `, min_tls_version: "1.3"`, false},
+		{"min 1.3 on 1.3", tls13.URL, `, min_tls_version: "1.3"`, true},
+		{"max 1.2 on 1.3", tls13.URL, `, max_tls_version: "1.2"`, false},
+		{"range on 1.2", tls12.URL, `, min_tls_version: "1.0", max_tls_version: "1.2"`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := runHTTP(t, tt.url, tt.opts)
+			if res.Status != tt.wantUp {
+				t.Fatalf("status %v (%s), want %v", res.Status, res.Error, tt.wantUp)
+			}
+			if !tt.wantUp && !strings.Contains(res.Error, "version") {
+				t.Errorf("error %q doesn't mention the protocol version", res.Error)
+			}
+		})
+	}
+}
+
+func TestHTTPCertFingerprint(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	first := newTLSServer(t, ok, trustedCerts[0], nil)
+	second := newTLSServer(t, ok, trustedCerts[1], nil)
+	plain := httptest.NewServer(ok)
+	defer plain.Close()
+
+	sum := sha256.Sum256(trustedCerts[0].Certificate[0])
+	fp := hex.EncodeToString(sum[:])
+	var colons []string
+	for i := 0; i < len(fp); i += 2 {
+		colons = append(colons, strings.ToUpper(fp[i:i+2]))
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		fp      string
+		wantErr string // empty: UP
+	}{
+		{"match", first.URL, fp, ""},
+		{"match with colons", first.URL, strings.Join(colons, ":"), ""},
+		{"other certificate", second.URL, fp, "doesn't match expect_cert_fingerprint"},
+		{"no TLS", plain.URL, fp, "not over TLS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := runHTTP(t, tt.url, fmt.Sprintf(", expect_cert_fingerprint: %q", tt.fp))
+			if res.Status != (tt.wantErr == "") || !strings.Contains(res.Error, tt.wantErr) {
+				t.Errorf("up %v, error %q; want error %q", res.Status, res.Error, tt.wantErr)
+			}
+		})
+	}
+}
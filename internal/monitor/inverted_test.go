@@ -0,0 +1,86 @@
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInvertedHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	reachable := srv.URL
+	gone := httptest.NewServer(nil)
+	unreachable := gone.URL
+	gone.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		opts    string
+		wantUp  bool
+		wantErr string
+	}{
+		{"reachable", reachable, "", true, ""},
+		{"unreachable", unreachable, "", false, "refused"},
+		{"inverted, reachable", reachable, ", inverted: true", false, "expected not to be (inverted monitor)"},
+		{"inverted, unreachable", unreachable, ", inverted: true", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := runHTTP(t, tt.url, tt.opts)
+			if res.Status != tt.wantUp {
+				t.Errorf("up %v, want %v (%s)", res.Status, tt.wantUp, res.Error)
+			}
+			if !strings.Contains(res.Error, tt.wantErr) || (tt.wantErr == "" && res.Error != "") {
+				t.Errorf("error %q, want %q", res.Error, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInvertedTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	open := ln.Addr().(*net.TCPAddr).Port
+	gone, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := gone.Addr().(*net.TCPAddr).Port
+	gone.Close()
+
+	tests := []struct {
+		name    string
+		host    string
+		port    int
+		wantUp  bool
+		wantErr string
+	}{
+		{"open port", "127.0.0.1", open, false, "port unexpectedly open: connected to 127.0.0.1:"},
+		{"closed port", "127.0.0.1", closed, true, ""},
+		// Says nothing about the firewall
+		{"unresolvable host", "nowhere.invalid", closed, false, "nowhere.invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t, fmt.Sprintf(`
+monitors:
+  - {name: admin, type: tcp, host: %s, port: %d, inverted: true}
+`, tt.host, tt.port))
+			res := RunCheck(cfg.Monitors[0])
+			if res.Status != tt.wantUp {
+				t.Errorf("up %v, want %v (%s)", res.Status, tt.wantUp, res.Error)
+			}
+			if !strings.Contains(res.Error, tt.wantErr) || (tt.wantErr == "" && res.Error != "") {
+				t.Errorf("error %q, want %q", res.Error, tt.wantErr)
+			}
+		})
+	}
+}
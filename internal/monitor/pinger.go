@@ -1,22 +1,58 @@
 package monitor
 
 import (
+	"context"
 	"fmt"
-	"net"
-	"net/http"
+	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pronzzz/zenmonitor/internal/config"
+	"github.com/pronzzz/zenmonitor/internal/cron"
 )
 
+// Version is reported in the default User-Agent of HTTP checks.
+// Can be overridden at build time with -ldflags "-X .../monitor.Version=x.y.z".
+var Version = "dev"
+
+// DefaultUserAgent is sent by HTTP checks unless the config overrides it.
+// Plenty of bot filters reject Go's stock "Go-http-client/1.1".
+func DefaultUserAgent() string {
+	return "ZenMonitor/" + Version
+}
+
 // CheckResult represents the outcome of a single check
 type CheckResult struct {
 	MonitorName string
 	Timestamp   time.Time
 	Status      bool // true = UP, false = DOWN
+	Degraded    bool // UP, but status_map says not healthy
 	Latency     time.Duration
 	Error       string
+	StatusCode  int   // HTTP only, 0 otherwise
+	BodySize    int64 // HTTP only, bytes of response body read
+	Timings     HTTPTimings
+	// Set when a 429/503 response carried a Retry-After header.
+	// Only used for scheduling, not persisted.
+	RetryAfter time.Duration
+	// The monitor's configured labels, for notifications. Not persisted.
+	Labels map[string]string
+	// Checks this stored row stands for when store_every samples steady
+	// state; 0 and 1 both mean just this one.
+	Checks int
+	// Set on the alert the stale watchdog sends when no result arrived
+	// in time (see stale.go). Such results are never stored.
+	Stale bool
+}
+
+// HTTPTimings breaks an HTTP check's latency down by phase.
+// TTFB is measured from the start of the request, so it includes the others.
+type HTTPTimings struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	TTFB    time.Duration
 }
 
 // Store interface to decouple persistence
@@ -26,166 +62,589 @@ type Store interface {
 
 // Notifier interface (optional for now, or direct call)
 type Notifier interface {
-	Notify(monitorName string, isUp bool, oldState bool)
+	Notify(result CheckResult, wasUp bool)
 }
 
 type Engine struct {
-	Cfg      *config.Config
+	// Current config, replaced by Reload, see Config
+	cfg      atomic.Pointer[config.Config]
 	Store    Store
 	Notifier Notifier
-	// State tracking for alerting (simple map)
-	lastState map[string]bool 
-	mu        sync.RWMutex
-	stopCh    chan struct{}
+	// Runtime state per monitor name (alerting, schedule info), see state.go
+	states map[string]*monitorState
+	// Aggregate monitors keyed by the name of each of their children
+	parents map[string][]config.MonitorConfig
+	aggMu   sync.Mutex // serializes aggregate evaluation
+	mu      sync.RWMutex
+	// Outbound rate limiters keyed by target host, see ratelimit.go
+	limiters   map[string]*hostLimiter
+	limitersMu sync.Mutex
+	// Paused/muted state is restored from the store once, see control.go
+	runtimeOnce sync.Once
+	// Results that couldn't be written to the store even after retrying
+	storeErrors atomic.Uint64
+	// Results with timestamps that don't fit the clock, see clock.go
+	clockAnomalies atomic.Uint64
+	// Sinks, observers and subscribers of checks and transitions, see
+	// events.go
+	events eventBus
+	// One per monitor, so scheduled and on-demand checks of a monitor
+	// don't overlap and record results out of order. Guarded by mu, like
+	// parents; Reload updates both.
+	checkLocks map[string]*sync.Mutex
+	// Schedulers of the monitors, keyed by monitor name. Guarded by mu;
+	// nil until Start.
+	monitorRuns map[string]monitorRun
+	stopCh      chan struct{}
+	// Serializes Reload
+	reloadMu sync.Mutex
+	// Goroutines started by Start, which Stop waits for
+	running sync.WaitGroup
 }
 
 func NewEngine(cfg *config.Config, store Store, notifier Notifier) *Engine {
-	return &Engine{
-		Cfg:       cfg,
-		Store:     store,
-		Notifier:  notifier,
-		lastState: make(map[string]bool),
-		stopCh:    make(chan struct{}),
+	e := &Engine{
+		Store:    store,
+		Notifier: notifier,
+		states:   make(map[string]*monitorState),
+		parents:  make(map[string][]config.MonitorConfig),
+		limiters: make(map[string]*hostLimiter),
+		stopCh:   make(chan struct{}),
+
+		checkLocks: make(map[string]*sync.Mutex, len(cfg.Monitors)),
+	}
+	e.cfg.Store(cfg)
+	httpPool.configure(cfg.Global.HTTPPool)
+	for _, m := range cfg.Monitors {
+		e.checkLocks[m.Name] = &sync.Mutex{}
 	}
+	e.parents = aggregateParents(cfg.Monitors)
+	e.events.sinks = []func(Event){e.observeMetrics, e.storeResults, e.notify}
+	return e
+}
+
+// Config returns the config the engine currently runs, which Reload may
+// replace at any time. Callers that look at it more than once should keep
+// the returned pointer rather than call Config again.
+func (e *Engine) Config() *config.Config {
+	return e.cfg.Load()
+}
+
+// aggregateParents maps each monitor name to the aggregates it's a child of.
+func aggregateParents(monitors []config.MonitorConfig) map[string][]config.MonitorConfig {
+	parents := make(map[string][]config.MonitorConfig)
+	for _, m := range monitors {
+		if m.Type != "aggregate" {
+			continue
+		}
+		for _, child := range m.Children {
+			parents[child] = append(parents[child], m)
+		}
+	}
+	return parents
 }
 
 func (e *Engine) Start() {
-	for _, m := range e.Cfg.Monitors {
-		go e.runMonitor(m)
+	e.loadRuntimeStates()
+
+	cfg := e.Config()
+	e.mu.Lock()
+	e.monitorRuns = make(map[string]monitorRun, len(cfg.Monitors))
+	for _, m := range cfg.Monitors {
+		e.startMonitor(m, false)
 	}
+	e.mu.Unlock()
+	if cfg.Global.StaleAfter > 0 {
+		e.goRun(e.watchStale)
+	}
+	// Runs without SLOs too, a reload may add some
+	if counter, ok := e.Store.(CheckCounter); ok {
+		e.goRun(func() { e.watchSLOs(counter) })
+	}
+}
+
+// monitorRun is the scheduler goroutine of one monitor.
+type monitorRun struct {
+	stop chan struct{} // closed to stop it
+	done chan struct{} // closed once it returned, its last check recorded
+}
+
+// startMonitor starts the scheduler of a monitor, see runMonitor for
+// immediate. Aggregates don't probe anything, they follow their children;
+// push monitors are fed by remote agents through Ingest. Caller must hold
+// e.mu.
+func (e *Engine) startMonitor(m config.MonitorConfig, immediate bool) {
+	if m.Type == "aggregate" || m.Type == "push" {
+		return
+	}
+	run := monitorRun{stop: make(chan struct{}), done: make(chan struct{})}
+	e.monitorRuns[m.Name] = run
+	e.goRun(func() {
+		defer close(run.done)
+		e.runMonitor(m, run.stop, immediate)
+	})
+}
+
+// stopMonitor stops the scheduler of a monitor. A check in progress still
+// completes; the returned channel is closed once it has, right away if the
+// monitor had no scheduler. Caller must hold e.mu.
+func (e *Engine) stopMonitor(name string) <-chan struct{} {
+	run, ok := e.monitorRuns[name]
+	if !ok {
+		done := make(chan struct{})
+		close(done)
+		return done
+	}
+	close(run.stop)
+	delete(e.monitorRuns, name)
+	return run.done
+}
+
+// checkLock returns the lock that serializes the checks of a monitor, nil
+// once Reload removed the monitor.
+func (e *Engine) checkLock(name string) *sync.Mutex {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.checkLocks[name]
 }
 
-func (e *Engine) Stop() {
+// goRun runs f in a goroutine Stop waits for.
+func (e *Engine) goRun(f func()) {
+	e.running.Add(1)
+	go func() {
+		defer e.running.Done()
+		f()
+	}()
+}
+
+// Stop stops scheduling checks and waits for the ones in flight to finish
+// and record their results, until ctx is done. Checks aren't interrupted,
+// so a slow one may outlast ctx; Stop then returns ctx's error.
+func (e *Engine) Stop(ctx context.Context) error {
 	close(e.stopCh)
+	done := make(chan struct{})
+	go func() {
+		e.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("checks still running: %w", ctx.Err())
+	}
 }
 
-func (e *Engine) runMonitor(m config.MonitorConfig) {
+// runMonitor schedules the checks of a monitor until the engine stops or
+// stop is closed. The first check is right away when immediate is set (a
+// reload added or changed the monitor), otherwise as check_on_start says.
+// Cron monitors always follow their schedule.
+func (e *Engine) runMonitor(m config.MonitorConfig, stop <-chan struct{}, immediate bool) {
+	if m.Cron != "" {
+		e.runCronMonitor(m, stop)
+		return
+	}
+
 	// Determine interval
-	interval := config.ParseDuration(e.Cfg.Global.CheckInterval)
+	upInterval := config.ParseDuration(e.Config().Global.CheckInterval)
 	if m.Interval != "" {
-		interval = config.ParseDuration(m.Interval)
+		upInterval = config.ParseDuration(m.Interval)
+	}
+	// Optionally probe a failing monitor more often to catch the recovery
+	downInterval := upInterval
+	if m.DownInterval != "" {
+		downInterval = config.ParseDuration(m.DownInterval)
+	}
+	interval := upInterval
+
+	// Initial check immediately, unless told to wait for the first tick
+	// or the startup self-check already did it
+	next := time.Now()
+	if !immediate && ((m.CheckOnStart != nil && !*m.CheckOnStart) || e.State(m.Name).Checked) {
+		next = next.Add(interval)
 	}
 
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	for {
+		e.setNextCheck(m.Name, next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-e.stopCh:
+			timer.Stop()
+			return
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// Keep the schedule ticking while paused so resuming doesn't
+		// fire a burst of checks
+		if e.isPaused(m.Name) {
+			next = next.Add(interval)
+			continue
+		}
+		if !e.waitForHost(m) {
+			return
+		}
+
+		scheduled := next
+		result := e.timedCheck(m, interval)
 
-	// Initial check immediately
-	e.performCheck(m)
+		effective := upInterval
+		if !result.Status {
+			effective = downInterval
+		}
+		if effective != interval {
+			log.Printf("Monitor %s: now checking every %s", m.Name, effective)
+			interval = effective
+		}
+
+		// Stay on a fixed grid like a ticker would; slots that passed
+		// while an overrunning check was busy are skipped.
+		next = scheduled.Add(interval)
+		for !next.After(time.Now()) {
+			next = next.Add(interval)
+		}
+
+		// Back off politely if the upstream asked us to
+		if result.RetryAfter > 0 {
+			backoff := result.RetryAfter
+			if backoff > maxRetryAfter {
+				backoff = maxRetryAfter
+			}
+			if t := time.Now().Add(backoff); t.After(next) {
+				log.Printf("Monitor %s: upstream sent Retry-After, delaying next check by %s", m.Name, backoff)
+				next = t
+			}
+		}
+	}
+}
+
+// runCronMonitor fires checks at the times given by the monitor's cron
+// expression instead of on a fixed ticker, read in global.timezone. No
+// check is done on start: the schedule decides exactly when probes happen.
+func (e *Engine) runCronMonitor(m config.MonitorConfig, stop <-chan struct{}) {
+	sched, err := cron.Parse(m.Cron)
+	if err != nil {
+		// Validated in LoadConfig, so this shouldn't happen
+		log.Printf("Monitor %s: invalid cron expression: %v", m.Name, err)
+		return
+	}
+	loc, err := e.Config().Global.Location()
+	if err != nil {
+		// Validated in LoadConfig, so this shouldn't happen
+		log.Printf("Monitor %s: invalid timezone, running cron in UTC: %v", m.Name, err)
+		loc = time.UTC
+	}
 
 	for {
+		next := sched.Next(time.Now().In(loc))
+		if next.IsZero() {
+			log.Printf("Monitor %s: cron expression %q never fires", m.Name, m.Cron)
+			return
+		}
+
+		e.setNextCheck(m.Name, next)
+
+		timer := time.NewTimer(time.Until(next))
 		select {
 		case <-e.stopCh:
+			timer.Stop()
+			return
+		case <-stop:
+			timer.Stop()
 			return
-		case <-ticker.C:
-			e.performCheck(m)
+		case <-timer.C:
+			// Overruns are harmless here, Next() is computed from the
+			// time the check finished, but still worth knowing about.
+			if e.isPaused(m.Name) {
+				continue
+			}
+			if !e.waitForHost(m) {
+				return
+			}
+			following := sched.Next(next)
+			e.setNextCheck(m.Name, following)
+			e.timedCheck(m, time.Until(following))
 		}
 	}
 }
 
-func (e *Engine) performCheck(m config.MonitorConfig) {
+// storeRetryDelays are the pauses between attempts to write a result.
+// Enough to ride out a locked database, not enough to stall the monitor.
+var storeRetryDelays = []time.Duration{100 * time.Millisecond, 500 * time.Millisecond}
+
+// logCheck writes a result to the store, retrying transient failures.
+// A result that still can't be written is counted and logged, never
+// silently dropped.
+func (e *Engine) logCheck(result CheckResult) {
+	err := e.Store.LogCheck(result)
+	for _, delay := range storeRetryDelays {
+		if err == nil {
+			return
+		}
+		time.Sleep(delay)
+		err = e.Store.LogCheck(result)
+	}
+	if err != nil {
+		total := e.storeErrors.Add(1)
+		log.Printf("Monitor %s: failed to store check result (%d store errors so far): %v", result.MonitorName, total, err)
+	}
+}
+
+// StoreErrors returns how many check results failed to be stored.
+func (e *Engine) StoreErrors() uint64 {
+	return e.storeErrors.Load()
+}
+
+// maxRetryAfter caps how long a Retry-After header can postpone a check.
+const maxRetryAfter = 30 * time.Minute
+
+// timedCheck runs a check and flags it when it took longer than the interval.
+// The scheduler skips slots that passed while a check was running, so
+// without this an interval shorter than the check timeout would silently
+// check less often than configured.
+func (e *Engine) timedCheck(m config.MonitorConfig, interval time.Duration) CheckResult {
+	start := time.Now()
+	result := e.performCheck(m)
+	took := time.Since(start)
+
+	if interval > 0 && took > interval {
+		e.mu.Lock()
+		e.stateFor(m.Name).overruns++
+		e.mu.Unlock()
+		log.Printf("Monitor %s: check overrun, took %s but interval is %s (%d tick(s) skipped). Consider a longer interval.",
+			m.Name, took.Round(time.Millisecond), interval, int(took/interval))
+	}
+	return result
+}
+
+func (e *Engine) performCheck(m config.MonitorConfig) CheckResult {
+	mu := e.checkLock(m.Name)
+	if mu == nil {
+		return removedResult(m)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// Reload may have removed the monitor while this waited
+	if e.checkLock(m.Name) != mu {
+		return removedResult(m)
+	}
+	result := RunCheck(m)
+	e.checkLatencyAnomaly(m, &result)
+
+	e.recordResult(m, result)
+	e.updateAggregates(m.Name)
+	return result
+}
+
+// RunCheck probes a monitor once and returns the result, without touching
+// any engine state, store or notifier. Aggregate and push monitors have
+// nothing to probe and come back DOWN with an error.
+func RunCheck(m config.MonitorConfig) CheckResult {
 	start := time.Now()
 	var err error
 	var success bool
 
+	result := CheckResult{
+		MonitorName: m.Name,
+		Timestamp:   start,
+	}
+
 	// Perform the check based on type
 	switch m.Type {
 	case "http", "https":
-		success, err = checkHTTP(m)
+		success, err = checkHTTP(m, &result)
 	case "tcp":
 		success, err = checkTCP(m)
+	case "smtp":
+		success, err = checkSMTP(m)
+	case "exec":
+		success, err = checkExec(m)
 	case "icmp":
 		success, err = checkICMP(m) // "ping"
+	case "aggregate", "push":
+		err = fmt.Errorf("%s monitors can't be checked directly", m.Type)
 	default:
 		// Fallback or duplicate http logic
 		if m.URL != "" {
-			success, err = checkHTTP(m)
+			success, err = checkHTTP(m, &result)
 		} else {
 			err = fmt.Errorf("unknown monitor type")
 		}
 	}
 
-	latency := time.Since(start)
-
-	errMsg := ""
+	result.Status = success
+	result.Latency = time.Since(start)
 	if err != nil {
-		errMsg = err.Error()
+		result.Error = err.Error()
 	}
+	if m.Inverted && m.Type != "tcp" { // checkTCPClosed is inverted already
+		invert(&result)
+	}
+	if !result.Status {
+		result.Degraded = false
+	}
+	return result
+}
 
-	result := CheckResult{
-		MonitorName: m.Name,
-		Timestamp:   start,
-		Status:      success,
-		Latency:     latency,
-		Error:       errMsg,
+// invert turns the result of an inverted monitor's probe around: the
+// target answering is the outage. A failed probe is UP and its error,
+// which is the expected outcome, is dropped.
+func invert(result *CheckResult) {
+	if result.Status {
+		result.Status = false
+		result.Error = "target is reachable but expected not to be (inverted monitor)"
+		return
+	}
+	result.Status = true
+	result.Error = ""
+}
+
+// recordResult updates the monitor's state with a result and publishes it,
+// which stores it, and a transition alert on UP <-> DOWN changes.
+func (e *Engine) recordResult(m config.MonitorConfig, result CheckResult) {
+	success := result.Status
+
+	e.mu.RLock()
+	var prev time.Time
+	seen := false
+	if st, ok := e.states[result.MonitorName]; ok {
+		prev = st.lastCheck
+		seen = st.checked
 	}
+	e.mu.RUnlock()
+	e.checkClock(&result, prev)
 
-	// Persist
-	if e.Store != nil {
-		// Log error but don't stop
-		_ = e.Store.LogCheck(result)
+	// On the first check, pick up the current run from before a restart
+	var since time.Time
+	if ss, ok := e.Store.(StateSinceStore); ok && !seen {
+		t, err := ss.StateSince(result.MonitorName, success)
+		if err != nil {
+			log.Printf("Monitor %s: failed to look up last state change: %v", m.Name, err)
+		}
+		since = t
+	}
+	var flaps []time.Time
+	if ts, ok := e.Store.(TransitionStore); ok && !seen {
+		t, err := ts.Transitions(result.MonitorName, result.Timestamp.Add(-e.flapWindow()))
+		if err != nil {
+			log.Printf("Monitor %s: failed to look up state changes: %v", m.Name, err)
+		}
+		flaps = t
 	}
 
 	// Alerting / State Update
 	e.mu.Lock()
-	wasUp, exists := e.lastState[m.Name]
-	e.lastState[m.Name] = success
+	st := e.stateFor(result.MonitorName)
+	wasUp, exists := st.isUp, st.checked
+	wasStale := st.stale
+	st.stale = false
+	st.isUp = success
+	st.degraded = result.Degraded
+	st.checked = true
+	st.lastCheck = result.Timestamp
+	switch {
+	case !exists && !since.IsZero() && since.Before(result.Timestamp):
+		st.lastChange = since
+	case !exists || wasUp != success:
+		st.lastChange = result.Timestamp
+	}
+	if success {
+		st.consecutiveSuccesses++
+		st.consecutiveFailures = 0
+	} else {
+		st.consecutiveFailures++
+		st.consecutiveSuccesses = 0
+	}
+	if !exists {
+		st.flaps = flaps
+	}
+	flipped := st.countFlaps(exists && wasUp != success, result.Timestamp, e.flapWindow(), m.FlapThreshold)
+	flap := FlapAlert{
+		MonitorName: m.Name,
+		Flapping:    st.flapping,
+		Changes:     len(st.flaps),
+		Threshold:   m.FlapThreshold,
+		Window:      e.flapWindow(),
+		Timestamp:   result.Timestamp,
+		Labels:      m.Labels,
+	}
 	e.mu.Unlock()
 
-	// If state changed, or it's the first run (maybe don't alert on first run? 
-	// PRD: "Trigger alert on UP -> DOWN transition". 
+	if flipped {
+		e.alertFlapping(m, flap)
+	}
+
+	e.publish(Event{Type: EventCheck, Monitor: m, Result: result})
+
+	// If state changed, or it's the first run (maybe don't alert on first run?
+	// PRD: "Trigger alert on UP -> DOWN transition".
 	// So we need to know previous state. If new, assume it was UP or ignore?
 	// Let's assume on first run, we just set state.
-	if exists && wasUp != success {
-		if e.Notifier != nil {
-			e.Notifier.Notify(m.Name, success, wasUp)
+	if wasStale {
+		log.Printf("Monitor %s: reporting again", m.Name)
+	}
+	// A stale alert went out, so follow it up even without a transition
+	if (exists && wasUp != success) || wasStale {
+		if e.suppressAlert(m, success) {
+			return
+		}
+		if e.isMuted(m.Name) {
+			log.Printf("Monitor %s: alert not sent, monitor is muted", m.Name)
+			return
 		}
+		result.Labels = m.Labels
+		e.publish(Event{Type: EventTransition, Monitor: m, Result: result, WasUp: wasUp})
 	}
 }
 
-// --- Check Implementations ---
+// suppressAlert decides whether a transition alert is swallowed because a
+// monitor this one depends on is DOWN (the dependency's own alert covers
+// it). A recovery whose outage alert was suppressed is suppressed too.
+func (e *Engine) suppressAlert(m config.MonitorConfig, isUp bool) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 
-func checkHTTP(m config.MonitorConfig) (bool, error) {
-	client := http.Client{
-		Timeout: 10 * time.Second,
-	}
-	resp, err := client.Get(m.URL)
-	if err != nil {
-		return false, err
+	st := e.stateFor(m.Name)
+	if isUp {
+		if st.alertSuppressed {
+			st.alertSuppressed = false
+			log.Printf("Monitor %s: recovery alert suppressed, its outage alert was suppressed too", m.Name)
+			return true
+		}
+		return false
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != m.ExpectStatus {
-		return false, fmt.Errorf("status code %d, expected %d", resp.StatusCode, m.ExpectStatus)
+	for _, dep := range m.DependsOn {
+		if d, ok := e.states[dep]; ok && d.checked && !d.isUp {
+			st.alertSuppressed = true
+			log.Printf("Monitor %s: DOWN alert suppressed, dependency %s is DOWN", m.Name, dep)
+			return true
+		}
 	}
-	return true, nil
+	return false
 }
 
-func checkTCP(m config.MonitorConfig) (bool, error) {
-	target := fmt.Sprintf("%s:%d", m.Host, m.Port)
-	conn, err := net.DialTimeout("tcp", target, 10*time.Second)
-	if err != nil {
-		return false, err
-	}
-	conn.Close()
-	return true, nil
-}
+// --- Check Implementations ---
 
 func checkICMP(m config.MonitorConfig) (bool, error) {
 	// ICMP usually requires root or specialized libraries (go-ping).
-	// Since we want to keep deps low/simple, we might try a simple net.Dial("ip4:icmp") 
-	// but that needs root. 
+	// Since we want to keep deps low/simple, we might try a simple net.Dial("ip4:icmp")
+	// but that needs root.
 	// Or execute "ping" command?
 	// PRD says "ICMP (Ping)".
-	// standard lib does not easily support ICMP without privileges. 
-	// "github.com/prometheus-community/pro-bing" is common. 
+	// standard lib does not easily support ICMP without privileges.
+	// "github.com/prometheus-community/pro-bing" is common.
 	// For "Zen" minimal: let's try a TCP handshake to port 80? No, that's TCP.
-	// Let's implement a shell-out to `ping` as a fallback, or just skip proper ICMP for now 
+	// Let's implement a shell-out to `ping` as a fallback, or just skip proper ICMP for now
 	// and note it.
 	// Actually, let's use a "fake" ping via UDP dial? No.
 	// Let's treat ICMP as "not fully implemented" or use `go-ping` if I can add the dep.
 	// Since I can't run `go get`, I'll write the code assuming `exec.Command("ping")`.
 	// It's safer for "no-root" containers often.
-	
+
 	// Simplified shell ping
 	// ping -c 1 -W 1 host (linux)
 	return false, fmt.Errorf("ICMP not yet implemented (requires decision on root vs shell)")
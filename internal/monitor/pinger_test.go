@@ -0,0 +1,456 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestCronRunsInGlobalTimezone(t *testing.T) {
+	timezones := []string{
+		"UTC",
+		"Asia/Kolkata", // +05:30, so 09:00 there is never on the hour in UTC
+		"America/St_Johns",
+	}
+	for _, timezone := range timezones {
+		t.Run(timezone, func(t *testing.T) {
+			loc, err := time.LoadLocation(timezone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := NewEngine(testConfig(t, `
+global: {timezone: `+timezone+`}
+monitors:
+  - {name: nightly, type: tcp, host: 127.0.0.1, port: 1, cron: "0 9 * * *"}
+`), &memStore{}, nil)
+			e.Start()
+			defer e.Stop(context.Background())
+
+			var next time.Time
+			waitFor(t, func() bool {
+				next = e.State("nightly").NextCheck
+				return !next.IsZero()
+			})
+			if h, m := next.In(loc).Hour(), next.In(loc).Minute(); h != 9 || m != 0 {
+				t.Errorf("next check at %s, want 09:00 in %s", next.In(loc), timezone)
+			}
+			if d := time.Until(next); d <= 0 || d > 24*time.Hour {
+				t.Errorf("next check in %s, want within a day", d)
+			}
+		})
+	}
+}
+
+func TestCheckOverrun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		overruns int
+	}{
+		{"slower than the interval", 10 * time.Millisecond, 1},
+		{"within the interval", time.Minute, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+
+			cfg := testConfig(t, `
+monitors:
+  - {name: slow, type: http, url: "`+srv.URL+`"}
+`)
+			e := NewEngine(cfg, &memStore{}, nil)
+			e.timedCheck(cfg.Monitors[0], tt.interval)
+
+			if got := e.State("slow").Overruns; got != tt.overruns {
+				t.Errorf("Overruns = %d, want %d", got, tt.overruns)
+			}
+			if logged := strings.Contains(logs.String(), "check overrun"); logged != (tt.overruns > 0) {
+				t.Errorf("overrun logged = %v, want %v; log:\n%s", logged, tt.overruns > 0, logs.String())
+			}
+		})
+	}
+}
+
+func TestCheckOnStart(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	tests := []struct {
+		name   string
+		global string
+		own    string
+		want   int // checks right after the start
+	}{
+		{"default", "", "", 1},
+		{"disabled", "check_on_start: false", "", 0},
+		{"disabled for the monitor", "", ", check_on_start: false", 0},
+		{"enabled for the monitor", "check_on_start: false", ", check_on_start: true", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &memStore{}
+			e := NewEngine(testConfig(t, fmt.Sprintf(`
+global: {check_interval: 1h, %s}
+monitors:
+  - {name: db, type: tcp, host: 127.0.0.1, port: %d%s}
+`, tt.global, port, tt.own)), st, nil)
+			e.Start()
+			defer e.Stop(context.Background())
+
+			waitFor(t, func() bool { return !e.State("db").NextCheck.IsZero() })
+			time.Sleep(100 * time.Millisecond)
+			if got := len(st.checks("db")); got != tt.want {
+				t.Errorf("%d checks, want %d", got, tt.want)
+			}
+			// Without the initial check the first one waits a whole interval
+			if tt.want == 0 {
+				if d := time.Until(e.State("db").NextCheck); d < 59*time.Minute {
+					t.Errorf("next check in %s, want about an hour", d)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryAfterDelaysNextCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		wantDelay  time.Duration // roughly, from the first check
+	}{
+		{"429 with Retry-After", http.StatusTooManyRequests, "30", 30 * time.Second},
+		{"503 with Retry-After", http.StatusServiceUnavailable, "30", 30 * time.Second},
+		{"429 without", http.StatusTooManyRequests, "", time.Second},
+		{"Retry-After shorter than the interval", http.StatusTooManyRequests, "0", time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			st := &memStore{}
+			e := NewEngine(testConfig(t, `
+global: {check_interval: 1s}
+monitors:
+  - {name: api, type: http, url: "`+srv.URL+`"}
+`), st, nil)
+			e.Start()
+			defer e.Stop(context.Background())
+
+			var first CheckResult
+			waitFor(t, func() bool {
+				checks := st.checks("api")
+				if len(checks) == 0 {
+					return false
+				}
+				first = checks[0]
+				return e.State("api").NextCheck.After(first.Timestamp.Add(first.Latency))
+			})
+			if d := e.State("api").NextCheck.Sub(first.Timestamp); d < tt.wantDelay-time.Second || d > tt.wantDelay+time.Second {
+				t.Errorf("next check %s after the first, want about %s", d, tt.wantDelay)
+			}
+		})
+	}
+}
+
+// flakyStore fails the first failures writes.
+type flakyStore struct {
+	memStore
+	failures int
+	attempts int
+}
+
+func (s *flakyStore) LogCheck(result CheckResult) error {
+	s.mu.Lock()
+	s.attempts++
+	fail := s.attempts <= s.failures
+	s.mu.Unlock()
+	if fail {
+		return errors.New("database is locked")
+	}
+	return s.memStore.LogCheck(result)
+}
+
+func TestStoreErrors(t *testing.T) {
+	delays := storeRetryDelays
+	storeRetryDelays = []time.Duration{time.Millisecond, time.Millisecond}
+	defer func() { storeRetryDelays = delays }()
+
+	tests := []struct {
+		name       string
+		failures   int
+		wantStored int
+		wantErrors uint64
+	}{
+		{"no failures", 0, 1, 0},
+		{"retried", 2, 1, 0},
+		{"gave up", 3, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+
+			st := &flakyStore{failures: tt.failures}
+			e := NewEngine(testConfig(t, `
+monitors:
+  - {name: db, type: tcp, host: 127.0.0.1, port: 1}
+`), st, nil)
+			if _, err := e.CheckNow("db"); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := len(st.checks("db")); got != tt.wantStored {
+				t.Errorf("%d results stored, want %d", got, tt.wantStored)
+			}
+			if got := e.StoreErrors(); got != tt.wantErrors {
+				t.Errorf("StoreErrors() = %d, want %d", got, tt.wantErrors)
+			}
+			if logged := strings.Contains(logs.String(), "failed to store check result"); logged != (tt.wantErrors > 0) {
+				t.Errorf("failure logged = %v, want %v; log:\n%s", logged, tt.wantErrors > 0, logs.String())
+			}
+		})
+	}
+}
+
+func TestDownInterval(t *testing.T) {
+	var up atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	st := &memStore{}
+	e := NewEngine(testConfig(t, `
+global: {check_interval: 1h}
+monitors:
+  - {name: api, type: http, url: "`+srv.URL+`", down_interval: 50ms}
+`), st, nil)
+	e.Start()
+	defer e.Stop(context.Background())
+
+	steps := []struct {
+		name     string
+		up       bool
+		checks   int           // in a row in that state before looking
+		interval time.Duration // between the checks
+	}{
+		{"down", false, 3, 50 * time.Millisecond},
+		{"recovered", true, 1, time.Hour},
+	}
+	for _, step := range steps {
+		up.Store(step.up)
+		var last CheckResult
+		waitFor(t, func() bool {
+			checks := st.checks("api")
+			if len(checks) < step.checks {
+				return false
+			}
+			for _, c := range checks[len(checks)-step.checks:] {
+				if c.Status != step.up {
+					return false
+				}
+			}
+			last = checks[len(checks)-1]
+			return true
+		})
+		time.Sleep(20 * time.Millisecond)
+		next := e.State("api").NextCheck
+		if d := next.Sub(last.Timestamp); d < step.interval/2 || d > step.interval+time.Second {
+			t.Errorf("%s: next check %s after the last, want about %s", step.name, d, step.interval)
+		}
+	}
+}
+
+func TestDependencySuppressesAlerts(t *testing.T) {
+	// step sets both monitors' targets and checks them in order
+	type step struct {
+		gwUp, appUp bool
+		check       []string
+	}
+	tests := []struct {
+		name  string
+		steps []step
+		want  []bool // app alerts sent, by their IsUp
+	}{
+		{
+			name: "parent down",
+			steps: []step{
+				{true, true, []string{"gw", "app"}},
+				{false, false, []string{"gw", "app"}},
+				{true, true, []string{"gw", "app"}},
+			},
+			want: nil,
+		},
+		{
+			name: "parent up",
+			steps: []step{
+				{true, true, []string{"gw", "app"}},
+				{true, false, []string{"gw", "app"}},
+				{true, true, []string{"gw", "app"}},
+			},
+			want: []bool{false, true},
+		},
+		{
+			name: "parent recovered first",
+			steps: []step{
+				{true, true, []string{"gw", "app"}},
+				{false, false, []string{"gw", "app"}},
+				{true, false, []string{"gw", "app"}},
+				{true, true, []string{"app"}},
+			},
+			// The outage started while the parent was down, so its
+			// recovery is suppressed too
+			want: nil,
+		},
+		{
+			name: "child checked before the parent notices",
+			steps: []step{
+				{true, true, []string{"gw", "app"}},
+				{false, false, []string{"app", "gw"}},
+			},
+			want: []bool{false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gwUp, appUp atomic.Bool
+			n := &recordingNotifier{}
+			e := NewEngine(testConfig(t, `
+monitors:
+  - {name: gw, type: http, url: "`+toggleServer(t, &gwUp)+`"}
+  - {name: app, type: http, url: "`+toggleServer(t, &appUp)+`", depends_on: [gw]}
+`), &memStore{}, n)
+			for _, s := range tt.steps {
+				gwUp.Store(s.gwUp)
+				appUp.Store(s.appUp)
+				for _, name := range s.check {
+					if _, err := e.CheckNow(name); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			var got []bool
+			for _, sent := range n.notifications() {
+				if sent.result.MonitorName == "app" {
+					got = append(got, sent.result.Status)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("app alerts %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotificationLabels(t *testing.T) {
+	var up atomic.Bool
+	up.Store(true)
+	n := &recordingNotifier{}
+	e := NewEngine(testConfig(t, `
+monitors:
+  - {name: api, type: http, url: "`+toggleServer(t, &up)+`", labels: {team: payments}}
+`), &memStore{}, n)
+	for _, state := range []bool{true, false} {
+		up.Store(state)
+		if _, err := e.CheckNow("api"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sent := n.notifications()
+	if len(sent) == 0 {
+		t.Fatal("no alert sent")
+	}
+	if got := sent[len(sent)-1].result.Labels["team"]; got != "payments" {
+		t.Errorf("alert has team label %q, want %q", got, "payments")
+	}
+}
+
+func TestStopWaitsForChecks(t *testing.T) {
+	tests := []struct {
+		name         string
+		checkTakes   time.Duration // 0: until the test ends
+		timeout      time.Duration
+		wantErr      bool
+		wantRecorded bool
+	}{
+		{"check finishes in time", 50 * time.Millisecond, 5 * time.Second, false, true},
+		{"check outlasts the timeout", 0, 100 * time.Millisecond, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{}, 1)
+			release := make(chan struct{})
+			var once sync.Once
+			unblock := func() { once.Do(func() { close(release) }) }
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				started <- struct{}{}
+				<-release
+			}))
+			t.Cleanup(srv.Close)
+			t.Cleanup(unblock) // before srv.Close, which waits for the handler
+
+			cfg := testConfig(t, `
+global: {check_interval: 1h}
+monitors:
+  - {name: slow, type: http, url: "`+srv.URL+`", timeout: 30s}
+`)
+			st := &memStore{}
+			e := NewEngine(cfg, st, nil)
+			e.Start()
+			<-started
+			if tt.checkTakes > 0 {
+				time.AfterFunc(tt.checkTakes, unblock)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			begin := time.Now()
+			err := e.Stop(ctx)
+			took := time.Since(begin)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, context.DeadlineExceeded)) {
+				t.Errorf("Stop: %v, want error %v", err, tt.wantErr)
+			}
+			if took > tt.timeout+time.Second {
+				t.Errorf("Stop took %s with a %s timeout", took, tt.timeout)
+			}
+			if recorded := len(st.checks("slow")) > 0; recorded != tt.wantRecorded {
+				t.Errorf("check recorded when Stop returned: %v, want %v", recorded, tt.wantRecorded)
+			}
+		})
+	}
+}
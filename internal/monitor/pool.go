@@ -0,0 +1,67 @@
+package monitor
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// httpPool holds the transports HTTP checks share when global.http_pool
+// is set. Monitors whose connections are set up the same way share one,
+// so their idle connections can be reused.
+var httpPool transportPool
+
+type transportPool struct {
+	mu         sync.Mutex
+	cfg        *config.HTTPPoolConfig // nil: no pooling
+	transports map[transportKey]*http.Transport
+}
+
+// transportKey is everything newHTTPClient configures a transport from.
+type transportKey struct {
+	resolver, sourceIP, socketPath string
+	minTLS, maxTLS                 string
+}
+
+func keyFor(m config.MonitorConfig) transportKey {
+	return transportKey{
+		resolver:   m.Resolver,
+		sourceIP:   m.SourceIP,
+		socketPath: m.SocketPath,
+		minTLS:     m.MinTLSVersion,
+		maxTLS:     m.MaxTLSVersion,
+	}
+}
+
+// configure switches pooling on (or off, with nil), dropping the
+// transports built with the previous settings.
+func (p *transportPool) configure(cfg *config.HTTPPoolConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, t := range p.transports {
+		t.CloseIdleConnections()
+	}
+	p.cfg = cfg
+	p.transports = make(map[transportKey]*http.Transport)
+}
+
+// transport returns the shared transport for a monitor, building it with
+// build on first use. ok is false when pooling is off.
+func (p *transportPool) transport(m config.MonitorConfig, build func() *http.Transport) (t *http.Transport, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cfg == nil {
+		return nil, false
+	}
+	key := keyFor(m)
+	if t, ok := p.transports[key]; ok {
+		return t, true
+	}
+	t = build()
+	t.MaxIdleConns = p.cfg.MaxIdleConns
+	t.MaxIdleConnsPerHost = p.cfg.MaxIdleConnsPerHost
+	t.IdleConnTimeout = config.ParseDuration(p.cfg.IdleConnTimeout)
+	p.transports[key] = t
+	return t, true
+}
@@ -0,0 +1,115 @@
+package monitor
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// countingServer counts the connections made to it.
+func countingServer(tb testing.TB) (url string, conns *atomic.Int32) {
+	tb.Helper()
+	conns = new(atomic.Int32)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	tb.Cleanup(srv.Close)
+	return srv.URL, conns
+}
+
+func TestHTTPPool(t *testing.T) {
+	url, conns := countingServer(t)
+	tests := []struct {
+		name      string
+		pool      string
+		wantConns int32
+	}{
+		{"off", "", 5},
+		{"on", "http_pool: {max_idle_conns_per_host: 3, idle_conn_timeout: 30s}", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() { httpPool.configure(nil) })
+			e := NewEngine(testConfig(t, `
+global: {check_interval: 1h, `+tt.pool+`}
+monitors:
+  - {name: a, type: http, url: "`+url+`"}
+  - {name: b, type: http, url: "`+url+`/b"}
+  - {name: other, type: http, url: "`+url+`", source_ip: 127.0.0.1}
+`), &memStore{}, nil)
+			conns.Store(0)
+			for range 5 {
+				if res, err := e.CheckNow("a"); err != nil || !res.Status {
+					t.Fatalf("check: %v %s", err, res.Error)
+				}
+			}
+			if got := conns.Load(); got != tt.wantConns {
+				t.Errorf("%d connections for 5 checks, want %d", got, tt.wantConns)
+			}
+		})
+	}
+
+	// The configured values end up on the shared transports, one per way
+	// of connecting
+	t.Cleanup(func() { httpPool.configure(nil) })
+	cfg := testConfig(t, `
+global: {http_pool: {max_idle_conns: 7, max_idle_conns_per_host: 3, idle_conn_timeout: 30s}}
+monitors:
+  - {name: a, type: http, url: "`+url+`"}
+  - {name: b, type: http, url: "`+url+`/b"}
+  - {name: other, type: http, url: "`+url+`", source_ip: 127.0.0.1}
+`)
+	NewEngine(cfg, &memStore{}, nil)
+	transports := make([]*http.Transport, len(cfg.Monitors))
+	for i, m := range cfg.Monitors {
+		client, pooled := newHTTPClient(m)
+		if !pooled {
+			t.Fatalf("%s: not pooled", m.Name)
+		}
+		tr := client.Transport.(*http.Transport)
+		if tr.MaxIdleConns != 7 || tr.MaxIdleConnsPerHost != 3 || tr.IdleConnTimeout != 30*time.Second {
+			t.Errorf("%s: transport has %d idle, %d per host, %s timeout; want 7, 3, 30s",
+				m.Name, tr.MaxIdleConns, tr.MaxIdleConnsPerHost, tr.IdleConnTimeout)
+		}
+		transports[i] = tr
+	}
+	if transports[0] != transports[1] {
+		t.Errorf("monitors connecting the same way don't share a transport")
+	}
+	if transports[0] == transports[2] {
+		t.Errorf("monitor with its own source_ip shares a transport")
+	}
+}
+
+func BenchmarkHTTPCheck(b *testing.B) {
+	url, conns := countingServer(b)
+	m := config.MonitorConfig{Name: "web", Type: "http", URL: url, Method: http.MethodGet, ExpectStatus: http.StatusOK}
+	for _, bm := range []struct {
+		name string
+		pool *config.HTTPPoolConfig
+	}{
+		{"fresh", nil},
+		{"pooled", &config.HTTPPoolConfig{MaxIdleConns: 100, MaxIdleConnsPerHost: 10, IdleConnTimeout: "90s"}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			httpPool.configure(bm.pool)
+			defer httpPool.configure(nil)
+			conns.Store(0)
+			for range b.N {
+				if res := RunCheck(m); !res.Status {
+					b.Fatal(res.Error)
+				}
+			}
+			b.ReportMetric(float64(conns.Load())/float64(b.N), "conns/op")
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package monitor
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// ErrNotPushMonitor is returned by Ingest for monitors the engine probes itself.
+var ErrNotPushMonitor = errors.New("monitor does not accept pushed results")
+
+// Ingest records a result reported by a remote agent for a monitor of type
+// "push". It goes through the same state tracking and alerting as a local
+// check, under the monitor's check lock so results are recorded one at a
+// time. Results for paused monitors are dropped.
+func (e *Engine) Ingest(result CheckResult) error {
+	var m *config.MonitorConfig
+	cfg := e.Config()
+	for i := range cfg.Monitors {
+		if cfg.Monitors[i].Name == result.MonitorName {
+			m = &cfg.Monitors[i]
+			break
+		}
+	}
+	if m == nil || m.Type != "push" {
+		return ErrNotPushMonitor
+	}
+	if e.isPaused(m.Name) {
+		return nil
+	}
+	if result.Timestamp.IsZero() {
+		result.Timestamp = time.Now()
+	}
+
+	mu := e.checkLock(m.Name)
+	if mu == nil {
+		return ErrNotPushMonitor
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// Reload may have removed the monitor while this waited
+	if e.checkLock(m.Name) != mu {
+		return ErrNotPushMonitor
+	}
+	e.recordResult(*m, result)
+	e.updateAggregates(m.Name)
+	return nil
+}
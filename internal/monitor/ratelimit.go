@@ -0,0 +1,86 @@
+package monitor
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// hostLimiter is a token bucket for the checks hitting one host,
+// implemented as a GCRA: tat is when the bucket will be full again.
+type hostLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration // time to earn one token
+	burst    int
+	tat      time.Time
+}
+
+// reserve takes a token and returns how long to wait before using it.
+func (l *hostLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.tat.Before(now) {
+		l.tat = now
+	}
+	wait := l.tat.Sub(now) - time.Duration(l.burst-1)*l.interval
+	l.tat = l.tat.Add(l.interval)
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// waitForHost blocks until the monitor's target host has a check token
+// to spare (global.host_rate_limit). Returns false if the engine stopped
+// while waiting.
+func (e *Engine) waitForHost(m config.MonitorConfig) bool {
+	rate := e.Config().Global.HostRateLimit
+	host := targetHost(m)
+	if rate <= 0 || host == "" {
+		return true
+	}
+
+	e.limitersMu.Lock()
+	l, ok := e.limiters[host]
+	if !ok {
+		burst := e.Config().Global.HostRateBurst
+		if burst < 1 {
+			burst = 1
+		}
+		l = &hostLimiter{interval: time.Duration(float64(time.Second) / rate), burst: burst}
+		e.limiters[host] = l
+	}
+	e.limitersMu.Unlock()
+
+	wait := l.reserve(time.Now())
+	if wait == 0 {
+		return true
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-e.stopCh:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// targetHost is the host a monitor sends traffic to, "" if none (unix
+// sockets, aggregates).
+func targetHost(m config.MonitorConfig) string {
+	if m.SocketPath != "" {
+		return ""
+	}
+	if m.URL != "" {
+		u, err := url.Parse(m.URL)
+		if err != nil {
+			return ""
+		}
+		return u.Hostname()
+	}
+	return m.Host
+}
@@ -0,0 +1,75 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHostLimiterReserve(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		burst int
+		at    []time.Duration // reservations, from now
+		want  []time.Duration // waits
+	}{
+		{"one at a time", 1, []time.Duration{0, 0, 0}, []time.Duration{0, time.Second, 2 * time.Second}},
+		{"spaced out", 1, []time.Duration{0, time.Second, 3 * time.Second}, []time.Duration{0, 0, 0}},
+		{"burst", 2, []time.Duration{0, 0, 0, 0}, []time.Duration{0, 0, time.Second, 2 * time.Second}},
+		{"refilled", 2, []time.Duration{0, 0, 5 * time.Second, 5 * time.Second}, []time.Duration{0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &hostLimiter{interval: time.Second, burst: tt.burst}
+			for i, at := range tt.at {
+				if got := l.reserve(now.Add(at)); got != tt.want[i] {
+					t.Errorf("reservation %d: wait %s, want %s", i+1, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHostRateLimit(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]time.Time)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path] = time.Now()
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	// The same server under another host name
+	other := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+
+	e := NewEngine(testConfig(t, `
+global: {check_interval: 1h, host_rate_limit: 4}
+monitors:
+  - {name: a, type: http, url: "`+srv.URL+`/a"}
+  - {name: b, type: http, url: "`+srv.URL+`/b"}
+  - {name: c, type: http, url: "`+other+`/c"}
+`), &memStore{}, nil)
+	start := time.Now()
+	e.Start()
+	defer e.Stop(context.Background())
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(hits) == 3
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	// 4 per second: the second check of 127.0.0.1 waits 250ms
+	if gap := hits["/a"].Sub(hits["/b"]).Abs(); gap < 200*time.Millisecond {
+		t.Errorf("checks of the same host %s apart, want at least 250ms", gap)
+	}
+	if d := hits["/c"].Sub(start); d > 200*time.Millisecond {
+		t.Errorf("check of another host after %s, want it right away", d)
+	}
+}
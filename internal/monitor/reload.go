@@ -0,0 +1,140 @@
+package monitor
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// ErrNoMonitors is returned by Reload for a config without monitors while
+// the running one has some. That's far more likely a truncated or empty
+// read than an intent to stop monitoring everything.
+var ErrNoMonitors = errors.New("new config has no monitors, refusing to remove all of them")
+
+// ReloadSummary lists what a Reload changed, by monitor name.
+type ReloadSummary struct {
+	Added   []string
+	Removed []string
+	Changed []string
+	// The new config also changes global settings or notifications,
+	// which only take effect on a restart
+	RestartNeeded bool
+}
+
+// Reload switches the engine to the monitors and views of cfg, which must
+// have been loaded and validated with config.LoadConfig. Monitors that are
+// gone stop being checked, new ones start, and changed ones are
+// rescheduled with their new settings, keeping their state; both are
+// checked right away unless global.check_on_reload is off. Unchanged
+// monitors carry on undisturbed. The rest of the engine's config stays as
+// it was.
+//
+// Reload returns once the schedulers of removed and changed monitors have
+// stopped, which waits for checks of them in progress, so no result of a
+// removed monitor turns up afterwards.
+func (e *Engine) Reload(cfg *config.Config) (ReloadSummary, error) {
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+
+	old := e.Config()
+	if len(cfg.Monitors) == 0 && len(old.Monitors) > 0 {
+		return ReloadSummary{}, ErrNoMonitors
+	}
+	next := *old
+	next.Monitors = cfg.Monitors
+	next.Views = cfg.Views
+	next.Skipped = cfg.Skipped
+
+	sum := ReloadSummary{
+		RestartNeeded: !reflect.DeepEqual(old.Global, cfg.Global) ||
+			!reflect.DeepEqual(old.Notifications, cfg.Notifications),
+	}
+	previous := make(map[string]config.MonitorConfig, len(old.Monitors))
+	for _, m := range old.Monitors {
+		previous[m.Name] = m
+	}
+	var restart []config.MonitorConfig
+	for _, m := range cfg.Monitors {
+		prev, ok := previous[m.Name]
+		delete(previous, m.Name)
+		switch {
+		case !ok:
+			sum.Added = append(sum.Added, m.Name)
+		case !reflect.DeepEqual(prev, m):
+			sum.Changed = append(sum.Changed, m.Name)
+		default:
+			continue
+		}
+		restart = append(restart, m)
+	}
+	for _, m := range old.Monitors {
+		if _, ok := previous[m.Name]; ok {
+			sum.Removed = append(sum.Removed, m.Name)
+		}
+	}
+
+	e.mu.Lock()
+	e.cfg.Store(&next)
+	e.parents = aggregateParents(next.Monitors)
+	for _, name := range sum.Added {
+		e.checkLocks[name] = &sync.Mutex{}
+	}
+	started := e.monitorRuns != nil
+	var stopped []<-chan struct{}
+	if started {
+		for _, name := range sum.Removed {
+			stopped = append(stopped, e.stopMonitor(name))
+		}
+		for _, name := range sum.Changed {
+			stopped = append(stopped, e.stopMonitor(name))
+		}
+	}
+	e.mu.Unlock()
+
+	// Old schedulers finish their check in progress before the new ones
+	// start, so the two don't both set the next check time
+	for _, done := range stopped {
+		<-done
+	}
+	for _, name := range sum.Removed {
+		e.forget(name)
+	}
+	if started {
+		immediate := old.Global.CheckOnReload == nil || *old.Global.CheckOnReload
+		e.mu.Lock()
+		for _, m := range restart {
+			e.startMonitor(m, immediate)
+		}
+		e.mu.Unlock()
+	}
+	return sum, nil
+}
+
+// forget drops the state of a monitor Reload removed. It waits for an
+// on-demand check of it that's still running; checks that come after find
+// no check lock and don't record anything (see removedResult).
+func (e *Engine) forget(name string) {
+	mu := e.checkLock(name)
+	if mu == nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.checkLocks, name)
+	delete(e.states, name)
+}
+
+// removedResult is the outcome of a check of a monitor that Reload removed
+// in the meantime. It isn't recorded.
+func removedResult(m config.MonitorConfig) CheckResult {
+	return CheckResult{
+		MonitorName: m.Name,
+		Timestamp:   time.Now(),
+		Error:       "monitor was removed from the config",
+	}
+}
@@ -0,0 +1,210 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+const reloadBase = `
+global: {check_interval: 1h}
+monitors:
+  - {name: a, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: b, type: tcp, host: 127.0.0.1, port: 2}
+`
+
+func TestReloadSummary(t *testing.T) {
+	tests := []struct {
+		name          string
+		next          string
+		added         []string
+		removed       []string
+		changed       []string
+		restartNeeded bool
+	}{
+		{
+			name: "unchanged",
+			next: reloadBase,
+		},
+		{
+			name: "added, removed and changed",
+			next: `
+global: {check_interval: 1h}
+monitors:
+  - {name: a, type: tcp, host: 127.0.0.1, port: 3}
+  - {name: c, type: tcp, host: 127.0.0.1, port: 2}
+`,
+			added:   []string{"c"},
+			removed: []string{"b"},
+			changed: []string{"a"},
+		},
+		{
+			name: "global change needs a restart",
+			next: `
+global: {check_interval: 1h, history_days: 7}
+monitors:
+  - {name: a, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: b, type: tcp, host: 127.0.0.1, port: 2}
+`,
+			restartNeeded: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine(testConfig(t, reloadBase), &memStore{}, nil)
+			sum, err := e.Reload(testConfig(t, tt.next))
+			if err != nil {
+				t.Fatalf("Reload: %v", err)
+			}
+			if !slices.Equal(sum.Added, tt.added) || !slices.Equal(sum.Removed, tt.removed) || !slices.Equal(sum.Changed, tt.changed) {
+				t.Errorf("added %v, removed %v, changed %v; want %v, %v, %v",
+					sum.Added, sum.Removed, sum.Changed, tt.added, tt.removed, tt.changed)
+			}
+			if sum.RestartNeeded != tt.restartNeeded {
+				t.Errorf("RestartNeeded = %v, want %v", sum.RestartNeeded, tt.restartNeeded)
+			}
+			// Global settings stay until a restart
+			if got := e.Config().Global.HistoryDays; got == 7 {
+				t.Errorf("global.history_days was applied by the reload")
+			}
+		})
+	}
+}
+
+func TestReloadRefusesEmptyConfig(t *testing.T) {
+	e := NewEngine(testConfig(t, reloadBase), &memStore{}, nil)
+	_, err := e.Reload(testConfig(t, "global: {check_interval: 1h}\n"))
+	if !errors.Is(err, ErrNoMonitors) {
+		t.Fatalf("Reload of an empty config: err = %v, want ErrNoMonitors", err)
+	}
+	if n := len(e.Config().Monitors); n != 2 {
+		t.Errorf("running config has %d monitors after a refused reload, want 2", n)
+	}
+
+	// Nothing to lose when there were no monitors to begin with
+	e = NewEngine(testConfig(t, "global: {check_interval: 1h}\n"), &memStore{}, nil)
+	if _, err := e.Reload(testConfig(t, "global: {check_interval: 1h}\n")); err != nil {
+		t.Errorf("Reload of an empty config over an empty one: %v", err)
+	}
+}
+
+// A monitor removed while its check is running leaves no state behind:
+// Reload waits for the check to be recorded before dropping the monitor.
+func TestReloadWaitsForRemovedMonitorsCheck(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case entered <- struct{}{}:
+		default:
+		}
+		<-release
+	}))
+	defer srv.Close()
+
+	st := &memStore{}
+	e := NewEngine(testConfig(t, `
+global: {check_interval: 1h}
+monitors:
+  - {name: slow, type: http, url: "`+srv.URL+`"}
+  - {name: keep, type: tcp, host: 127.0.0.1, port: 1}
+`), st, nil)
+	e.Start()
+	defer e.Stop(context.Background())
+	<-entered
+
+	reloaded := make(chan error)
+	go func() {
+		_, err := e.Reload(testConfig(t, `
+global: {check_interval: 1h}
+monitors:
+  - {name: keep, type: tcp, host: 127.0.0.1, port: 1}
+`))
+		reloaded <- err
+	}()
+	select {
+	case <-reloaded:
+		t.Fatal("Reload returned while a check of the removed monitor was running")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	if err := <-reloaded; err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	if n := len(st.checks("slow")); n != 1 {
+		t.Errorf("%d checks of the removed monitor stored, want the one in flight", n)
+	}
+	for _, s := range e.States() {
+		if s.Name == "slow" {
+			t.Errorf("removed monitor is still in States()")
+		}
+	}
+	e.mu.RLock()
+	_, ghost := e.states["slow"]
+	e.mu.RUnlock()
+	if ghost {
+		t.Errorf("removed monitor's state is still kept")
+	}
+	if _, err := e.CheckNow("slow"); !errors.Is(err, ErrUnknownMonitor) {
+		t.Errorf("CheckNow of the removed monitor: err = %v, want ErrUnknownMonitor", err)
+	}
+}
+
+func TestReloadChecksRightAway(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	tests := []struct {
+		name          string
+		checkOnReload string
+		want          map[string]int // checks per monitor shortly after the reload
+	}{
+		{"default", "", map[string]int{"added": 1, "changed": 2, "same": 1}},
+		{"off", "check_on_reload: false", map[string]int{"added": 1, "changed": 1, "same": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitors := func(changedPort int, added bool) string {
+				src := fmt.Sprintf(`
+global: {check_interval: 1h, %s}
+monitors:
+  - {name: same, type: tcp, host: 127.0.0.1, port: %d}
+  - {name: changed, type: tcp, host: 127.0.0.1, port: %d}
+`, tt.checkOnReload, port, changedPort)
+				if added {
+					src += fmt.Sprintf("  - {name: added, type: tcp, host: 127.0.0.1, port: %d}\n", port)
+				}
+				return src
+			}
+			st := &memStore{}
+			e := NewEngine(testConfig(t, monitors(port, false)), st, nil)
+			e.Start()
+			defer e.Stop(context.Background())
+			waitFor(t, func() bool { return len(st.checks("same")) == 1 && len(st.checks("changed")) == 1 })
+
+			if _, err := e.Reload(testConfig(t, monitors(1, true))); err != nil {
+				t.Fatal(err)
+			}
+			// The added monitor is checked on start either way, since it
+			// has never been checked (check_on_start)
+			waitFor(t, func() bool { return len(st.checks("added")) == 1 })
+			time.Sleep(100 * time.Millisecond)
+			for name, want := range tt.want {
+				if got := len(st.checks(name)); got != want {
+					t.Errorf("%s: %d checks, want %d", name, got, want)
+				}
+			}
+		})
+	}
+}
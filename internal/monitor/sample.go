@@ -0,0 +1,47 @@
+package monitor
+
+import "github.com/pronzzz/zenmonitor/internal/config"
+
+// sampleForStore implements store_every: of a run of identical healthy
+// checks only every Nth is stored, standing in for the ones skipped
+// before it (CheckResult.Checks). Failures, degraded results and
+// transitions are always stored; the last skipped check is flushed first
+// so the history shows when the steady state ended.
+//
+// It returns the results to write, in order. Skipped checks that are
+// still pending when the engine stops are lost, which only costs a
+// little uptime precision.
+func (e *Engine) sampleForStore(m config.MonitorConfig, result CheckResult) []CheckResult {
+	if m.StoreEvery <= 1 {
+		return []CheckResult{result}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	st := e.stateFor(m.Name)
+
+	// The monitor's state already has this result, so the sampler keeps
+	// track of the one before itself
+	healthy := result.Status && !result.Degraded
+	wasHealthy := st.sampledHealthy
+	st.sampledHealthy = healthy
+	if healthy && wasHealthy {
+		st.skipped++
+		if st.skipped < m.StoreEvery {
+			st.pending = result
+			return nil
+		}
+		result.Checks = st.skipped
+		st.skipped = 0
+		return []CheckResult{result}
+	}
+
+	var out []CheckResult
+	if st.skipped > 0 {
+		pending := st.pending
+		pending.Checks = st.skipped
+		out = append(out, pending)
+		st.skipped = 0
+	}
+	return append(out, result)
+}
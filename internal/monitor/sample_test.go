@@ -0,0 +1,63 @@
+package monitor
+
+import (
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+func TestStoreEvery(t *testing.T) {
+	type stored struct {
+		up     bool
+		checks int
+	}
+	tests := []struct {
+		name       string
+		storeEvery string
+		sequence   []bool // check results, in order
+		want       []stored
+	}{
+		{
+			name:     "every check by default",
+			sequence: []bool{true, true, true, false},
+			want:     []stored{{true, 0}, {true, 0}, {true, 0}, {false, 0}},
+		},
+		{
+			name:       "steady state sampled, transitions kept",
+			storeEvery: ", store_every: 3",
+			sequence:   []bool{true, true, true, true, true, true, false, false, true, true},
+			want: []stored{
+				{true, 0},  // first check
+				{true, 3},  // stands for 3 checks
+				{true, 2},  // the 2 skipped before going down
+				{false, 0}, // failures are all stored
+				{false, 0},
+				{true, 0}, // recovery
+				// the last UP is pending
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var up atomic.Bool
+			st := &memStore{}
+			e := NewEngine(testConfig(t, `
+monitors:
+  - {name: api, type: http, url: "`+toggleServer(t, &up)+`"`+tt.storeEvery+`}
+`), st, nil)
+			for _, status := range tt.sequence {
+				up.Store(status)
+				if _, err := e.CheckNow("api"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var got []stored
+			for _, r := range st.checks("api") {
+				got = append(got, stored{r.Status, r.Checks})
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("stored %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
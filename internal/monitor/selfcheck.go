@@ -0,0 +1,49 @@
+package monitor
+
+import "sync"
+
+// SelfCheckSummary counts the outcome of a startup self-check.
+type SelfCheckSummary struct {
+	Total  int
+	Up     int
+	Down   int // the target answered, but an assertion failed
+	Errors int // no answer at all: DNS, connection, TLS or config problems
+}
+
+// SelfCheck probes every monitor once, concurrently, and waits for the
+// results. It's meant to run before Start, which then doesn't repeat the
+// check on start for monitors covered here. Paused, aggregate and push
+// monitors are skipped.
+func (e *Engine) SelfCheck() SelfCheckSummary {
+	e.loadRuntimeStates()
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sum SelfCheckSummary
+	)
+	for _, m := range e.Config().Monitors {
+		if m.Type == "aggregate" || m.Type == "push" || e.isPaused(m.Name) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := e.performCheck(m)
+
+			mu.Lock()
+			defer mu.Unlock()
+			sum.Total++
+			switch {
+			case result.Status:
+				sum.Up++
+			case result.StatusCode != 0:
+				sum.Down++
+			default:
+				sum.Errors++
+			}
+		}()
+	}
+	wg.Wait()
+	return sum
+}
@@ -0,0 +1,49 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSelfCheckSummary(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer up.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	st := &memStore{}
+	e := NewEngine(testConfig(t, `
+global: {check_interval: 1h}
+monitors:
+  - {name: up1, type: http, url: "`+up.URL+`"}
+  - {name: up2, type: http, url: "`+up.URL+`/other"}
+  - {name: failing, type: http, url: "`+failing.URL+`"}
+  - {name: refused, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: paused, type: http, url: "`+up.URL+`"}
+  - {name: agent, type: push}
+  - {name: all, type: aggregate, children: [up1, failing]}
+`), st, nil)
+	if err := e.Pause("paused"); err != nil {
+		t.Fatal(err)
+	}
+
+	sum := e.SelfCheck()
+	want := SelfCheckSummary{Total: 4, Up: 2, Down: 1, Errors: 1}
+	if sum != want {
+		t.Errorf("SelfCheck() = %+v, want %+v", sum, want)
+	}
+	// The self-check results are recorded like any other check
+	for _, name := range []string{"up1", "up2", "failing", "refused"} {
+		if n := len(st.checks(name)); n != 1 {
+			t.Errorf("%s: %d checks stored, want 1", name, n)
+		}
+	}
+	for _, name := range []string{"paused", "agent"} {
+		if n := len(st.checks(name)); n != 0 {
+			t.Errorf("%s: %d checks stored, want none", name, n)
+		}
+	}
+}
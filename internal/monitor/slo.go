@@ -0,0 +1,123 @@
+package monitor
+
+import (
+	"log"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// sloEvalInterval is how often SLO burn rates are recomputed.
+const sloEvalInterval = time.Minute
+
+// CheckCounter counts stored checks. It's optional like RuntimeStore: SLOs
+// are only evaluated when the engine's Store implements it.
+type CheckCounter interface {
+	CountChecks(monitorName string, since time.Time) (up, total int, err error)
+}
+
+// SLOAlert reports that a monitor's error budget started or stopped
+// burning too fast.
+type SLOAlert struct {
+	MonitorName string
+	Firing      bool // false when the burn rate dropped back below the threshold
+	Target      float64
+	BurnRate    float64
+	Threshold   float64
+	// Fraction of the window's error budget left; negative once overspent
+	BudgetRemaining float64
+	Timestamp       time.Time
+	Labels          map[string]string
+}
+
+// SLONotifier is implemented by notifiers that deliver SLO alerts.
+type SLONotifier interface {
+	NotifySLO(alert SLOAlert)
+}
+
+// SLOStatus is the last evaluation of a monitor's SLO.
+type SLOStatus struct {
+	BurnRate        float64
+	BudgetRemaining float64
+	Firing          bool
+}
+
+// watchSLOs periodically evaluates the SLO of every monitor that has one.
+func (e *Engine) watchSLOs(counter CheckCounter) {
+	ticker := time.NewTicker(sloEvalInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case now := <-ticker.C:
+			for _, m := range e.Config().Monitors {
+				if m.SLO != nil {
+					e.evaluateSLO(counter, m, now)
+				}
+			}
+		}
+	}
+}
+
+// evaluateSLO recomputes a monitor's burn rate and budget, alerting when
+// the burn rate crosses the threshold in either direction.
+func (e *Engine) evaluateSLO(counter CheckCounter, m config.MonitorConfig, now time.Time) {
+	slo := *m.SLO
+	burnUp, burnTotal, err := counter.CountChecks(m.Name, now.Add(-config.ParseDuration(slo.BurnWindow)))
+	if err != nil {
+		log.Printf("Monitor %s: failed to evaluate SLO: %v", m.Name, err)
+		return
+	}
+	up, total, err := counter.CountChecks(m.Name, now.Add(-config.ParseDuration(slo.Window)))
+	if err != nil {
+		log.Printf("Monitor %s: failed to evaluate SLO: %v", m.Name, err)
+		return
+	}
+
+	status := SLOStatus{
+		BurnRate:        burnRate(burnUp, burnTotal, slo.ErrorBudget()),
+		BudgetRemaining: 1 - burnRate(up, total, slo.ErrorBudget()),
+	}
+	status.Firing = status.BurnRate >= slo.BurnRate
+
+	e.mu.Lock()
+	st := e.stateFor(m.Name)
+	wasFiring := st.slo != nil && st.slo.Firing
+	st.slo = &status
+	e.mu.Unlock()
+
+	if status.Firing == wasFiring {
+		return
+	}
+	if status.Firing {
+		log.Printf("Monitor %s: error budget burning at %.1fx (threshold %.1fx), %.0f%% left",
+			m.Name, status.BurnRate, slo.BurnRate, 100*status.BudgetRemaining)
+	} else {
+		log.Printf("Monitor %s: error budget burn back to %.1fx", m.Name, status.BurnRate)
+	}
+	if e.isMuted(m.Name) {
+		log.Printf("Monitor %s: alert not sent, monitor is muted", m.Name)
+		return
+	}
+	e.publish(Event{Type: EventSLO, Monitor: m, SLO: SLOAlert{
+		MonitorName:     m.Name,
+		Firing:          status.Firing,
+		Target:          slo.Target,
+		BurnRate:        status.BurnRate,
+		Threshold:       slo.BurnRate,
+		BudgetRemaining: status.BudgetRemaining,
+		Timestamp:       now,
+		Labels:          m.Labels,
+	}})
+}
+
+// burnRate is the failure rate as a multiple of the error budget: 1 uses
+// the budget up exactly over the window, 0 when there's no data.
+func burnRate(up, total int, budget float64) float64 {
+	if total == 0 || budget <= 0 {
+		return 0
+	}
+	failed := float64(total-up) / float64(total)
+	return failed / budget
+}
@@ -0,0 +1,146 @@
+package monitor
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBurnRate(t *testing.T) {
+	tests := []struct {
+		name      string
+		up, total int
+		budget    float64
+		want      float64
+	}{
+		{"no data", 0, 0, 0.001, 0},
+		{"no failures", 1000, 1000, 0.001, 0},
+		{"budget used up exactly", 999, 1000, 0.001, 1},
+		{"14.4x", 9856, 10000, 0.001, 14.4},
+		{"all failing", 0, 100, 0.01, 100},
+		{"no budget", 50, 100, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := burnRate(tt.up, tt.total, tt.budget); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("burnRate(%d, %d, %g) = %g, want %g", tt.up, tt.total, tt.budget, got, tt.want)
+			}
+		})
+	}
+}
+
+// seededCounter counts checks from a fixed list of results.
+type seededCounter struct {
+	results []CheckResult
+}
+
+func (c *seededCounter) CountChecks(monitorName string, since time.Time) (up, total int, err error) {
+	for _, r := range c.results {
+		if r.MonitorName == monitorName && !r.Timestamp.Before(since) {
+			total++
+			if r.Status {
+				up++
+			}
+		}
+	}
+	return up, total, nil
+}
+
+// sloNotifier records SLO alerts.
+type sloNotifier struct {
+	recordingNotifier
+	mu     sync.Mutex
+	alerts []SLOAlert
+}
+
+func (n *sloNotifier) NotifySLO(alert SLOAlert) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.alerts = append(n.alerts, alert)
+}
+
+func (n *sloNotifier) sent() []SLOAlert {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]SLOAlert(nil), n.alerts...)
+}
+
+func TestSLOAlert(t *testing.T) {
+	// A budget of 1% over a day, alerting at 10x: 6 failures out of the
+	// last hour's 60 checks
+	cfg := testConfig(t, `
+monitors:
+  - name: api
+    type: tcp
+    host: 127.0.0.1
+    port: 1
+    slo: {target: 99, window: 24h, burn_window: 1h, burn_rate: 10}
+`)
+	m := cfg.Monitors[0]
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// A check a minute for the day up to now, failing the last
+	// `failures` of them
+	seed := func(failures int) *seededCounter {
+		c := &seededCounter{}
+		for i := 1; i <= 24*60; i++ {
+			c.results = append(c.results, CheckResult{
+				MonitorName: "api",
+				Timestamp:   now.Add(-time.Duration(i) * time.Minute),
+				Status:      i > failures,
+			})
+		}
+		return c
+	}
+
+	steps := []struct {
+		name     string
+		failures int
+		at       time.Duration // after now
+		burnRate float64
+		firing   bool
+		alert    bool // a new alert is sent
+	}{
+		{"below the threshold", 5, 0, 5.0 / 60 / 0.01, false, false},
+		{"at the threshold", 6, 0, 10, true, true},
+		{"still burning", 30, 0, 50, true, false},
+		// The failures fell out of the burn window
+		{"recovered", 30, 2 * time.Hour, 0, false, true},
+	}
+
+	n := &sloNotifier{}
+	e := NewEngine(cfg, &memStore{}, n)
+	for _, s := range steps {
+		before := len(n.sent())
+		e.evaluateSLO(seed(s.failures), m, now.Add(s.at))
+
+		st := e.State("api").SLO
+		if st == nil {
+			t.Fatalf("%s: no SLO status", s.name)
+		}
+		if math.Abs(st.BurnRate-s.burnRate) > 1e-9 || st.Firing != s.firing {
+			t.Errorf("%s: burn rate %g, firing %v; want %g, %v", s.name, st.BurnRate, st.Firing, s.burnRate, s.firing)
+		}
+		alerts := n.sent()[before:]
+		if !s.alert {
+			if len(alerts) != 0 {
+				t.Errorf("%s: sent %+v, want no alert", s.name, alerts)
+			}
+			continue
+		}
+		if len(alerts) != 1 {
+			t.Fatalf("%s: sent %d alerts, want 1", s.name, len(alerts))
+		}
+		if a := alerts[0]; a.MonitorName != "api" || a.Firing != s.firing || a.Threshold != 10 || a.Target != 99 {
+			t.Errorf("%s: alert %+v", s.name, a)
+		}
+	}
+
+	// 6 of the day's 1440 checks failed: 1 - (6/1440)/0.01 of the budget left
+	e = NewEngine(cfg, &memStore{}, n)
+	e.evaluateSLO(seed(6), m, now)
+	if got, want := e.State("api").SLO.BudgetRemaining, 1-6.0/1440/0.01; math.Abs(got-want) > 1e-9 {
+		t.Errorf("budget remaining %g, want %g", got, want)
+	}
+}
@@ -0,0 +1,57 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/textproto"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// smtpTimeout bounds the whole SMTP conversation after connecting.
+const smtpTimeout = 10 * time.Second
+
+// checkSMTP verifies a mail server actually speaks SMTP: it must greet
+// with 220 and accept EHLO with 250. Nothing is sent beyond that, the
+// session ends with QUIT.
+func checkSMTP(m config.MonitorConfig) (bool, error) {
+	target := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	conn, err := dialContext(m)(context.Background(), "tcp", target)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	tp := textproto.NewConn(conn)
+	if _, _, err := tp.ReadResponse(220); err != nil {
+		return false, fmt.Errorf("bad greeting: %w", err)
+	}
+
+	id, err := tp.Cmd("EHLO %s", ehloName())
+	if err != nil {
+		return false, fmt.Errorf("failed to send EHLO: %w", err)
+	}
+	tp.StartResponse(id)
+	_, _, err = tp.ReadResponse(250)
+	tp.EndResponse(id)
+	if err != nil {
+		return false, fmt.Errorf("EHLO rejected: %w", err)
+	}
+
+	// Be polite, but the check already passed
+	tp.Cmd("QUIT")
+	return true, nil
+}
+
+// ehloName is the name we introduce ourselves with.
+func ehloName() string {
+	if h, err := os.Hostname(); err == nil && h != "" {
+		return h
+	}
+	return "localhost"
+}
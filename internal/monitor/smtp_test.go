@@ -0,0 +1,97 @@
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSMTP greets every connection with greeting and answers EHLO with
+// ehlo, QUIT with 221. It returns the port and the commands received.
+func fakeSMTP(t *testing.T, greeting, ehlo string) (port int, commands func() []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	var mu sync.Mutex
+	var got []string
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			tp := textproto.NewConn(c)
+			tp.PrintfLine("%s", greeting)
+			for {
+				line, err := tp.ReadLine()
+				if err != nil {
+					break
+				}
+				mu.Lock()
+				got = append(got, line)
+				mu.Unlock()
+				verb, _, _ := strings.Cut(line, " ")
+				if verb == "QUIT" {
+					tp.PrintfLine("221 bye")
+					break
+				}
+				tp.PrintfLine("%s", ehlo)
+			}
+			tp.Close()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), got...)
+	}
+}
+
+func TestSMTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		greeting string
+		ehlo     string
+		wantUp   bool
+		wantErr  string
+	}{
+		{"ESMTP", "220 mail.example.com ESMTP", "250-mail.example.com\r\n250 SIZE 10240000", true, ""},
+		{"plain greeting", "220 ready", "250 mail.example.com", true, ""},
+		{"service unavailable", "554 no SMTP service here", "250 ok", false, "bad greeting: 554"},
+		{"not SMTP at all", "* OK IMAP4rev1 ready", "250 ok", false, "bad greeting"},
+		{"EHLO rejected", "220 mail.example.com", "502 command not implemented", false, "EHLO rejected: 502"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port, commands := fakeSMTP(t, tt.greeting, tt.ehlo)
+			cfg := testConfig(t, fmt.Sprintf(`
+monitors:
+  - {name: mail, type: smtp, host: 127.0.0.1, port: %d}
+`, port))
+			res := RunCheck(cfg.Monitors[0])
+			if res.Status != tt.wantUp || !strings.Contains(res.Error, tt.wantErr) {
+				t.Errorf("up %v (%q), want %v (%q)", res.Status, res.Error, tt.wantUp, tt.wantErr)
+			}
+			if tt.wantUp {
+				waitFor(t, func() bool { return len(commands()) == 2 })
+				got := commands()
+				if got[0] != "EHLO "+ehloName() || got[1] != "QUIT" {
+					t.Errorf("commands %q, want EHLO and QUIT", got)
+				}
+			}
+		})
+	}
+}
+
+func TestSMTPDefaultPort(t *testing.T) {
+	cfg := testConfig(t, "monitors: [{name: mail, type: smtp, host: mail.example.com}]")
+	if got := cfg.Monitors[0].Port; got != 25 {
+		t.Errorf("port %d, want 25", got)
+	}
+}
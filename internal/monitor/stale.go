@@ -0,0 +1,95 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// staleCheckInterval is how often the watchdog looks for stale monitors.
+const staleCheckInterval = 10 * time.Second
+
+// watchStale is a dead man's switch: it alerts when a monitor hasn't
+// produced a result for global.stale_after of its intervals, e.g. because
+// its goroutine hung or a push agent went away. That's "no data", which
+// is different from DOWN: the last known state is left as it was.
+func (e *Engine) watchStale() {
+	started := time.Now()
+	ticker := time.NewTicker(staleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case now := <-ticker.C:
+			e.checkStale(now, started)
+		}
+	}
+}
+
+// checkStale alerts once for every monitor that went stale since the last
+// call. Monitors that were never checked count from started.
+func (e *Engine) checkStale(now, started time.Time) {
+	for _, m := range e.Config().Monitors {
+		interval, ok := e.expectedInterval(m)
+		if !ok {
+			continue
+		}
+		limit := time.Duration(e.Config().Global.StaleAfter) * interval
+
+		e.mu.Lock()
+		st := e.stateFor(m.Name)
+		last := st.lastCheck
+		if !st.checked {
+			last = started
+		}
+		// A check that's scheduled in the future (e.g. postponed by
+		// Retry-After) isn't overdue, however long ago the last one was.
+		overdue := now.Sub(last) > limit && now.After(st.nextCheck)
+		if st.stale || st.paused || !overdue {
+			e.mu.Unlock()
+			continue
+		}
+		st.stale = true
+		e.mu.Unlock()
+
+		age := now.Sub(last).Round(time.Second)
+		log.Printf("Monitor %s: STALE, no check result for %s", m.Name, age)
+		if e.isMuted(m.Name) {
+			log.Printf("Monitor %s: alert not sent, monitor is muted", m.Name)
+			continue
+		}
+
+		result := CheckResult{
+			MonitorName: m.Name,
+			Timestamp:   now,
+			Stale:       true,
+			Error:       fmt.Sprintf("no check result for %s", age),
+			Labels:      m.Labels,
+		}
+		wasUp := e.State(m.Name).IsUp
+		e.publish(Event{Type: EventTransition, Monitor: m, Result: result, WasUp: wasUp})
+	}
+}
+
+// expectedInterval is how often results for a monitor should arrive.
+// Aggregates (no results of their own) and cron monitors (no fixed
+// interval) aren't watched.
+func (e *Engine) expectedInterval(m config.MonitorConfig) (time.Duration, bool) {
+	if m.Type == "aggregate" || m.Cron != "" {
+		return 0, false
+	}
+	interval := config.ParseDuration(e.Config().Global.CheckInterval)
+	if m.Interval != "" {
+		interval = config.ParseDuration(m.Interval)
+	}
+	if m.DownInterval != "" {
+		st := e.State(m.Name)
+		if st.Checked && !st.IsUp {
+			interval = config.ParseDuration(m.DownInterval)
+		}
+	}
+	return interval, interval > 0
+}
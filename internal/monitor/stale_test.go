@@ -0,0 +1,71 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStaleAlert(t *testing.T) {
+	n := &recordingNotifier{}
+	e := NewEngine(testConfig(t, `
+global: {check_interval: 1m, stale_after: 3}
+monitors:
+  - {name: api, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: agent, type: push}
+  - {name: nightly, type: tcp, host: 127.0.0.1, port: 1, cron: "0 3 * * *"}
+  - {name: paused, type: tcp, host: 127.0.0.1, port: 1}
+`), &memStore{}, n)
+	if err := e.Pause("paused"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.CheckNow("api"); err != nil {
+		t.Fatal(err)
+	}
+	last := e.State("api").LastCheck
+	started := last.Add(-30 * time.Second)
+
+	staleAlerts := func() map[string]int {
+		out := make(map[string]int)
+		for _, sent := range n.notifications() {
+			if sent.result.Stale {
+				out[sent.result.MonitorName]++
+			}
+		}
+		return out
+	}
+	steps := []struct {
+		after time.Duration // since api's check
+		want  map[string]int
+	}{
+		{2 * time.Minute, map[string]int{}},
+		// The never-checked push monitor counts from startup, 30s before
+		// api's check
+		{2*time.Minute + 40*time.Second, map[string]int{"agent": 1}},
+		{3*time.Minute + 10*time.Second, map[string]int{"agent": 1, "api": 1}},
+		// Once per stale period
+		{time.Hour, map[string]int{"agent": 1, "api": 1}},
+	}
+	for _, s := range steps {
+		e.checkStale(last.Add(s.after), started)
+		if got := staleAlerts(); len(got) != len(s.want) || got["api"] != s.want["api"] || got["agent"] != s.want["agent"] {
+			t.Fatalf("after %s: stale alerts %v, want %v", s.after, got, s.want)
+		}
+	}
+	if st := e.State("api"); !st.Stale || st.IsUp {
+		t.Errorf("api state %+v, want stale and still down", st)
+	}
+
+	// The next result clears it and follows up on the alert, even
+	// though api is as down as before
+	before := len(n.notifications())
+	if _, err := e.CheckNow("api"); err != nil {
+		t.Fatal(err)
+	}
+	sent := n.notifications()[before:]
+	if len(sent) != 1 || sent[0].result.Stale || sent[0].result.MonitorName != "api" {
+		t.Errorf("after reporting again sent %+v, want one follow-up for api", sent)
+	}
+	if e.State("api").Stale {
+		t.Errorf("api still stale after a result")
+	}
+}
@@ -0,0 +1,196 @@
+package monitor
+
+import "time"
+
+// monitorState is the engine's runtime bookkeeping for one monitor.
+// Guarded by Engine.mu.
+type monitorState struct {
+	checked   bool // at least one check has completed
+	isUp      bool
+	degraded  bool // last result was UP but degraded
+	lastCheck time.Time
+	nextCheck time.Time
+	overruns  int // checks that took longer than their interval
+	latency   *latencyHistogram
+	// The last DOWN alert was swallowed because a dependency was DOWN
+	alertSuppressed bool
+	// Length of the current run of failed / successful checks;
+	// one of them is always zero.
+	consecutiveFailures  int
+	consecutiveSuccesses int
+	// When isUp last flipped. The first check takes the start of the
+	// current run from the store, if it knows, so restarts don't reset it.
+	lastChange time.Time
+	// Healthy checks not stored yet under store_every, and the last of them
+	skipped int
+	pending CheckResult
+	// The previous result sampleForStore saw was UP and not degraded
+	sampledHealthy bool
+	// No result arrived for global.stale_after intervals, see stale.go
+	stale bool
+	// Last SLO evaluation, nil until the first one (see slo.go)
+	slo *SLOStatus
+	// Cached latency_anomaly baseline, see anomaly.go
+	baseline *latencyBaseline
+	// UP <-> DOWN changes within global.flap_window, oldest first, and
+	// whether the flapping alert is on (see flap.go)
+	flaps    []time.Time
+	flapping bool
+	// Set at runtime through the API and persisted, see control.go
+	paused     bool
+	mutedUntil time.Time
+}
+
+// latencyHistogram accumulates check durations for the metrics endpoint.
+type latencyHistogram struct {
+	bounds []float64 // upper bounds in seconds, ascending
+	counts []uint64  // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	v := d.Seconds()
+	for i, b := range h.bounds {
+		if v <= b {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// HistogramSnapshot is a copy of a latency histogram in Prometheus shape:
+// Counts[i] is the cumulative number of observations <= Bounds[i].
+type HistogramSnapshot struct {
+	Bounds []float64
+	Counts []uint64
+	Sum    float64
+	Count  uint64
+}
+
+// MonitorState is a point-in-time snapshot of a monitor's runtime state,
+// safe to hand out to the web layer.
+type MonitorState struct {
+	Name      string
+	Checked   bool
+	IsUp      bool
+	Degraded  bool
+	LastCheck time.Time
+	NextCheck time.Time
+	Overruns  int
+	Latency   *HistogramSnapshot // nil until the first probe
+
+	ConsecutiveFailures  int
+	ConsecutiveSuccesses int
+	// When the monitor went UP or DOWN, zero before the first check
+	LastChange time.Time
+
+	// No result for global.stale_after intervals; IsUp is the last known state
+	Stale bool
+	// Last SLO evaluation, nil without an SLO or before the first one
+	SLO *SLOStatus
+	// UP <-> DOWN changes within global.flap_window, and whether that's
+	// reached flap_threshold
+	Flaps    int
+	Flapping bool
+
+	Paused     bool
+	MutedUntil time.Time // zero when not muted
+}
+
+// StateSinceStore tells when a monitor's current run of UP or DOWN checks
+// began. It's optional like RuntimeStore; without it the last state change
+// of a monitor is only known once the engine has seen one.
+type StateSinceStore interface {
+	StateSince(monitorName string, up bool) (time.Time, error)
+}
+
+// stateFor returns the state for a monitor, creating it on first use.
+// Caller must hold e.mu for writing.
+func (e *Engine) stateFor(name string) *monitorState {
+	st, ok := e.states[name]
+	if !ok {
+		st = &monitorState{}
+		e.states[name] = st
+	}
+	return st
+}
+
+// State returns a snapshot of a monitor's runtime state.
+func (e *Engine) State(name string) MonitorState {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	snap := MonitorState{Name: name}
+	if st, ok := e.states[name]; ok {
+		snap.Checked = st.checked
+		snap.IsUp = st.isUp
+		snap.Degraded = st.degraded
+		snap.LastCheck = st.lastCheck
+		snap.NextCheck = st.nextCheck
+		snap.Overruns = st.overruns
+		snap.ConsecutiveFailures = st.consecutiveFailures
+		snap.ConsecutiveSuccesses = st.consecutiveSuccesses
+		snap.LastChange = st.lastChange
+		snap.Stale = st.stale
+		snap.Flaps = st.flapCount(time.Now(), e.flapWindow())
+		snap.Flapping = st.flapping
+		if st.slo != nil {
+			slo := *st.slo
+			snap.SLO = &slo
+		}
+		snap.Paused = st.paused
+		if time.Now().Before(st.mutedUntil) {
+			snap.MutedUntil = st.mutedUntil
+		}
+		if h := st.latency; h != nil {
+			hs := &HistogramSnapshot{
+				Bounds: append([]float64(nil), h.bounds...),
+				Counts: make([]uint64, len(h.counts)),
+				Sum:    h.sum,
+				Count:  h.count,
+			}
+			var cum uint64
+			for i, c := range h.counts {
+				cum += c
+				hs.Counts[i] = cum
+			}
+			snap.Latency = hs
+		}
+	}
+	return snap
+}
+
+// observeLatency adds a probe's duration to the monitor's histogram.
+func (e *Engine) observeLatency(name string, d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	st := e.stateFor(name)
+	if st.latency == nil {
+		bounds := e.Config().Global.LatencyBuckets
+		st.latency = &latencyHistogram{
+			bounds: bounds,
+			counts: make([]uint64, len(bounds)),
+		}
+	}
+	st.latency.observe(d)
+}
+
+// States returns snapshots for all configured monitors, in config order.
+func (e *Engine) States() []MonitorState {
+	monitors := e.Config().Monitors
+	out := make([]MonitorState, 0, len(monitors))
+	for _, m := range monitors {
+		out = append(out, e.State(m.Name))
+	}
+	return out
+}
+
+func (e *Engine) setNextCheck(name string, next time.Time) {
+	e.mu.Lock()
+	e.stateFor(name).nextCheck = next
+	e.mu.Unlock()
+}
@@ -0,0 +1,131 @@
+package monitor
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConsecutiveCounters(t *testing.T) {
+	var up atomic.Bool
+	e := NewEngine(testConfig(t, `
+monitors:
+  - {name: api, type: http, url: "`+toggleServer(t, &up)+`"}
+`), &memStore{}, nil)
+
+	steps := []struct {
+		up                      bool
+		wantFailures, wantSuccs int
+	}{
+		{true, 0, 1},
+		{true, 0, 2},
+		{false, 1, 0},
+		{false, 2, 0},
+		{false, 3, 0},
+		{true, 0, 1},
+		{false, 1, 0},
+	}
+	for i, step := range steps {
+		up.Store(step.up)
+		if _, err := e.CheckNow("api"); err != nil {
+			t.Fatal(err)
+		}
+		s := e.State("api")
+		if s.ConsecutiveFailures != step.wantFailures || s.ConsecutiveSuccesses != step.wantSuccs {
+			t.Errorf("check %d (up %v): %d failures, %d successes in a row; want %d, %d",
+				i+1, step.up, s.ConsecutiveFailures, s.ConsecutiveSuccesses, step.wantFailures, step.wantSuccs)
+		}
+	}
+}
+
+func TestLastChange(t *testing.T) {
+	var up atomic.Bool
+	e := NewEngine(testConfig(t, `
+monitors:
+  - {name: api, type: http, url: "`+toggleServer(t, &up)+`"}
+`), &memStore{}, nil)
+
+	steps := []struct {
+		up      bool
+		changed bool
+	}{
+		{true, true}, // the first check sets it
+		{true, false},
+		{false, true},
+		{false, false},
+		{false, false},
+		{true, true},
+		{true, false},
+	}
+	var want time.Time
+	for i, step := range steps {
+		up.Store(step.up)
+		res, err := e.CheckNow("api")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if step.changed {
+			want = res.Timestamp
+		}
+		if got := e.State("api").LastChange; !got.Equal(want) {
+			t.Errorf("check %d (up %v): last change %s, want %s", i+1, step.up, got, want)
+		}
+	}
+}
+
+// sinceStore is a memStore that knows when the current run began.
+type sinceStore struct {
+	memStore
+	since time.Time
+	asked []bool
+}
+
+func (s *sinceStore) StateSince(monitorName string, up bool) (time.Time, error) {
+	s.asked = append(s.asked, up)
+	return s.since, nil
+}
+
+// The first check after a restart picks the run start up from the store
+func TestLastChangeFromStore(t *testing.T) {
+	var up atomic.Bool
+	up.Store(true)
+	cfg := testConfig(t, `
+monitors:
+  - {name: api, type: http, url: "`+toggleServer(t, &up)+`"}
+`)
+	tests := []struct {
+		name  string
+		since time.Time
+		// Whether LastChange is since rather than the first check
+		fromStore bool
+	}{
+		{"run from before the restart", time.Now().Add(-72 * time.Hour), true},
+		{"store knows nothing", time.Time{}, false},
+		{"store is ahead of the clock", time.Now().Add(time.Hour), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &sinceStore{since: tt.since}
+			e := NewEngine(cfg, st, nil)
+			res, err := e.CheckNow("api")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := res.Timestamp
+			if tt.fromStore {
+				want = tt.since
+			}
+			if got := e.State("api").LastChange; !got.Equal(want) {
+				t.Errorf("last change %s, want %s", got, want)
+			}
+
+			// Asked once, for the status of the first check
+			if _, err := e.CheckNow("api"); err != nil {
+				t.Fatal(err)
+			}
+			if len(st.asked) != 1 || !st.asked[0] {
+				t.Errorf("StateSince asked for %v, want [true]", st.asked)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// tcpIOTimeout bounds each write and read phase of a TCP check, on top of
+// the connect timeout.
+const tcpIOTimeout = 5 * time.Second
+
+// maxTCPReadBytes caps what a read-mode TCP check reads.
+const maxTCPReadBytes = 4096
+
+// checkTCP connects to the target. Optionally it then sends send_data
+// (e.g. a PROXY protocol header or a protocol greeting) and, in "read"
+// mode, waits for the server to answer, checking expect_data if set.
+// Inverted monitors are checked by checkTCPClosed instead.
+func checkTCP(m config.MonitorConfig) (bool, error) {
+	if m.Inverted {
+		return checkTCPClosed(m)
+	}
+	target := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	conn, err := dialContext(m)(context.Background(), "tcp", target)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if m.SendData != "" {
+		conn.SetWriteDeadline(time.Now().Add(tcpIOTimeout))
+		if _, err := conn.Write([]byte(m.SendData)); err != nil {
+			return false, fmt.Errorf("failed to send data: %w", err)
+		}
+	}
+
+	if m.TCPMode != "read" {
+		return true, nil
+	}
+
+	conn.SetReadDeadline(time.Now().Add(tcpIOTimeout))
+	buf := make([]byte, maxTCPReadBytes)
+	n, err := conn.Read(buf)
+	if n == 0 {
+		if err == nil {
+			err = fmt.Errorf("empty read")
+		}
+		return false, fmt.Errorf("no response: %w", err)
+	}
+	if m.ExpectData != "" && !bytes.Contains(buf[:n], []byte(m.ExpectData)) {
+		return false, fmt.Errorf("response does not contain %q", m.ExpectData)
+	}
+	return true, nil
+}
+
+// checkTCPClosed is the check of an inverted tcp monitor: UP when the
+// connection is refused or times out (the port is closed or filtered),
+// DOWN when it connects. Its result is final, Check doesn't invert it
+// again. A host that can't be resolved is DOWN too, it says nothing about
+// the firewall.
+func checkTCPClosed(m config.MonitorConfig) (bool, error) {
+	target := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	conn, err := dialContext(m)(context.Background(), "tcp", target)
+	if err != nil {
+		if dnsErr := (*net.DNSError)(nil); errors.As(err, &dnsErr) {
+			return false, err
+		}
+		return true, nil
+	}
+	conn.Close()
+	return false, fmt.Errorf("port unexpectedly open: connected to %s", target)
+}
@@ -0,0 +1,82 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// lineServer answers one line per connection, like a Redis-ish protocol:
+// PING gets +PONG, QUIT a hang-up, anything else an error. It returns the
+// address and the lines received so far.
+func lineServer(t *testing.T) (addr *net.TCPAddr, received func() []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	var mu sync.Mutex
+	var lines []string
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			line, _ := bufio.NewReader(c).ReadString('\n')
+			mu.Lock()
+			lines = append(lines, line)
+			mu.Unlock()
+			switch strings.TrimSpace(line) {
+			case "PING":
+				c.Write([]byte("+PONG\r\n"))
+			case "QUIT":
+			default:
+				c.Write([]byte("-ERR unknown command\r\n"))
+			}
+			c.Close()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), lines...)
+	}
+}
+
+func TestTCPSendData(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     string
+		wantUp   bool
+		wantErr  string
+		wantSent string
+	}{
+		{"expected answer", `send_data: "PING\r\n", tcp_mode: read, expect_data: "+PONG"`, true, "", "PING\r\n"},
+		{"any answer", `send_data: "HELLO\r\n", tcp_mode: read`, true, "", "HELLO\r\n"},
+		{"wrong answer", `send_data: "HELLO\r\n", tcp_mode: read, expect_data: "+PONG"`, false, `does not contain "+PONG"`, "HELLO\r\n"},
+		{"no answer", `send_data: "QUIT\r\n", tcp_mode: read`, false, "no response", "QUIT\r\n"},
+		{"connect only", `send_data: "PING\r\n"`, true, "", "PING\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, received := lineServer(t)
+			cfg := testConfig(t, fmt.Sprintf(`
+monitors:
+  - {name: redis, type: tcp, host: 127.0.0.1, port: %d, %s}
+`, addr.Port, tt.opts))
+			res := RunCheck(cfg.Monitors[0])
+			if res.Status != tt.wantUp || !strings.Contains(res.Error, tt.wantErr) {
+				t.Errorf("up %v (%s), want %v (%s)", res.Status, res.Error, tt.wantUp, tt.wantErr)
+			}
+			waitFor(t, func() bool { return len(received()) == 1 })
+			if got := received()[0]; got != tt.wantSent {
+				t.Errorf("server received %q, want %q", got, tt.wantSent)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package notifier
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// cooldowns drops repeated DOWN alerts of a monitor within its cooldown,
+// along with the recoveries of the outages it dropped.
+type cooldowns struct {
+	mu         sync.Mutex
+	lastAlert  map[string]time.Time // last DOWN alert sent, per monitor
+	suppressed map[string]bool      // the current outage's alert was dropped
+}
+
+// allow reports whether an UP/DOWN alert about data.Monitor may go out
+// under the given cooldown, and records it if so.
+func (c *cooldowns) allow(data MessageData, cooldown time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lastAlert == nil {
+		c.lastAlert = make(map[string]time.Time)
+		c.suppressed = make(map[string]bool)
+	}
+
+	name := data.Monitor
+	if data.IsUp {
+		if c.suppressed[name] {
+			delete(c.suppressed, name)
+			log.Printf("Monitor %s: recovery alert not sent, its outage alert was within the cooldown", name)
+			return false
+		}
+		return true
+	}
+
+	if last, ok := c.lastAlert[name]; ok && cooldown > 0 && data.Timestamp.Sub(last) < cooldown {
+		c.suppressed[name] = true
+		log.Printf("Monitor %s: %s alert not sent, last one was %s ago (cooldown %s)",
+			name, data.Status, data.Timestamp.Sub(last).Round(time.Second), cooldown)
+		return false
+	}
+	c.lastAlert[name] = data.Timestamp
+	delete(c.suppressed, name)
+	return true
+}
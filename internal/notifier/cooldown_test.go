@@ -0,0 +1,67 @@
+package notifier
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+func TestCooldownPerMonitor(t *testing.T) {
+	sender := &recordingSender{}
+	s := &Service{
+		Channels:  []Channel{testChannel(t, sender, "{{.Monitor}} {{.Status}} +{{.Error}}")},
+		Cooldown:  10 * time.Minute,
+		Cooldowns: map[string]time.Duration{"db": time.Minute, "cache": 0},
+	}
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	// The same outages for every monitor: down, up, down 2m after the
+	// first, up, down 20m after the first
+	steps := []struct {
+		at time.Duration
+		up bool
+	}{
+		{0, false},
+		{30 * time.Second, true},
+		{2 * time.Minute, false},
+		{3 * time.Minute, true},
+		{20 * time.Minute, false},
+	}
+	for _, step := range steps {
+		for _, name := range []string{"api", "db", "cache"} {
+			s.Notify(monitor.CheckResult{
+				MonitorName: name,
+				Timestamp:   start.Add(step.at),
+				Status:      step.up,
+				Error:       step.at.String(),
+			}, !step.up)
+		}
+	}
+	drain(t, s)
+
+	tests := []struct {
+		monitor string
+		want    []string
+	}{
+		// Global 10m: the second outage and its recovery are dropped
+		{"api", []string{"api DOWN +0s", "api UP +30s", "api DOWN +20m0s"}},
+		// 1m: every alert goes out
+		{"db", []string{"db DOWN +0s", "db UP +30s", "db DOWN +2m0s", "db UP +3m0s", "db DOWN +20m0s"}},
+		// 0 turns the global cooldown off
+		{"cache", []string{"cache DOWN +0s", "cache UP +30s", "cache DOWN +2m0s", "cache UP +3m0s", "cache DOWN +20m0s"}},
+	}
+	sent := sender.messages()
+	for _, tt := range tests {
+		var got []string
+		for _, msg := range sent {
+			if strings.HasPrefix(msg, tt.monitor+" ") {
+				got = append(got, msg)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: sent %q, want %q", tt.monitor, got, tt.want)
+		}
+	}
+}
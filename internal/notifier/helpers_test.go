@@ -0,0 +1,96 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+)
+
+// recordingSender keeps the messages it was asked to send, failing with
+// err when set.
+type recordingSender struct {
+	mu   sync.Mutex
+	sent []string
+	err  error
+}
+
+func (s *recordingSender) Send(message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, message)
+	return s.err
+}
+
+func (s *recordingSender) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.sent...)
+}
+
+// testChannel is a channel sending to s with the given message template,
+// the default one when tmpl is empty.
+func testChannel(t *testing.T, s Sender, tmpl string) Channel {
+	t.Helper()
+	if tmpl == "" {
+		tmpl = DefaultMessageTemplate
+	}
+	return Channel{Type: "test", Sender: s, Tmpl: template.Must(template.New("test").Parse(tmpl))}
+}
+
+// drain closes s, which waits until everything queued is sent.
+func drain(t *testing.T, s *Service) {
+	t.Helper()
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+// notifications parses the notifications section of a config.
+func notifications(t *testing.T, src string) []config.NotificationConfig {
+	t.Helper()
+	cfg, err := config.LoadConfigReader(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("LoadConfigReader: %v", err)
+	}
+	return cfg.Notifications
+}
+
+// apiRequest is a request received by recordingAPI.
+type apiRequest struct {
+	Method string
+	Path   string // escaped, with the query
+	Header http.Header
+	Body   map[string]any
+}
+
+// recordingAPI starts a server standing in for a notification provider's
+// API. It answers every request with status and returns its URL and a
+// function listing the requests so far.
+func recordingAPI(t *testing.T, status int) (url string, requests func() []apiRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var got []apiRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := apiRequest{Method: r.Method, Path: r.URL.RequestURI(), Header: r.Header}
+		if err := json.NewDecoder(r.Body).Decode(&req.Body); err != nil {
+			t.Errorf("%s %s: body isn't JSON: %v", r.Method, r.URL, err)
+		}
+		mu.Lock()
+		got = append(got, req)
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, func() []apiRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]apiRequest(nil), got...)
+	}
+}
@@ -0,0 +1,69 @@
+package notifier
+
+import (
+	"sync"
+	"time"
+)
+
+// channelLimit throttles the sends to one channel: at most max_concurrent
+// at a time, spaced to rate_limit per second with bursts of rate_burst.
+// The rate is a GCRA like the engine's host limiter: tat is when the
+// bucket will be full again.
+type channelLimit struct {
+	sem chan struct{} // nil: no concurrency cap
+
+	mu       sync.Mutex
+	interval time.Duration // time to earn one send, 0: no rate limit
+	burst    int
+	tat      time.Time
+}
+
+// newChannelLimit returns nil when neither limit is set.
+func newChannelLimit(maxConcurrent int, rate float64, burst int) *channelLimit {
+	if maxConcurrent <= 0 && rate <= 0 {
+		return nil
+	}
+	l := &channelLimit{burst: max(burst, 1)}
+	if maxConcurrent > 0 {
+		l.sem = make(chan struct{}, maxConcurrent)
+	}
+	if rate > 0 {
+		l.interval = time.Duration(float64(time.Second) / rate)
+	}
+	return l
+}
+
+// acquire blocks until a send may start and returns the func that ends
+// it. A nil limit doesn't block.
+func (l *channelLimit) acquire() (release func()) {
+	if l == nil {
+		return func() {}
+	}
+	if l.sem != nil {
+		l.sem <- struct{}{}
+	}
+	if wait := l.reserve(time.Now()); wait > 0 {
+		time.Sleep(wait)
+	}
+	return func() {
+		if l.sem != nil {
+			<-l.sem
+		}
+	}
+}
+
+// reserve takes a send slot and returns how long to wait before using it.
+func (l *channelLimit) reserve(now time.Time) time.Duration {
+	if l.interval == 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.tat.Before(now) {
+		l.tat = now
+	}
+	wait := l.tat.Sub(now) - time.Duration(l.burst-1)*l.interval
+	l.tat = l.tat.Add(l.interval)
+	return max(wait, 0)
+}
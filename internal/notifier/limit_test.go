@@ -0,0 +1,108 @@
+package notifier
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+func TestChannelLimitReserve(t *testing.T) {
+	tests := []struct {
+		name  string
+		rate  float64
+		burst int
+		want  []time.Duration // waits of sends all asked for at once
+	}{
+		{"no rate", 0, 0, []time.Duration{0, 0, 0}},
+		{"spaced", 10, 1, []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond}},
+		{"burst", 10, 3, []time.Duration{0, 0, 0, 100 * time.Millisecond, 200 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newChannelLimit(1, tt.rate, tt.burst)
+			now := time.Now()
+			for i, want := range tt.want {
+				if got := l.reserve(now); got != want {
+					t.Errorf("send %d waits %s, want %s", i, got, want)
+				}
+			}
+			// Once the bucket refilled there's no wait again
+			if got := l.reserve(now.Add(time.Minute)); got != 0 {
+				t.Errorf("after a minute: wait %s, want none", got)
+			}
+		})
+	}
+	if newChannelLimit(0, 0, 5) != nil {
+		t.Errorf("limit without rate or concurrency cap isn't nil")
+	}
+}
+
+// timedSender records when each message was sent.
+type timedSender struct {
+	mu    sync.Mutex
+	times []time.Time
+}
+
+func (s *timedSender) Send(message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.times = append(s.times, time.Now())
+	return nil
+}
+
+func (s *timedSender) sent() []time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]time.Time(nil), s.times...)
+}
+
+func TestChannelRateLimitIsPerChannel(t *testing.T) {
+	const alerts = 5
+	limited, free := &timedSender{}, &timedSender{}
+	slow := testChannel(t, limited, "")
+	slow.limit = newChannelLimit(0, 20, 1) // a send every 50ms
+	s := &Service{Channels: []Channel{slow, testChannel(t, free, "")}, Workers: alerts}
+
+	start := time.Now()
+	for i := range alerts {
+		s.Notify(monitor.CheckResult{MonitorName: fmt.Sprintf("m%d", i), Timestamp: start}, true)
+	}
+	drain(t, s)
+
+	got := limited.sent()
+	if len(got) != alerts || len(free.sent()) != alerts {
+		t.Fatalf("sent %d limited and %d free, want %d each", len(got), len(free.sent()), alerts)
+	}
+	if d := got[alerts-1].Sub(start); d < (alerts-1)*45*time.Millisecond {
+		t.Errorf("limited channel sent %d alerts in %s, faster than its rate", alerts, d)
+	}
+	// Done before the limited channel got to its third
+	if last := free.sent()[alerts-1]; last.After(got[2]) {
+		t.Errorf("unlimited channel took %s, held up by the other one", last.Sub(start))
+	}
+}
+
+func TestChannelMaxConcurrent(t *testing.T) {
+	const alerts = 12
+	capped, free := &slowSender{}, &slowSender{}
+	ch := testChannel(t, capped, "")
+	ch.limit = newChannelLimit(2, 0, 0)
+	s := &Service{Channels: []Channel{ch, testChannel(t, free, "")}, Workers: alerts}
+	for i := range alerts {
+		s.Notify(monitor.CheckResult{MonitorName: fmt.Sprintf("m%d", i), Timestamp: time.Now()}, true)
+	}
+	drain(t, s)
+
+	if n := len(capped.messages()); n != alerts {
+		t.Errorf("capped channel sent %d, want %d", n, alerts)
+	}
+	if got := capped.maxInFlight.Load(); got > 2 {
+		t.Errorf("capped channel had %d sends at once, want at most 2", got)
+	}
+	if got := free.maxInFlight.Load(); got <= 2 {
+		t.Errorf("other channel had at most %d sends at once, want more than the cap", got)
+	}
+}
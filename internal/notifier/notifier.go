@@ -2,58 +2,432 @@ package notifier
 
 import (
 	"bytes"
+	"cmp"
+	"context"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"log"
 	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"text/template"
 	"time"
 
 	"github.com/pronzzz/zenmonitor/internal/config"
+	"github.com/pronzzz/zenmonitor/internal/monitor"
 )
 
 type Sender interface {
 	Send(message string) error
 }
 
+// EventSender is implemented by senders that need more than the rendered
+// message, e.g. to open an incident on DOWN and resolve it on UP.
+type EventSender interface {
+	Sender
+	SendEvent(data MessageData, message string) error
+}
+
+// DefaultMessageTemplate is used by channels without a message_template.
+const DefaultMessageTemplate = `{{.Emoji}} Monitor *{{.Monitor}}* is {{.Status}} at {{.Time}}{{if or .SLO .Flapping}} ({{.Error}}){{end}}`
+
+// MessageData is what message templates are rendered with.
+type MessageData struct {
+	Monitor    string
+	Status     string // "UP", "DOWN" or "STALE" (no results, state unknown)
+	Emoji      string
+	IsUp       bool
+	WasUp      bool
+	Latency    time.Duration
+	Error      string
+	StatusCode int
+	Timestamp  time.Time         // in the configured timezone
+	Time       string            // Timestamp in the configured timestamp_format
+	Labels     map[string]string // from the monitor config, e.g. {{.Labels.runbook}}
+	// Set for error budget alerts, Status is then "SLO BURN" or "SLO OK"
+	// and Error describes the burn
+	SLO bool
+	// Set for flapping alerts, Status is then "FLAPPING" or "STABLE" and
+	// Error counts the state changes
+	Flapping bool
+}
+
+// Channel is a configured destination: a sender plus its message template.
+type Channel struct {
+	Type   string
+	Sender Sender
+	Tmpl   *template.Template
+	// Optional per-channel throttle (rate_limit, max_concurrent)
+	limit *channelLimit
+	// Events sent to this channel (see config.NotificationEvents), all
+	// when empty
+	Events []string
+}
+
+// wants reports whether ch is subscribed to event.
+func (ch Channel) wants(event string) bool {
+	return len(ch.Events) == 0 || slices.Contains(ch.Events, event)
+}
+
+// event names what data is about for channel event filters.
+func (data MessageData) event() string {
+	switch {
+	case data.Flapping && data.IsUp:
+		return "flap_ok"
+	case data.Flapping:
+		return "flapping"
+	case data.SLO && data.IsUp:
+		return "slo_ok"
+	case data.SLO:
+		return "slo_burn"
+	case data.Status == "STALE":
+		return "stale"
+	case data.IsUp:
+		return "up"
+	}
+	return "down"
+}
+
+// Record is one notification send attempt, kept for auditing.
+type Record struct {
+	MonitorName string
+	Channel     string
+	Message     string
+	Success     bool
+	Error       string
+	Timestamp   time.Time
+}
+
+// AuditLog persists send attempts (implemented by the store).
+type AuditLog interface {
+	LogNotification(rec Record) error
+}
+
 type Service struct {
-	Senders []Sender
+	Channels []Channel
+	// Optional, every send attempt is recorded here when set
+	Audit AuditLog
+	// Number of send workers per channel, DefaultWorkers when 0. Must be
+	// set before the first Notify.
+	Workers int
+	// Timezone (UTC when nil) and layout (RFC 3339 when empty) of the
+	// times in messages, global.timezone and global.timestamp_format
+	Location   *time.Location
+	TimeFormat string
+	// Minimum time between DOWN alerts of a monitor (0 for none), from
+	// global.notification_cooldown, and per-monitor overrides. Once
+	// alerts go out, change the overrides with SetCooldowns only.
+	Cooldown  time.Duration
+	Cooldowns map[string]time.Duration
+
+	cooldowns  cooldowns
+	cooldownMu sync.RWMutex // guards Cooldowns
+
+	startOnce sync.Once
+	queues    [][]chan sendJob // per channel, one per worker
+	workers   sync.WaitGroup
+	// Held for reading while enqueueing, for writing by Close. enqueue
+	// never blocks while holding it, so Close gets it right away.
+	closeMu sync.RWMutex
+	closed  bool
+	// Alerts that didn't fit in their worker's queue
+	dropped atomic.Uint64
+}
+
+// DefaultWorkers is the number of concurrent sends per channel when
+// Workers isn't set.
+const DefaultWorkers = 4
+
+// queueSize is the per-worker backlog. Alerts that don't fit are dropped:
+// Notify runs on the engine's check goroutines and must not wait for a
+// provider that stopped answering.
+const queueSize = 100
+
+// sendJob is one message for one channel.
+type sendJob struct {
+	ch      Channel
+	chIndex int // position in Service.Channels
+	data    MessageData
+	msg     string
 }
 
 func NewService(cfg []config.NotificationConfig) *Service {
-	var senders []Sender
+	defaultTmpl := template.Must(template.New("default").Parse(DefaultMessageTemplate))
+
+	var channels []Channel
 	for _, n := range cfg {
+		var sender Sender
 		switch n.Type {
 		case "telegram":
 			if n.Token != "" && n.ChatID != "" {
-				senders = append(senders, &TelegramSender{Token: n.Token, ChatID: n.ChatID})
+				sender = &TelegramSender{Token: n.Token, ChatID: n.ChatID}
 			}
 		case "slack":
 			if n.WebhookURL != "" {
-				senders = append(senders, &SlackSender{WebhookURL: n.WebhookURL})
+				sender = &SlackSender{WebhookURL: n.WebhookURL, Blocks: n.SlackBlocks}
+			}
+		case "opsgenie":
+			if n.APIKey != "" {
+				sender = &OpsgenieSender{APIKey: n.APIKey, BaseURL: n.APIURL}
+			}
+		}
+		if sender == nil {
+			continue
+		}
+
+		tmpl := defaultTmpl
+		if n.MessageTemplate != "" {
+			t, err := template.New(n.Type).Parse(n.MessageTemplate)
+			if err != nil {
+				// LoadConfig validates templates, so this is just belt and braces
+				log.Printf("Invalid message_template for %s notifier, using default: %v", n.Type, err)
+			} else {
+				tmpl = t
+			}
+		}
+		channels = append(channels, Channel{
+			Type:   n.Type,
+			Sender: sender,
+			Tmpl:   tmpl,
+			limit:  newChannelLimit(n.MaxConcurrent, n.RateLimit, n.RateBurst),
+			Events: n.Events,
+		})
+	}
+	return &Service{Channels: channels}
+}
+
+func (s *Service) Notify(result monitor.CheckResult, wasUp bool) {
+	data := MessageData{
+		Monitor:    result.MonitorName,
+		Status:     "DOWN",
+		Emoji:      "🔴",
+		IsUp:       result.Status,
+		WasUp:      wasUp,
+		Latency:    result.Latency,
+		Error:      result.Error,
+		StatusCode: result.StatusCode,
+		Timestamp:  result.Timestamp,
+		Labels:     result.Labels,
+	}
+	switch {
+	case result.Stale:
+		data.Status = "STALE"
+		data.Emoji = "⚪"
+	case result.Status:
+		data.Status = "UP"
+		data.Emoji = "🟢"
+	}
+
+	s.cooldownMu.RLock()
+	cooldown, ok := s.Cooldowns[result.MonitorName]
+	s.cooldownMu.RUnlock()
+	if !ok {
+		cooldown = s.Cooldown
+	}
+	if !s.cooldowns.allow(data, cooldown) {
+		return
+	}
+	s.dispatch(data)
+}
+
+// SetCooldowns replaces the per-monitor cooldown overrides, for a config
+// reload. Alerts already in flight keep the old ones.
+func (s *Service) SetCooldowns(overrides map[string]time.Duration) {
+	s.cooldownMu.Lock()
+	s.Cooldowns = overrides
+	s.cooldownMu.Unlock()
+}
+
+// NotifySLO sends an error budget burn alert, or its all-clear.
+func (s *Service) NotifySLO(a monitor.SLOAlert) {
+	data := MessageData{
+		Monitor:   a.MonitorName,
+		Status:    "SLO BURN",
+		Emoji:     "🔥",
+		IsUp:      !a.Firing,
+		WasUp:     a.Firing,
+		Timestamp: a.Timestamp,
+		Labels:    a.Labels,
+		SLO:       true,
+		Error: fmt.Sprintf("error budget of %g%% SLO burning at %.1fx (alerts at %.1fx), %.0f%% of budget left",
+			a.Target, a.BurnRate, a.Threshold, 100*a.BudgetRemaining),
+	}
+	if !a.Firing {
+		data.Status = "SLO OK"
+		data.Emoji = "✅"
+	}
+	s.dispatch(data)
+}
+
+// NotifyFlapping sends a flapping alert, or its all-clear.
+func (s *Service) NotifyFlapping(a monitor.FlapAlert) {
+	data := MessageData{
+		Monitor:   a.MonitorName,
+		Status:    "FLAPPING",
+		Emoji:     "🔁",
+		IsUp:      !a.Flapping,
+		WasUp:     a.Flapping,
+		Timestamp: a.Timestamp,
+		Labels:    a.Labels,
+		Flapping:  true,
+		Error:     fmt.Sprintf("%d state changes in %s (alerts at %d)", a.Changes, a.Window, a.Threshold),
+	}
+	if !a.Flapping {
+		data.Status = "STABLE"
+		data.Emoji = "✅"
+	}
+	s.dispatch(data)
+}
+
+// dispatch renders a message for every channel subscribed to the event
+// and queues it.
+func (s *Service) dispatch(data MessageData) {
+	loc := s.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	data.Timestamp = data.Timestamp.In(loc)
+	data.Time = data.Timestamp.Format(cmp.Or(s.TimeFormat, time.RFC3339))
+
+	event := data.event()
+	for i, ch := range s.Channels {
+		if !ch.wants(event) {
+			continue
+		}
+		msg, err := renderMessage(ch.Tmpl, data)
+		if err != nil {
+			log.Printf("Failed to render %s message for %s: %v", ch.Type, data.Monitor, err)
+			continue
+		}
+		s.enqueue(sendJob{ch: ch, chIndex: i, data: data, msg: msg})
+	}
+}
+
+// enqueue hands a job to the channel's worker owning its monitor.
+// Routing by monitor keeps one monitor's alerts in order (DOWN is never
+// overtaken by UP) while different monitors are sent concurrently. Each
+// channel has its own workers, so a throttled channel doesn't hold up
+// the others.
+func (s *Service) enqueue(j sendJob) {
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+	if s.closed {
+		log.Printf("Dropped %s notification for %s, shutting down", j.ch.Type, j.data.Monitor)
+		return
+	}
+	s.startOnce.Do(s.startWorkers)
+
+	h := fnv.New32a()
+	h.Write([]byte(j.data.Monitor))
+	queues := s.queues[j.chIndex]
+	select {
+	case queues[h.Sum32()%uint32(len(queues))] <- j:
+	default:
+		n := s.dropped.Add(1)
+		log.Printf("Dropped %s notification for %s, queue full (%d dropped so far)", j.ch.Type, j.data.Monitor, n)
+	}
+}
+
+func (s *Service) startWorkers() {
+	n := s.Workers
+	if n <= 0 {
+		n = DefaultWorkers
+	}
+	s.queues = make([][]chan sendJob, len(s.Channels))
+	for c := range s.queues {
+		s.queues[c] = make([]chan sendJob, n)
+		for i := range s.queues[c] {
+			q := make(chan sendJob, queueSize)
+			s.queues[c][i] = q
+			s.workers.Add(1)
+			go func() {
+				defer s.workers.Done()
+				for j := range q {
+					s.send(j.ch, j.data, j.msg)
+				}
+			}()
+		}
+	}
+}
+
+// Close stops accepting notifications and waits until the queued ones
+// are sent, so a shutdown doesn't lose an outage alert that just fired.
+// It gives up when ctx is done, reporting how many were left.
+func (s *Service) Close(ctx context.Context) error {
+	s.closeMu.Lock()
+	if s.closed {
+		s.closeMu.Unlock()
+		return nil
+	}
+	s.closed = true
+	// Nothing can start the workers anymore
+	s.startOnce.Do(func() {})
+	for _, queues := range s.queues {
+		for _, q := range queues {
+			close(q)
+		}
+	}
+	s.closeMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		pending := 0
+		for _, queues := range s.queues {
+			for _, q := range queues {
+				pending += len(q)
 			}
 		}
+		return fmt.Errorf("%d notification(s) still queued: %w", pending, ctx.Err())
 	}
-	return &Service{Senders: senders}
 }
 
-func (s *Service) Notify(monitorName string, isUp bool, wasUp bool) {
-	status := "DOWN"
-	if isUp {
-		status = "UP"
+// send delivers one message and records the attempt.
+func (s *Service) send(ch Channel, data MessageData, msg string) {
+	release := ch.limit.acquire()
+	var err error
+	if es, ok := ch.Sender.(EventSender); ok {
+		err = es.SendEvent(data, msg)
+	} else {
+		err = ch.Sender.Send(msg)
 	}
-	
-	emoji := "🔴"
-	if isUp {
-		emoji = "🟢"
+	release()
+	if err != nil {
+		log.Printf("Failed to send %s notification for %s: %v", ch.Type, data.Monitor, err)
 	}
 
-	msg := fmt.Sprintf("%s Monitor *%s* is %s at %s", emoji, monitorName, status, time.Now().Format(time.RFC1123))
+	if s.Audit == nil {
+		return
+	}
+	rec := Record{
+		MonitorName: data.Monitor,
+		Channel:     ch.Type,
+		Message:     msg,
+		Success:     err == nil,
+		Timestamp:   time.Now(),
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	if err := s.Audit.LogNotification(rec); err != nil {
+		log.Printf("Failed to record %s notification for %s: %v", ch.Type, data.Monitor, err)
+	}
+}
 
-	for _, sender := range s.Senders {
-		go func(snd Sender) {
-			// Ignore errors for now or log them
-			_ = snd.Send(msg)
-		}(sender)
+func renderMessage(tmpl *template.Template, data MessageData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
 	}
+	return buf.String(), nil
 }
 
 // --- Telegram ---
@@ -73,27 +447,36 @@ func (t *TelegramSender) Send(message string) error {
 	return postJSON(url, payload)
 }
 
-// --- Slack ---
+// --- Helper ---
 
-type SlackSender struct {
-	WebhookURL string
-}
+// sendTimeout bounds a single notification request, so a hung provider
+// can't pin a goroutine forever. A variable so tests can shorten it.
+var sendTimeout = 10 * time.Second
 
-func (s *SlackSender) Send(message string) error {
-	payload := map[string]string{
-		"text": message,
-	}
-	return postJSON(s.WebhookURL, payload)
-}
-
-// --- Helper ---
+// httpClient is shared by all senders to reuse connections.
+var httpClient = &http.Client{Timeout: sendTimeout}
 
 func postJSON(url string, v interface{}) error {
+	return postJSONWithHeaders(url, nil, v)
+}
+
+func postJSONWithHeaders(url string, headers map[string]string, v interface{}) error {
 	b, err := json.Marshal(v)
 	if err != nil {
 		return err
 	}
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(b))
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, val := range headers {
+		req.Header.Set(k, val)
+	}
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return err
 	}
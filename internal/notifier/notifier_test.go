@@ -0,0 +1,372 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+func TestNewServiceSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"token": "123:abc\n", "chat_id": "-1001\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewService(notifications(t, `
+notifications:
+  - {type: telegram, token_file: `+filepath.Join(dir, "token")+`, chat_id_file: `+filepath.Join(dir, "chat_id")+`}
+`))
+	if len(s.Channels) != 1 {
+		t.Fatalf("%d channels, want 1", len(s.Channels))
+	}
+	tg, ok := s.Channels[0].Sender.(*TelegramSender)
+	if !ok {
+		t.Fatalf("sender is %T, want *TelegramSender", s.Channels[0].Sender)
+	}
+	if tg.Token != "123:abc" || tg.ChatID != "-1001" {
+		t.Errorf("sender has token %q, chat ID %q; want %q, %q", tg.Token, tg.ChatID, "123:abc", "-1001")
+	}
+}
+
+func TestMessageTemplate(t *testing.T) {
+	down := monitor.CheckResult{
+		MonitorName: "api",
+		Timestamp:   time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		Error:       "connection refused",
+		StatusCode:  502,
+		Latency:     1500 * time.Millisecond,
+		Labels:      map[string]string{"team": "payments", "runbook": "https://wiki/api"},
+	}
+	tests := []struct {
+		name string
+		tmpl string
+		want string
+	}{
+		{
+			name: "default",
+			want: "🔴 Monitor *api* is DOWN at 2026-03-01T12:00:00Z",
+		},
+		{
+			name: "custom",
+			tmpl: `{{.Status}}: {{.Monitor}} ({{.StatusCode}}, {{.Error}}, {{.Latency}}){{if .WasUp}} was up{{end}}`,
+			want: "DOWN: api (502, connection refused, 1.5s) was up",
+		},
+		{
+			name: "labels",
+			tmpl: `{{.Monitor}} [{{.Labels.team}}] runbook: {{.Labels.runbook}}{{with .Labels.severity}} ({{.}}){{end}}`,
+			want: "api [payments] runbook: https://wiki/api",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recordingSender{}
+			s := &Service{Channels: []Channel{testChannel(t, rec, tt.tmpl)}}
+			s.Notify(down, true)
+			drain(t, s)
+			if got := rec.messages(); len(got) != 1 || got[0] != tt.want {
+				t.Errorf("sent %q, want [%q]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewServiceMessageTemplate(t *testing.T) {
+	s := NewService(notifications(t, `
+notifications:
+  - {type: slack, webhook_url: "http://127.0.0.1:1/hook", message_template: "{{.Monitor}} went {{.Status}}"}
+`))
+	msg, err := renderMessage(s.Channels[0].Tmpl, MessageData{Monitor: "api", Status: "DOWN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg != "api went DOWN" {
+		t.Errorf("rendered %q, want %q", msg, "api went DOWN")
+	}
+}
+
+func TestSendTimeout(t *testing.T) {
+	timeout := sendTimeout
+	sendTimeout = 100 * time.Millisecond
+	defer func() { sendTimeout = timeout }()
+
+	hung := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hung
+	}))
+	defer srv.Close()
+	defer close(hung)
+
+	senders := []struct {
+		name   string
+		sender Sender
+	}{
+		{"slack", &SlackSender{WebhookURL: srv.URL}},
+		{"opsgenie", &OpsgenieSender{APIKey: "key", BaseURL: srv.URL}},
+	}
+	for _, tt := range senders {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			err := tt.sender.Send("api is down")
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("err = %v, want a deadline error", err)
+			}
+			if took := time.Since(start); took > 2*time.Second {
+				t.Errorf("Send returned after %s, want about %s", took, sendTimeout)
+			}
+		})
+	}
+}
+
+// slowSender takes a while per message and tracks how many sends overlap.
+type slowSender struct {
+	recordingSender
+	inFlight, maxInFlight atomic.Int32
+}
+
+func (s *slowSender) Send(message string) error {
+	n := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	for {
+		m := s.maxInFlight.Load()
+		if n <= m || s.maxInFlight.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return s.recordingSender.Send(message)
+}
+
+func TestWorkersBounded(t *testing.T) {
+	const monitors, rounds = 12, 2
+	tests := []struct {
+		workers int
+		max     int32
+	}{
+		{1, 1},
+		{3, 3},
+		{0, DefaultWorkers},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.workers), func(t *testing.T) {
+			sender := &slowSender{}
+			s := &Service{Channels: []Channel{testChannel(t, sender, "{{.Monitor}} {{.Status}}")}, Workers: tt.workers}
+			for range rounds {
+				for m := range monitors {
+					name := fmt.Sprintf("m%d", m)
+					s.Notify(monitor.CheckResult{MonitorName: name, Timestamp: time.Now()}, true)
+					s.Notify(monitor.CheckResult{MonitorName: name, Timestamp: time.Now(), Status: true}, false)
+				}
+			}
+			drain(t, s)
+
+			sent := sender.messages()
+			if len(sent) != monitors*rounds*2 {
+				t.Errorf("%d messages sent, want %d", len(sent), monitors*rounds*2)
+			}
+			if got := sender.maxInFlight.Load(); got > tt.max || (tt.max > 1 && got < 2) {
+				t.Errorf("up to %d sends at once, want at most %d and some overlap", got, tt.max)
+			}
+			// Each monitor's alerts keep their order
+			last := make(map[string]string)
+			for _, msg := range sent {
+				name, status, _ := strings.Cut(msg, " ")
+				if status == last[name] {
+					t.Errorf("%s: %s twice in a row, alerts were reordered", name, status)
+				}
+				last[name] = status
+			}
+		})
+	}
+}
+
+func TestNotifyStale(t *testing.T) {
+	rec := &recordingSender{}
+	s := &Service{Channels: []Channel{testChannel(t, rec, "")}}
+	s.Notify(monitor.CheckResult{
+		MonitorName: "agent",
+		Timestamp:   time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		Stale:       true,
+		Error:       "no check result for 3m0s",
+	}, true)
+	drain(t, s)
+	want := "⚪ Monitor *agent* is STALE at 2026-03-01T12:00:00Z"
+	if got := rec.messages(); len(got) != 1 || got[0] != want {
+		t.Errorf("sent %q, want [%q]", got, want)
+	}
+}
+
+// gatedSender holds every send until release is closed.
+type gatedSender struct {
+	recordingSender
+	release chan struct{}
+}
+
+func (s *gatedSender) Send(message string) error {
+	<-s.release
+	return s.recordingSender.Send(message)
+}
+
+func TestCloseDrainsQueue(t *testing.T) {
+	const queued = 20
+	sender := &gatedSender{release: make(chan struct{})}
+	s := &Service{Channels: []Channel{testChannel(t, sender, "{{.Monitor}}")}, Workers: 2}
+	for i := range queued {
+		s.Notify(monitor.CheckResult{MonitorName: fmt.Sprintf("m%d", i), Timestamp: time.Now()}, true)
+	}
+
+	closed := make(chan error)
+	go func() { closed <- s.Close(context.Background()) }()
+	select {
+	case err := <-closed:
+		t.Fatalf("Close returned (%v) with sends still held up", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(sender.release)
+	if err := <-closed; err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if n := len(sender.messages()); n != queued {
+		t.Errorf("%d messages sent before Close returned, want %d", n, queued)
+	}
+
+	// Late ones are dropped, and closing again is fine
+	s.Notify(monitor.CheckResult{MonitorName: "late", Timestamp: time.Now()}, true)
+	if err := s.Close(context.Background()); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	if n := len(sender.messages()); n != queued {
+		t.Errorf("%d messages sent after Close, want %d", n, queued)
+	}
+}
+
+func TestCloseGivesUp(t *testing.T) {
+	sender := &gatedSender{release: make(chan struct{})}
+	defer close(sender.release)
+	s := &Service{Channels: []Channel{testChannel(t, sender, "{{.Monitor}}")}, Workers: 1}
+	for i := range 5 {
+		s.Notify(monitor.CheckResult{MonitorName: fmt.Sprintf("m%d", i), Timestamp: time.Now()}, true)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := s.Close(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "still queued") {
+		t.Errorf("Close = %v, want a deadline error counting what's left", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Close took %s past its deadline", d)
+	}
+}
+
+// A provider that never answers fills its queue. Further alerts are
+// dropped instead of blocking Notify, and Close still keeps to its deadline.
+func TestHungSenderSaturatedQueue(t *testing.T) {
+	sender := &gatedSender{release: make(chan struct{})}
+	defer close(sender.release)
+	s := &Service{Channels: []Channel{testChannel(t, sender, "{{.Monitor}}")}, Workers: 1}
+
+	// One held by the worker, queueSize waiting, the rest don't fit
+	const extra = 5
+	notified := make(chan struct{})
+	go func() {
+		defer close(notified)
+		for range 1 + queueSize + extra {
+			s.Notify(monitor.CheckResult{MonitorName: "api", Timestamp: time.Now()}, true)
+		}
+	}()
+	select {
+	case <-notified:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notify blocked on a full queue")
+	}
+	// The worker may not have taken the first one off the queue yet
+	if got := s.dropped.Load(); got < extra {
+		t.Errorf("%d alerts dropped, want at least %d", got, extra)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := s.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close = %v, want a deadline error", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Close took %s past its deadline", d)
+	}
+}
+
+func TestAlertTimeFormat(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	berlin := time.FixedZone("CET", 3600)
+	tests := []struct {
+		name   string
+		loc    *time.Location
+		format string
+		tmpl   string
+		want   string
+	}{
+		{"defaults to UTC and RFC 3339", nil, "", "", "🔴 Monitor *api* is DOWN at 2026-03-01T12:00:00Z"},
+		{"timezone", berlin, "", "", "🔴 Monitor *api* is DOWN at 2026-03-01T13:00:00+01:00"},
+		{"timezone and layout", berlin, time.RFC1123, "", "🔴 Monitor *api* is DOWN at Sun, 01 Mar 2026 13:00:00 CET"},
+		{"custom layout", nil, "Jan 02 15:04", "", "🔴 Monitor *api* is DOWN at Mar 01 12:00"},
+		{"Timestamp is in the timezone too", berlin, "", "{{.Timestamp.Hour}} {{.Timestamp.Location}}", "13 CET"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recordingSender{}
+			s := &Service{Channels: []Channel{testChannel(t, rec, tt.tmpl)}, Location: tt.loc, TimeFormat: tt.format}
+			s.Notify(monitor.CheckResult{MonitorName: "api", Timestamp: at}, true)
+			drain(t, s)
+			if got := rec.messages(); len(got) != 1 || got[0] != tt.want {
+				t.Errorf("sent %q, want [%q]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChannelEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []string
+		want   []string
+	}{
+		{"everything", nil, []string{"DOWN", "UP", "STALE", "SLO BURN", "SLO OK"}},
+		{"pager", []string{"down"}, []string{"DOWN"}},
+		{"chat", []string{"down", "up", "stale"}, []string{"DOWN", "UP", "STALE"}},
+		{"budget", []string{"slo_burn", "slo_ok"}, []string{"SLO BURN", "SLO OK"}},
+	}
+	s := &Service{Workers: 1}
+	senders := make([]*recordingSender, len(tests))
+	for i, tt := range tests {
+		senders[i] = &recordingSender{}
+		ch := testChannel(t, senders[i], "{{.Status}}")
+		ch.Events = tt.events
+		s.Channels = append(s.Channels, ch)
+	}
+
+	at := time.Now()
+	s.Notify(monitor.CheckResult{MonitorName: "api", Timestamp: at}, true)
+	s.Notify(monitor.CheckResult{MonitorName: "api", Timestamp: at, Status: true}, false)
+	s.Notify(monitor.CheckResult{MonitorName: "api", Timestamp: at, Stale: true}, true)
+	s.NotifySLO(monitor.SLOAlert{MonitorName: "api", Firing: true, Timestamp: at})
+	s.NotifySLO(monitor.SLOAlert{MonitorName: "api", Timestamp: at})
+	drain(t, s)
+
+	for i, tt := range tests {
+		if got := senders[i].messages(); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s channel got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package notifier
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultOpsgenieURL is the Opsgenie API base. EU accounts use https://api.eu.opsgenie.com.
+const DefaultOpsgenieURL = "https://api.opsgenie.com"
+
+// OpsgenieSender opens an alert when a monitor goes DOWN and closes it
+// again on recovery. Alerts are deduplicated by a per-monitor alias.
+type OpsgenieSender struct {
+	APIKey  string
+	BaseURL string
+}
+
+// Send is only used if the sender is called without event context;
+// it creates an alert with the message as-is.
+func (o *OpsgenieSender) Send(message string) error {
+	return o.createAlert("zenmonitor", truncate(message, 130), message)
+}
+
+func (o *OpsgenieSender) SendEvent(data MessageData, message string) error {
+	alias := opsgenieAlias(data.Monitor)
+	switch {
+	case data.SLO:
+		// Separate alert, an SLO recovery mustn't close an outage
+		alias += "-slo"
+	case data.Flapping:
+		alias += "-flapping"
+	}
+	if data.IsUp {
+		return o.closeAlert(alias, message)
+	}
+	summary := fmt.Sprintf("[ZenMonitor] %s is %s", data.Monitor, data.Status)
+	return o.createAlert(alias, truncate(summary, 130), message)
+}
+
+func (o *OpsgenieSender) createAlert(alias, summary, description string) error {
+	payload := map[string]string{
+		"message":     summary,
+		"alias":       alias,
+		"description": description,
+		"source":      "ZenMonitor",
+	}
+	return postJSONWithHeaders(o.baseURL()+"/v2/alerts", o.headers(), payload)
+}
+
+func (o *OpsgenieSender) closeAlert(alias, note string) error {
+	endpoint := fmt.Sprintf("%s/v2/alerts/%s/close?identifierType=alias", o.baseURL(), url.PathEscape(alias))
+	payload := map[string]string{
+		"note":   note,
+		"source": "ZenMonitor",
+	}
+	return postJSONWithHeaders(endpoint, o.headers(), payload)
+}
+
+func (o *OpsgenieSender) headers() map[string]string {
+	return map[string]string{"Authorization": "GenieKey " + o.APIKey}
+}
+
+func (o *OpsgenieSender) baseURL() string {
+	if o.BaseURL == "" {
+		return DefaultOpsgenieURL
+	}
+	return strings.TrimRight(o.BaseURL, "/")
+}
+
+// opsgenieAlias is stable per monitor so a recovery closes the right alert.
+func opsgenieAlias(monitorName string) string {
+	return "zenmonitor-" + monitorName
+}
+
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max])
+}
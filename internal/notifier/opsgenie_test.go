@@ -0,0 +1,93 @@
+package notifier
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestOpsgenieSendEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     MessageData
+		wantPath string
+		wantBody map[string]any
+	}{
+		{
+			name:     "down opens an alert",
+			data:     MessageData{Monitor: "api", Status: "DOWN"},
+			wantPath: "/v2/alerts",
+			wantBody: map[string]any{
+				"message":     "[ZenMonitor] api is DOWN",
+				"alias":       "zenmonitor-api",
+				"description": "api is down",
+				"source":      "ZenMonitor",
+			},
+		},
+		{
+			name:     "up closes it",
+			data:     MessageData{Monitor: "api", Status: "UP", IsUp: true},
+			wantPath: "/v2/alerts/zenmonitor-api/close?identifierType=alias",
+			wantBody: map[string]any{"note": "api is down", "source": "ZenMonitor"},
+		},
+		{
+			name:     "names are escaped",
+			data:     MessageData{Monitor: "eu/api", Status: "UP", IsUp: true},
+			wantPath: "/v2/alerts/zenmonitor-eu%2Fapi/close?identifierType=alias",
+			wantBody: map[string]any{"note": "api is down", "source": "ZenMonitor"},
+		},
+		{
+			name:     "SLO burn has its own alert",
+			data:     MessageData{Monitor: "api", Status: "SLO BURN", SLO: true},
+			wantPath: "/v2/alerts",
+			wantBody: map[string]any{
+				"message":     "[ZenMonitor] api is SLO BURN",
+				"alias":       "zenmonitor-api-slo",
+				"description": "api is down",
+				"source":      "ZenMonitor",
+			},
+		},
+		{
+			name:     "stable closes the flapping alert",
+			data:     MessageData{Monitor: "api", Status: "STABLE", Flapping: true, IsUp: true},
+			wantPath: "/v2/alerts/zenmonitor-api-flapping/close?identifierType=alias",
+			wantBody: map[string]any{"note": "api is down", "source": "ZenMonitor"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, requests := recordingAPI(t, http.StatusAccepted)
+			o := &OpsgenieSender{APIKey: "key", BaseURL: url + "/"}
+			if err := o.SendEvent(tt.data, "api is down"); err != nil {
+				t.Fatal(err)
+			}
+
+			got := requests()
+			if len(got) != 1 {
+				t.Fatalf("%d requests, want 1", len(got))
+			}
+			req := got[0]
+			if req.Method != http.MethodPost || req.Path != tt.wantPath {
+				t.Errorf("%s %s, want POST %s", req.Method, req.Path, tt.wantPath)
+			}
+			if auth := req.Header.Get("Authorization"); auth != "GenieKey key" {
+				t.Errorf("Authorization %q, want %q", auth, "GenieKey key")
+			}
+			if len(req.Body) != len(tt.wantBody) {
+				t.Errorf("body %v, want %v", req.Body, tt.wantBody)
+			}
+			for k, want := range tt.wantBody {
+				if req.Body[k] != want {
+					t.Errorf("%s = %v, want %v", k, req.Body[k], want)
+				}
+			}
+		})
+	}
+}
+
+func TestOpsgenieError(t *testing.T) {
+	url, _ := recordingAPI(t, http.StatusUnauthorized)
+	o := &OpsgenieSender{APIKey: "wrong", BaseURL: url}
+	if err := o.SendEvent(MessageData{Monitor: "api", Status: "DOWN"}, "api is down"); err == nil {
+		t.Error("a 401 from Opsgenie wasn't an error")
+	}
+}
@@ -0,0 +1,82 @@
+package notifier
+
+import (
+	"fmt"
+	"time"
+)
+
+// Attachment colors, matching the dashboard
+const (
+	slackColorUp   = "#2ec4b6"
+	slackColorDown = "#e71d36"
+)
+
+type SlackSender struct {
+	WebhookURL string
+	// Send Block Kit messages rather than plain text
+	Blocks bool
+}
+
+func (s *SlackSender) Send(message string) error {
+	payload := map[string]string{
+		"text": message,
+	}
+	return postJSON(s.WebhookURL, payload)
+}
+
+// SendEvent sends the Block Kit layout when enabled, plain text otherwise.
+func (s *SlackSender) SendEvent(data MessageData, message string) error {
+	if !s.Blocks {
+		return s.Send(message)
+	}
+	return postJSON(s.WebhookURL, slackBlocks(data, message))
+}
+
+// slackBlocks lays out an alert as a header, the rendered message, and a
+// colored attachment with the check details. text stays as the fallback
+// for notifications and clients without Block Kit support.
+func slackBlocks(data MessageData, message string) map[string]interface{} {
+	color := slackColorDown
+	if data.IsUp {
+		color = slackColorUp
+	}
+
+	details := []map[string]string{
+		mrkdwn(fmt.Sprintf("*Latency:* %s", data.Latency.Round(time.Millisecond))),
+	}
+	if data.StatusCode != 0 {
+		details = append(details, mrkdwn(fmt.Sprintf("*Status code:* %d", data.StatusCode)))
+	}
+	if data.Error != "" {
+		details = append(details, mrkdwn(fmt.Sprintf("*Error:* %s", data.Error)))
+	}
+
+	return map[string]interface{}{
+		"text": message,
+		"blocks": []map[string]interface{}{
+			{
+				"type": "header",
+				"text": map[string]string{
+					"type": "plain_text",
+					"text": truncate(fmt.Sprintf("%s %s is %s", data.Emoji, data.Monitor, data.Status), 150),
+				},
+			},
+			{
+				"type": "section",
+				"text": mrkdwn(message),
+			},
+		},
+		"attachments": []map[string]interface{}{
+			{
+				"color": color,
+				"blocks": []map[string]interface{}{
+					{"type": "context", "elements": details},
+				},
+			},
+		},
+	}
+}
+
+func mrkdwn(text string) map[string]string {
+	return map[string]string{"type": "mrkdwn", "text": text}
+}
@@ -0,0 +1,81 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSlackBlocks(t *testing.T) {
+	tests := []struct {
+		name   string
+		blocks bool
+		data   MessageData
+		want   string // JSON body
+	}{
+		{
+			name:   "down",
+			blocks: true,
+			data: MessageData{
+				Monitor: "api", Status: "DOWN", Emoji: "🔴",
+				Latency: 1234567 * time.Microsecond, StatusCode: 502, Error: "bad gateway",
+			},
+			want: `{
+				"text": "api is down",
+				"blocks": [
+					{"type": "header", "text": {"type": "plain_text", "text": "🔴 api is DOWN"}},
+					{"type": "section", "text": {"type": "mrkdwn", "text": "api is down"}}
+				],
+				"attachments": [{"color": "#e71d36", "blocks": [{"type": "context", "elements": [
+					{"type": "mrkdwn", "text": "*Latency:* 1.235s"},
+					{"type": "mrkdwn", "text": "*Status code:* 502"},
+					{"type": "mrkdwn", "text": "*Error:* bad gateway"}
+				]}]}]
+			}`,
+		},
+		{
+			name:   "up",
+			blocks: true,
+			data:   MessageData{Monitor: "api", Status: "UP", Emoji: "🟢", IsUp: true, Latency: 80 * time.Millisecond, StatusCode: 200},
+			want: `{
+				"text": "api is down",
+				"blocks": [
+					{"type": "header", "text": {"type": "plain_text", "text": "🟢 api is UP"}},
+					{"type": "section", "text": {"type": "mrkdwn", "text": "api is down"}}
+				],
+				"attachments": [{"color": "#2ec4b6", "blocks": [{"type": "context", "elements": [
+					{"type": "mrkdwn", "text": "*Latency:* 80ms"},
+					{"type": "mrkdwn", "text": "*Status code:* 200"}
+				]}]}]
+			}`,
+		},
+		{
+			name: "plain text when off",
+			data: MessageData{Monitor: "api", Status: "DOWN", Emoji: "🔴"},
+			want: `{"text": "api is down"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, requests := recordingAPI(t, http.StatusOK)
+			s := &SlackSender{WebhookURL: url, Blocks: tt.blocks}
+			if err := s.SendEvent(tt.data, "api is down"); err != nil {
+				t.Fatal(err)
+			}
+			got := requests()
+			if len(got) != 1 {
+				t.Fatalf("%d requests, want 1", len(got))
+			}
+			var want map[string]any
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got[0].Body, want) {
+				body, _ := json.MarshalIndent(got[0].Body, "", "  ")
+				t.Errorf("sent\n%s\nwant\n%s", body, tt.want)
+			}
+		})
+	}
+}
@@ -3,17 +3,48 @@ package store
 import (
 	"database/sql"
 	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/pronzzz/zenmonitor/internal/monitor"
+	"github.com/pronzzz/zenmonitor/internal/notifier"
 	_ "modernc.org/sqlite" // Import generic driver
 )
 
+// SQLiteStore persists checks and notifications. All timestamps are
+// written and read back in UTC: DATETIME columns are compared as text, so
+// mixing offsets would break range queries. Rows from before that are
+// converted when the database is opened (see migrate). Converting to a
+// display timezone is the web layer's job.
 type SQLiteStore struct {
-	db *sql.DB
+	db        *sql.DB
+	stopCh    chan struct{}
+	closeOnce sync.Once
+	// Writes hold the read side, Vacuum the write side: VACUUM needs the
+	// database to itself and concurrent writers would fail with SQLITE_BUSY.
+	writeMu sync.RWMutex
+	wal     bool
+}
+
+// Options tune how the database file is managed.
+type Options struct {
+	// SQLite journal mode: WAL (default), DELETE, TRUNCATE or PERSIST.
+	// WAL misbehaves on some network filesystems.
+	JournalMode string
+	// How often to checkpoint and truncate the WAL file (WAL mode only).
+	// 0 leaves it to SQLite's automatic checkpoints.
+	CheckpointInterval time.Duration
 }
 
 func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	return NewSQLiteStoreWithOptions(path, Options{})
+}
+
+func NewSQLiteStoreWithOptions(path string, opts Options) (*SQLiteStore, error) {
 	// Open database (creates file if not exists)
 	db, err := sql.Open("sqlite", path)
 	if err != nil {
@@ -24,20 +55,92 @@ func NewSQLiteStore(path string) (*SQLiteStore, error) {
 		return nil, err
 	}
 
-	// Enable WAL mode for concurrency
-	_, err = db.Exec("PRAGMA journal_mode=WAL;")
-	if err != nil {
-		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
+	// WAL by default for concurrency
+	mode := strings.ToUpper(opts.JournalMode)
+	switch mode {
+	case "":
+		mode = "WAL"
+	case "WAL", "DELETE", "TRUNCATE", "PERSIST":
+	default:
+		return nil, fmt.Errorf("unsupported journal mode %q", opts.JournalMode)
+	}
+	var applied string
+	if err := db.QueryRow("PRAGMA journal_mode=" + mode).Scan(&applied); err != nil {
+		return nil, fmt.Errorf("failed to set journal mode %s: %w", mode, err)
+	}
+	if !strings.EqualFold(applied, mode) {
+		return nil, fmt.Errorf("failed to set journal mode %s, database is in %s mode", mode, applied)
 	}
 
-	s := &SQLiteStore{db: db}
+	s := &SQLiteStore{db: db, stopCh: make(chan struct{}), wal: mode == "WAL"}
 	if err := s.initSchema(); err != nil {
 		return nil, err
 	}
+	if err := s.migrate(); err != nil {
+		return nil, err
+	}
+
+	if mode == "WAL" && opts.CheckpointInterval > 0 {
+		go s.checkpointLoop(opts.CheckpointInterval)
+	}
 
 	return s, nil
 }
 
+// checkpointLoop periodically folds the WAL back into the database and
+// truncates it, so write-heavy setups don't grow it without bound.
+func (s *SQLiteStore) checkpointLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			if err := s.Checkpoint(); err != nil {
+				log.Printf("WAL checkpoint failed: %v", err)
+			}
+		}
+	}
+}
+
+// Checkpoint runs a truncating WAL checkpoint.
+func (s *SQLiteStore) Checkpoint() error {
+	_, err := s.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	return err
+}
+
+// Vacuum rebuilds the database file to give the pages freed by deletes
+// back to the filesystem; SQLite only reuses them otherwise. Writes wait
+// until it's done. It does nothing when there's no free page to reclaim.
+func (s *SQLiteStore) Vacuum() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	var free int
+	if err := s.db.QueryRow("PRAGMA freelist_count").Scan(&free); err != nil {
+		return err
+	}
+	if free == 0 {
+		return nil
+	}
+	if _, err := s.db.Exec("VACUUM"); err != nil {
+		return err
+	}
+	// In WAL mode the rebuilt pages sit in the WAL until checkpointed
+	if s.wal {
+		return s.Checkpoint()
+	}
+	return nil
+}
+
+// JournalMode reports the database's current journal mode, lower case.
+func (s *SQLiteStore) JournalMode() (string, error) {
+	var mode string
+	err := s.db.QueryRow("PRAGMA journal_mode").Scan(&mode)
+	return strings.ToLower(mode), err
+}
+
 func (s *SQLiteStore) initSchema() error {
 	query := `
 	CREATE TABLE IF NOT EXISTS checks (
@@ -49,87 +152,650 @@ func (s *SQLiteStore) initSchema() error {
 		error_msg TEXT
 	);
 	CREATE INDEX IF NOT EXISTS idx_monitor_time ON checks(monitor_name, timestamp);
+
+	CREATE TABLE IF NOT EXISTS notifications (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		monitor_name TEXT NOT NULL,
+		channel TEXT NOT NULL,
+		message TEXT NOT NULL,
+		success INTEGER NOT NULL, -- 1=sent, 0=failed
+		error_msg TEXT NOT NULL DEFAULT '',
+		timestamp DATETIME NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_notifications_time ON notifications(timestamp);
+
+	CREATE TABLE IF NOT EXISTS monitor_runtime (
+		monitor_name TEXT PRIMARY KEY,
+		paused INTEGER NOT NULL DEFAULT 0,
+		muted_until DATETIME, -- NULL when not muted
+		updated_at DATETIME NOT NULL
+	);
 	`
-	_, err := s.db.Exec(query)
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
+
+	// Columns added after the initial schema. CREATE TABLE IF NOT EXISTS
+	// won't touch existing databases, so add them if they're missing.
+	// backfill, if set, computes the new column for existing rows.
+	columns := []struct{ name, decl, backfill string }{
+		{"status_code", "INTEGER NOT NULL DEFAULT 0", ""},
+		{"response_size", "INTEGER NOT NULL DEFAULT 0", ""},
+		{"dns_ms", "INTEGER NOT NULL DEFAULT 0", ""},
+		{"connect_ms", "INTEGER NOT NULL DEFAULT 0", ""},
+		{"tls_ms", "INTEGER NOT NULL DEFAULT 0", ""},
+		{"ttfb_ms", "INTEGER NOT NULL DEFAULT 0", ""},
+		{"degraded", "INTEGER NOT NULL DEFAULT 0", ""},
+		// Durations in microseconds; the _ms columns rounded fast local
+		// checks down to 0. latency_ms is still written for old readers.
+		{"latency_us", "INTEGER NOT NULL DEFAULT 0", "latency_ms * 1000"},
+		{"dns_us", "INTEGER NOT NULL DEFAULT 0", "dns_ms * 1000"},
+		{"connect_us", "INTEGER NOT NULL DEFAULT 0", "connect_ms * 1000"},
+		{"tls_us", "INTEGER NOT NULL DEFAULT 0", "tls_ms * 1000"},
+		{"ttfb_us", "INTEGER NOT NULL DEFAULT 0", "ttfb_ms * 1000"},
+		// Checks a row stands for when store_every skips steady ones
+		{"checks", "INTEGER NOT NULL DEFAULT 1", ""},
+	}
+	for _, c := range columns {
+		added, err := s.addColumnIfMissing("checks", c.name, c.decl)
+		if err != nil {
+			return err
+		}
+		if added && c.backfill != "" {
+			if _, err := s.db.Exec(fmt.Sprintf("UPDATE checks SET %s = %s", c.name, c.backfill)); err != nil {
+				return fmt.Errorf("failed to backfill checks.%s: %w", c.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// schemaVersion is kept in PRAGMA user_version. Migrations that rewrite
+// existing rows run once, when a database with an older version is opened.
+const schemaVersion = 1
+
+func (s *SQLiteStore) migrate() error {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version >= schemaVersion {
+		return nil
+	}
+	if version < 1 {
+		for _, table := range []string{"checks", "notifications"} {
+			n, err := s.timestampsToUTC(table)
+			if err != nil {
+				return fmt.Errorf("failed to convert %s timestamps to UTC: %w", table, err)
+			}
+			if n > 0 {
+				log.Printf("Converted %d %s timestamps from local time to UTC", n, table)
+			}
+		}
+	}
+	_, err := s.db.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion))
 	return err
 }
 
+// timestampsToUTC rewrites the timestamps of rows written before they
+// were stored in UTC, which kept the server's offset. Since DATETIME is
+// compared as text, those would sort wrong against UTC ones in range
+// queries. Rows already in UTC, whichever way the driver spells it, are
+// left alone. It returns how many rows it converted.
+func (s *SQLiteStore) timestampsToUTC(table string) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`SELECT id, timestamp FROM %s
+		WHERE id > ? AND timestamp NOT LIKE '%% UTC' AND timestamp NOT LIKE '%%+00:00' AND timestamp NOT LIKE '%%Z'
+		ORDER BY id LIMIT 1000`, table)
+	update, err := tx.Prepare(fmt.Sprintf("UPDATE %s SET timestamp = ? WHERE id = ?", table))
+	if err != nil {
+		return 0, err
+	}
+	defer update.Close()
+
+	type row struct {
+		id int64
+		ts time.Time
+	}
+	converted := 0
+	var last int64
+	for {
+		rows, err := tx.Query(query, last)
+		if err != nil {
+			return 0, err
+		}
+		var batch []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.id, &r.ts); err != nil {
+				rows.Close()
+				return 0, err
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for _, r := range batch {
+			if _, err := update.Exec(r.ts.UTC(), r.id); err != nil {
+				return 0, err
+			}
+		}
+		converted += len(batch)
+		last = batch[len(batch)-1].id
+	}
+	return converted, tx.Commit()
+}
+
+// addColumnIfMissing adds a column, reporting whether it had to.
+func (s *SQLiteStore) addColumnIfMissing(table, column, decl string) (bool, error) {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return false, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	rows.Close()
+
+	_, err = s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, decl))
+	if err != nil {
+		return false, fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	return true, nil
+}
+
 func (s *SQLiteStore) LogCheck(result monitor.CheckResult) error {
+	s.writeMu.RLock()
+	defer s.writeMu.RUnlock()
+
 	query := `
-	INSERT INTO checks (monitor_name, timestamp, status, latency_ms, error_msg)
-	VALUES (?, ?, ?, ?, ?)
+	INSERT INTO checks (monitor_name, timestamp, status, latency_ms, latency_us, error_msg, status_code, response_size,
+		dns_us, connect_us, tls_us, ttfb_us, degraded, checks)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	statusInt := 0
 	if result.Status {
 		statusInt = 1
 	}
-	
-	_, err := s.db.Exec(query, 
-		result.MonitorName, 
-		result.Timestamp, 
-		statusInt, 
-		result.Latency.Milliseconds(), 
+	degraded := 0
+	if result.Degraded {
+		degraded = 1
+	}
+
+	_, err := s.db.Exec(query,
+		result.MonitorName,
+		result.Timestamp.UTC(),
+		statusInt,
+		result.Latency.Milliseconds(),
+		result.Latency.Microseconds(),
 		result.Error,
+		result.StatusCode,
+		result.BodySize,
+		result.Timings.DNS.Microseconds(),
+		result.Timings.Connect.Microseconds(),
+		result.Timings.TLS.Microseconds(),
+		result.Timings.TTFB.Microseconds(),
+		degraded,
+		max(result.Checks, 1),
 	)
 	return err
 }
 
+// checkColumns is the column list scanChecks expects, in order.
+const checkColumns = `timestamp, status, latency_us, error_msg, status_code, response_size,
+	dns_us, connect_us, tls_us, ttfb_us, degraded, checks`
+
+// GetHistory returns the last `limit` checks for a monitor, oldest first.
+// The inner query grabs the newest rows via idx_monitor_time, the outer one
+// flips them back into chronological order so the dot matrix reads left-to-right.
 func (s *SQLiteStore) GetHistory(monitorName string, limit int) ([]monitor.CheckResult, error) {
 	query := `
-	SELECT timestamp, status, latency_ms, error_msg 
-	FROM checks 
-	WHERE monitor_name = ? 
-	ORDER BY timestamp DESC 
-	LIMIT ?
+	SELECT ` + checkColumns + ` FROM (
+		SELECT ` + checkColumns + `
+		FROM checks
+		WHERE monitor_name = ?
+		ORDER BY timestamp DESC
+		LIMIT ?
+	)
+	ORDER BY timestamp ASC
 	`
-	
+
 	rows, err := s.db.Query(query, monitorName, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var results []monitor.CheckResult
+	return scanChecks(rows, monitorName)
+}
+
+// HistoryCursor marks where a page of history ends. Checks can share a
+// timestamp, so the row id breaks ties. The zero cursor starts at the
+// newest check.
+type HistoryCursor struct {
+	Timestamp time.Time
+	ID        int64
+}
+
+// IsZero reports whether c is the zero cursor.
+func (c HistoryCursor) IsZero() bool {
+	return c.Timestamp.IsZero() && c.ID == 0
+}
+
+// GetHistoryPaged returns up to limit checks of a monitor older than
+// before, oldest first like GetHistory, and the cursor for the page before
+// this one: zero once there are no older checks. It walks idx_monitor_time
+// backwards, so deep pages cost no more than the first.
+func (s *SQLiteStore) GetHistoryPaged(monitorName string, limit int, before HistoryCursor) ([]monitor.CheckResult, HistoryCursor, error) {
+	query := `SELECT id, ` + checkColumns + ` FROM checks WHERE monitor_name = ?`
+	args := []interface{}{monitorName}
+	if !before.IsZero() {
+		ts := before.Timestamp.UTC()
+		query += ` AND (timestamp < ? OR (timestamp = ? AND id < ?))`
+		args = append(args, ts, ts, before.ID)
+	}
+	// One extra row tells whether there's another page
+	query += ` ORDER BY timestamp DESC, id DESC LIMIT ?`
+	args = append(args, limit+1)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, HistoryCursor{}, err
+	}
+	defer rows.Close()
+
+	var page []monitor.CheckResult
+	var next HistoryCursor
+	var lastID int64
+	for rows.Next() {
+		var id int64
+		r, err := scanCheck(rows, monitorName, &id)
+		if err != nil {
+			return nil, HistoryCursor{}, err
+		}
+		if len(page) == limit {
+			// The last row of this page is where the next one starts
+			next = HistoryCursor{Timestamp: page[len(page)-1].Timestamp, ID: lastID}
+			break
+		}
+		page = append(page, r)
+		lastID = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, HistoryCursor{}, err
+	}
+	slices.Reverse(page)
+	return page, next, nil
+}
+
+// CountChecks returns how many checks of a monitor since the given time
+// succeeded, out of how many. Rows sampled by store_every count for the
+// checks they stand for.
+func (s *SQLiteStore) CountChecks(monitorName string, since time.Time) (up, total int, err error) {
+	err = s.db.QueryRow(`
+	SELECT COALESCE(SUM(CASE WHEN status = 1 THEN checks ELSE 0 END), 0), COALESCE(SUM(checks), 0)
+	FROM checks
+	WHERE monitor_name = ? AND timestamp >= ?
+	`, monitorName, since.UTC()).Scan(&up, &total)
+	return up, total, err
+}
+
+// LatencyPercentile returns the given percentile (nearest rank) of the
+// latency of a monitor's UP checks since the given time, and how many
+// checks that is. Zero latency when there are none.
+func (s *SQLiteStore) LatencyPercentile(monitorName string, since time.Time, percentile float64) (time.Duration, int, error) {
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM checks WHERE monitor_name = ? AND status = 1 AND timestamp >= ?`,
+		monitorName, since.UTC()).Scan(&n)
+	if err != nil || n == 0 {
+		return 0, n, err
+	}
+	rank := int(math.Ceil(percentile / 100 * float64(n)))
+	var us int64
+	err = s.db.QueryRow(`
+	SELECT latency_us FROM checks
+	WHERE monitor_name = ? AND status = 1 AND timestamp >= ?
+	ORDER BY latency_us ASC LIMIT 1 OFFSET ?
+	`, monitorName, since.UTC(), max(rank-1, 0)).Scan(&us)
+	return time.Duration(us) * time.Microsecond, n, err
+}
+
+// StateSince returns when a monitor's current run of UP (or DOWN) checks
+// began: the first stored check with that status after the last one
+// without it. Zero if there is no such check.
+func (s *SQLiteStore) StateSince(monitorName string, up bool) (time.Time, error) {
+	status := 0
+	if up {
+		status = 1
+	}
+	var since time.Time
+	err := s.db.QueryRow(`
+	SELECT timestamp FROM checks
+	WHERE monitor_name = ? AND status = ? AND timestamp > COALESCE(
+		(SELECT MAX(timestamp) FROM checks WHERE monitor_name = ? AND status != ?), 0)
+	ORDER BY timestamp ASC LIMIT 1
+	`, monitorName, status, monitorName, status).Scan(&since)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return since.UTC(), err
+}
+
+// Incident is an outage of a monitor: a run of DOWN checks from the first
+// one (Start) to the UP check that ended it (End, zero while ongoing).
+type Incident struct {
+	MonitorName string
+	Start       time.Time
+	End         time.Time
+}
+
+// Ongoing reports whether the monitor is still down.
+func (i Incident) Ongoing() bool {
+	return i.End.IsZero()
+}
+
+// Duration is how long the incident lasted, or has lasted until now.
+func (i Incident) Duration(now time.Time) time.Duration {
+	if i.Ongoing() {
+		return now.Sub(i.Start)
+	}
+	return i.End.Sub(i.Start)
+}
+
+// GetIncidents returns a monitor's incidents that ended after since,
+// oldest first. Failures and transitions are always stored (store_every
+// only skips repeated UPs), so the bounds are exact except for an incident
+// already under way at since, which starts there.
+func (s *SQLiteStore) GetIncidents(monitorName string, since time.Time) ([]Incident, error) {
+	var incidents []Incident
+	open := false
+	err := s.scanStatuses(monitorName, since, func(ts time.Time, up bool) {
+		switch {
+		case !up && !open:
+			incidents = append(incidents, Incident{MonitorName: monitorName, Start: ts})
+			open = true
+		case up && open:
+			incidents[len(incidents)-1].End = ts
+			open = false
+		}
+	})
+	return incidents, err
+}
+
+// Transitions returns when a monitor went UP or DOWN since the given
+// time, oldest first. The first check after since is only a reference,
+// not a transition.
+func (s *SQLiteStore) Transitions(monitorName string, since time.Time) ([]time.Time, error) {
+	var changes []time.Time
+	var last *bool
+	err := s.scanStatuses(monitorName, since, func(ts time.Time, up bool) {
+		if last != nil && *last != up {
+			changes = append(changes, ts)
+		}
+		last = &up
+	})
+	return changes, err
+}
+
+// scanStatuses calls fn with the timestamp and status of a monitor's
+// checks since the given time, in order.
+func (s *SQLiteStore) scanStatuses(monitorName string, since time.Time, fn func(ts time.Time, up bool)) error {
+	rows, err := s.db.Query(`
+	SELECT timestamp, status FROM checks
+	WHERE monitor_name = ? AND timestamp >= ?
+	ORDER BY timestamp ASC, id ASC
+	`, monitorName, since.UTC())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
 	for rows.Next() {
-		var r monitor.CheckResult
-		var statusInt int
-		var latMs int64
 		var ts time.Time
-		r.MonitorName = monitorName
+		var status int
+		if err := rows.Scan(&ts, &status); err != nil {
+			return err
+		}
+		fn(ts.UTC(), status == 1)
+	}
+	return rows.Err()
+}
 
-		if err := rows.Scan(&ts, &statusInt, &latMs, &r.Error); err != nil {
+// GetErrors returns the most recent failed checks for a monitor, newest first.
+func (s *SQLiteStore) GetErrors(monitorName string, limit int) ([]monitor.CheckResult, error) {
+	query := `
+	SELECT ` + checkColumns + `
+	FROM checks
+	WHERE monitor_name = ? AND status = 0
+	ORDER BY timestamp DESC
+	LIMIT ?
+	`
+
+	rows, err := s.db.Query(query, monitorName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanChecks(rows, monitorName)
+}
+
+// scanChecks reads rows selected with checkColumns.
+func scanChecks(rows *sql.Rows, monitorName string) ([]monitor.CheckResult, error) {
+	var results []monitor.CheckResult
+	for rows.Next() {
+		r, err := scanCheck(rows, monitorName)
+		if err != nil {
 			return nil, err
 		}
-		r.Status = (statusInt == 1)
-		r.Latency = time.Duration(latMs) * time.Millisecond
-		r.Timestamp = ts
 		results = append(results, r)
 	}
-	
-	// Since we order by DESC (newest first), we might want to reverse if the UI expects time order, 
-	// but UI usually handles that or we can order ASC in a subquery. 
-	// The PRD says "grid of green/red dots representing the last 90 days". 
-	// Typical dot matrix is left-to-right (oldest to newest).
-	// So we should reverse this list or query ASC with offset.
-	// But getting last N usually implies DESC limit.
-	// Let's reverse them in code for convenience.
-	// Or just ORDER BY timestamp DESC LIMIT ? -> then reverse.
-	
-	// Reversing in place
-	for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
-		results[i], results[j] = results[j], results[i]
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
 	return results, nil
 }
 
-func (s *SQLiteStore) PruneOldData(days int) error {
-	cutoff := time.Now().AddDate(0, 0, -days)
+// scanCheck scans one row of checkColumns, preceded by the columns for
+// extra if any.
+func scanCheck(rows *sql.Rows, monitorName string, extra ...any) (monitor.CheckResult, error) {
+	var r monitor.CheckResult
+	var statusInt, degraded int
+	var latUs, dnsUs, connectUs, tlsUs, ttfbUs int64
+	var ts time.Time
+	r.MonitorName = monitorName
+
+	dest := append(extra, &ts, &statusInt, &latUs, &r.Error, &r.StatusCode, &r.BodySize,
+		&dnsUs, &connectUs, &tlsUs, &ttfbUs, &degraded, &r.Checks)
+	if err := rows.Scan(dest...); err != nil {
+		return r, err
+	}
+	r.Status = (statusInt == 1)
+	r.Degraded = degraded == 1
+	r.Latency = time.Duration(latUs) * time.Microsecond
+	r.Timings = monitor.HTTPTimings{
+		DNS:     time.Duration(dnsUs) * time.Microsecond,
+		Connect: time.Duration(connectUs) * time.Microsecond,
+		TLS:     time.Duration(tlsUs) * time.Microsecond,
+		TTFB:    time.Duration(ttfbUs) * time.Microsecond,
+	}
+	r.Timestamp = ts.UTC()
+	return r, nil
+}
+
+// PruneOldData deletes history older than days. Monitors listed in
+// overrides keep their checks for their own number of days instead.
+func (s *SQLiteStore) PruneOldData(days int, overrides map[string]int) error {
+	s.writeMu.RLock()
+	defer s.writeMu.RUnlock()
+
+	cutoff := time.Now().UTC().AddDate(0, 0, -days)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Everything without an override against the global cutoff...
 	query := `DELETE FROM checks WHERE timestamp < ?`
-	_, err := s.db.Exec(query, cutoff)
+	args := []interface{}{cutoff}
+	if len(overrides) > 0 {
+		query += ` AND monitor_name NOT IN (?` + strings.Repeat(`, ?`, len(overrides)-1) + `)`
+		for name := range overrides {
+			args = append(args, name)
+		}
+	}
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+
+	// ...then each override against its own
+	for name, d := range overrides {
+		monitorCutoff := time.Now().UTC().AddDate(0, 0, -d)
+		if _, err := tx.Exec(`DELETE FROM checks WHERE monitor_name = ? AND timestamp < ?`, name, monitorCutoff); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM notifications WHERE timestamp < ?`, cutoff); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// LogNotification records a notification send attempt (notifier.AuditLog).
+func (s *SQLiteStore) LogNotification(rec notifier.Record) error {
+	s.writeMu.RLock()
+	defer s.writeMu.RUnlock()
+
+	query := `
+	INSERT INTO notifications (monitor_name, channel, message, success, error_msg, timestamp)
+	VALUES (?, ?, ?, ?, ?, ?)
+	`
+	success := 0
+	if rec.Success {
+		success = 1
+	}
+	_, err := s.db.Exec(query, rec.MonitorName, rec.Channel, rec.Message, success, rec.Error, rec.Timestamp.UTC())
 	return err
 }
 
+// GetNotifications returns the most recent notification attempts, newest first.
+func (s *SQLiteStore) GetNotifications(limit int) ([]notifier.Record, error) {
+	query := `
+	SELECT monitor_name, channel, message, success, error_msg, timestamp
+	FROM notifications
+	ORDER BY timestamp DESC
+	LIMIT ?
+	`
+	rows, err := s.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []notifier.Record
+	for rows.Next() {
+		var rec notifier.Record
+		var success int
+		if err := rows.Scan(&rec.MonitorName, &rec.Channel, &rec.Message, &success, &rec.Error, &rec.Timestamp); err != nil {
+			return nil, err
+		}
+		rec.Success = success == 1
+		rec.Timestamp = rec.Timestamp.UTC()
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+// SaveRuntimeState stores a monitor's paused/muted state (monitor.RuntimeStore).
+func (s *SQLiteStore) SaveRuntimeState(rs monitor.RuntimeState) error {
+	s.writeMu.RLock()
+	defer s.writeMu.RUnlock()
+
+	query := `
+	INSERT INTO monitor_runtime (monitor_name, paused, muted_until, updated_at)
+	VALUES (?, ?, ?, ?)
+	ON CONFLICT(monitor_name) DO UPDATE SET
+		paused = excluded.paused,
+		muted_until = excluded.muted_until,
+		updated_at = excluded.updated_at
+	`
+	paused := 0
+	if rs.Paused {
+		paused = 1
+	}
+	var mutedUntil sql.NullTime
+	if !rs.MutedUntil.IsZero() {
+		mutedUntil = sql.NullTime{Time: rs.MutedUntil.UTC(), Valid: true}
+	}
+	_, err := s.db.Exec(query, rs.MonitorName, paused, mutedUntil, time.Now().UTC())
+	return err
+}
+
+// LoadRuntimeStates returns the saved paused/muted state of every monitor
+// that has one (monitor.RuntimeStore).
+func (s *SQLiteStore) LoadRuntimeStates() ([]monitor.RuntimeState, error) {
+	rows, err := s.db.Query(`SELECT monitor_name, paused, muted_until FROM monitor_runtime`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var states []monitor.RuntimeState
+	for rows.Next() {
+		var rs monitor.RuntimeState
+		var paused int
+		var mutedUntil sql.NullTime
+		if err := rows.Scan(&rs.MonitorName, &paused, &mutedUntil); err != nil {
+			return nil, err
+		}
+		rs.Paused = paused == 1
+		if mutedUntil.Valid {
+			rs.MutedUntil = mutedUntil.Time.UTC()
+		}
+		states = append(states, rs)
+	}
+	return states, rows.Err()
+}
+
+// Close stops the background maintenance and closes the database. Calls
+// after the first do nothing.
 func (s *SQLiteStore) Close() error {
-	return s.db.Close()
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.stopCh)
+		err = s.db.Close()
+	})
+	return err
 }
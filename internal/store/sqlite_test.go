@@ -0,0 +1,835 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+	"github.com/pronzzz/zenmonitor/internal/notifier"
+)
+
+func berlin(t *testing.T) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func openStore(t *testing.T, path string) *SQLiteStore {
+	t.Helper()
+	s, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestTimestampsRoundTripAcrossDST(t *testing.T) {
+	loc := berlin(t)
+	tests := []struct {
+		name string
+		// Local wall times a check was made at, in order
+		times []time.Time
+	}{
+		{"spring forward", []time.Time{
+			time.Date(2026, 3, 29, 1, 59, 0, 0, loc),
+			time.Date(2026, 3, 29, 3, 0, 0, 0, loc), // a minute later
+			time.Date(2026, 3, 29, 3, 1, 0, 0, loc),
+		}},
+		{"fall back", []time.Time{
+			time.Date(2026, 10, 25, 2, 30, 0, 0, loc),                       // CEST, first 02:30
+			time.Date(2026, 10, 25, 2, 30, 0, 0, loc).Add(30 * time.Minute), // CET 02:00
+			time.Date(2026, 10, 25, 2, 30, 0, 0, loc).Add(time.Hour),        // CET, second 02:30
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := openStore(t, filepath.Join(t.TempDir(), "zen.db"))
+			defer s.Close()
+			for _, ts := range tt.times {
+				if err := s.LogCheck(monitor.CheckResult{MonitorName: "api", Timestamp: ts, Status: true}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			history, err := s.GetHistory("api", 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(history) != len(tt.times) {
+				t.Fatalf("%d results, want %d", len(history), len(tt.times))
+			}
+			for i, r := range history {
+				if !r.Timestamp.Equal(tt.times[i]) || r.Timestamp.Location() != time.UTC {
+					t.Errorf("result %d at %s, want %s in UTC", i, r.Timestamp, tt.times[i].UTC())
+				}
+			}
+			_, total, err := s.CountChecks("api", tt.times[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != 2 {
+				t.Errorf("%d checks since the second one, want 2", total)
+			}
+		})
+	}
+}
+
+// Rows from before timestamps were stored in UTC carry the server's
+// offset; opening the database converts them, once.
+func TestMigrateLocalTimestamps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zen.db")
+	s := openStore(t, path)
+	utc := time.Date(2026, 6, 1, 10, 30, 0, 0, time.UTC)
+	rows := []time.Time{
+		utc.In(berlin(t)),    // written by an old version: 12:30+02:00
+		utc.Add(time.Minute), // 10:31 in UTC, sorts before 12:30 as text
+	}
+	for _, ts := range rows {
+		if _, err := s.db.Exec(`INSERT INTO checks (monitor_name, timestamp, status, latency_ms, error_msg) VALUES ('api', ?, 1, 0, '')`, ts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.db.Exec("PRAGMA user_version = 0"); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s = openStore(t, path)
+	defer s.Close()
+	history, err := s.GetHistory("api", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || !history[0].Timestamp.Equal(rows[0]) || !history[1].Timestamp.Equal(rows[1]) {
+		t.Fatalf("history %v, want the converted row first", history)
+	}
+	_, total, err := s.CountChecks("api", utc.Add(30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 {
+		t.Errorf("%d checks after the converted row, want 1", total)
+	}
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version != schemaVersion {
+		t.Errorf("user_version %d after opening, want %d", version, schemaVersion)
+	}
+}
+
+// newStore opens an empty database in a temp dir, closed with the test.
+func newStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	s := openStore(t, filepath.Join(t.TempDir(), "zen.db"))
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// logChecks stores results, failing the test on error.
+func logChecks(t *testing.T, s *SQLiteStore, results ...monitor.CheckResult) {
+	t.Helper()
+	for _, r := range results {
+		if err := s.LogCheck(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// minutely returns n checks of a monitor a minute apart, starting at start.
+// Every fourth one is DOWN.
+func minutely(name string, start time.Time, n int) []monitor.CheckResult {
+	out := make([]monitor.CheckResult, n)
+	for i := range out {
+		out[i] = monitor.CheckResult{
+			MonitorName: name,
+			Timestamp:   start.Add(time.Duration(i) * time.Minute),
+			Status:      i%4 != 3,
+			Latency:     time.Duration(i+1) * time.Millisecond,
+		}
+	}
+	return out
+}
+
+func TestGetHistory(t *testing.T) {
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		stored int
+		limit  int
+		want   int
+	}{
+		{"more than the limit", 10, 4, 4},
+		{"fewer than the limit", 3, 90, 3},
+		{"none", 0, 90, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStore(t)
+			checks := minutely("api", start, tt.stored)
+			// Stored newest first, and with another monitor's in between
+			for i := len(checks) - 1; i >= 0; i-- {
+				logChecks(t, s, checks[i], monitor.CheckResult{MonitorName: "other", Timestamp: checks[i].Timestamp})
+			}
+
+			history, err := s.GetHistory("api", tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(history) != tt.want {
+				t.Fatalf("%d results, want %d", len(history), tt.want)
+			}
+			// The newest ones, oldest first
+			want := checks[len(checks)-tt.want:]
+			for i, r := range history {
+				if r.MonitorName != "api" || !r.Timestamp.Equal(want[i].Timestamp) || r.Status != want[i].Status {
+					t.Errorf("result %d: %s at %s (up %v), want %s (up %v)", i, r.MonitorName, r.Timestamp, r.Status, want[i].Timestamp, want[i].Status)
+				}
+			}
+			if tt.want > 0 && !history[len(history)-1].Timestamp.Equal(checks[len(checks)-1].Timestamp) {
+				t.Errorf("last result at %s, want the newest check", history[len(history)-1].Timestamp)
+			}
+		})
+	}
+}
+
+// senderFunc adapts a function to notifier.Sender.
+type senderFunc func(message string) error
+
+func (f senderFunc) Send(message string) error { return f(message) }
+
+func TestNotificationAudit(t *testing.T) {
+	tmpl := template.Must(template.New("test").Parse("{{.Monitor}} is {{.Status}}"))
+	tests := []struct {
+		name    string
+		sendErr error
+		wantErr string
+	}{
+		{"sent", nil, ""},
+		{"failed", errors.New("api request failed with status: 500"), "api request failed with status: 500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStore(t)
+			svc := &notifier.Service{
+				Channels: []notifier.Channel{{
+					Type:   "slack",
+					Sender: senderFunc(func(string) error { return tt.sendErr }),
+					Tmpl:   tmpl,
+				}},
+				Audit: s,
+			}
+			before := time.Now().UTC().Truncate(time.Second)
+			svc.Notify(monitor.CheckResult{MonitorName: "api", Timestamp: time.Now()}, true)
+			if err := svc.Close(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			recs, err := s.GetNotifications(10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(recs) != 1 {
+				t.Fatalf("%d audit rows, want 1", len(recs))
+			}
+			rec := recs[0]
+			if rec.MonitorName != "api" || rec.Channel != "slack" || rec.Message != "api is DOWN" {
+				t.Errorf("row for %s on %s with %q, want api on slack with %q", rec.MonitorName, rec.Channel, rec.Message, "api is DOWN")
+			}
+			if rec.Success != (tt.sendErr == nil) || rec.Error != tt.wantErr {
+				t.Errorf("success %v, error %q; want %v, %q", rec.Success, rec.Error, tt.sendErr == nil, tt.wantErr)
+			}
+			if rec.Timestamp.Before(before) || rec.Timestamp.After(time.Now()) {
+				t.Errorf("timestamp %s, want the time of the send", rec.Timestamp)
+			}
+		})
+	}
+}
+
+func TestRuntimeStateSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zen.db")
+	cfgPath := filepath.Join(t.TempDir(), "monitors.yaml")
+	if err := os.WriteFile(cfgPath, []byte(`
+global: {check_interval: 1h}
+monitors:
+  - {name: paused, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: muted, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: expired, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: active, type: tcp, host: 127.0.0.1, port: 1}
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutedUntil := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	// First run: an operator pauses and mutes monitors
+	s := openStore(t, path)
+	e := monitor.NewEngine(cfg, s, nil)
+	for _, err := range []error{
+		e.Pause("paused"),
+		e.Mute("muted", mutedUntil),
+		e.Mute("expired", time.Now().Add(50*time.Millisecond)),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	// Second run on the same database
+	s = openStore(t, path)
+	defer s.Close()
+	e = monitor.NewEngine(cfg, s, nil)
+	e.Start()
+	// Wait for the checks on start, which skip the paused monitor
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		if h, _ := s.GetHistory("active", 10); len(h) > 0 || time.Now().After(deadline) {
+			break
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	e.Stop(context.Background())
+
+	tests := []struct {
+		name       string
+		paused     bool
+		mutedUntil time.Time
+		checks     int
+	}{
+		{"paused", true, time.Time{}, 0},
+		{"muted", false, mutedUntil, 1},
+		{"expired", false, time.Time{}, 1},
+		{"active", false, time.Time{}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := e.State(tt.name)
+			if st.Paused != tt.paused || !st.MutedUntil.Equal(tt.mutedUntil) {
+				t.Errorf("paused %v, muted until %s; want %v, %s", st.Paused, st.MutedUntil, tt.paused, tt.mutedUntil)
+			}
+			history, err := s.GetHistory(tt.name, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(history) != tt.checks {
+				t.Errorf("%d checks after the restart, want %d", len(history), tt.checks)
+			}
+		})
+	}
+}
+
+func TestPruneOldDataPerMonitor(t *testing.T) {
+	s := newStore(t)
+	now := time.Now().UTC()
+	for _, name := range []string{"default", "short", "long"} {
+		// One check a day, from 10 days ago to an hour ago
+		for day := 10; day >= 0; day-- {
+			logChecks(t, s, monitor.CheckResult{
+				MonitorName: name,
+				Timestamp:   now.AddDate(0, 0, -day).Add(-time.Hour),
+				Status:      true,
+			})
+		}
+	}
+
+	if err := s.PruneOldData(7, map[string]int{"short": 2, "long": 30}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		days int // retention
+		want int // checks left
+	}{
+		{"default", 7, 7},
+		{"short", 2, 2},
+		{"long", 30, 11},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history, err := s.GetHistory(tt.name, 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(history) != tt.want {
+				t.Errorf("%d checks left, want %d", len(history), tt.want)
+			}
+			cutoff := now.AddDate(0, 0, -tt.days)
+			for _, r := range history {
+				if r.Timestamp.Before(cutoff) {
+					t.Errorf("check at %s kept, older than %d days", r.Timestamp, tt.days)
+				}
+			}
+		})
+	}
+}
+
+func TestJournalMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    string
+		wantErr bool
+	}{
+		{"", "wal", false},
+		{"wal", "wal", false},
+		{"DELETE", "delete", false},
+		{"truncate", "truncate", false},
+		{"persist", "persist", false},
+		{"memory", "", true},
+		{"off; DROP TABLE checks", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			s, err := NewSQLiteStoreWithOptions(filepath.Join(t.TempDir(), "zen.db"), Options{JournalMode: tt.mode})
+			if tt.wantErr {
+				if err == nil {
+					s.Close()
+					t.Fatal("unsupported mode was accepted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			if got, err := s.JournalMode(); err != nil || got != tt.want {
+				t.Errorf("JournalMode() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckpointTruncatesWAL(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		wantWAL  bool // WAL still has data after a while
+	}{
+		{"automatic checkpoints only", 0, true},
+		{"periodic checkpoints", 20 * time.Millisecond, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "zen.db")
+			s, err := NewSQLiteStoreWithOptions(path, Options{CheckpointInterval: tt.interval})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			walSize := func() int64 {
+				fi, err := os.Stat(path + "-wal")
+				if err != nil {
+					return 0
+				}
+				return fi.Size()
+			}
+			logChecks(t, s, minutely("api", time.Now().Add(-time.Hour), 50)...)
+			deadline := time.Now().Add(200 * time.Millisecond)
+			for walSize() > 0 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if got := walSize() > 0; got != tt.wantWAL {
+				t.Errorf("WAL is %d bytes, want data in it %v", walSize(), tt.wantWAL)
+			}
+		})
+	}
+}
+
+func TestSubMillisecondLatency(t *testing.T) {
+	s := newStore(t)
+	at := time.Now().UTC().Truncate(time.Second)
+	tests := []struct {
+		latency time.Duration
+		timings monitor.HTTPTimings
+		want    time.Duration // microsecond precision
+	}{
+		{250 * time.Microsecond, monitor.HTTPTimings{}, 250 * time.Microsecond},
+		{1 * time.Microsecond, monitor.HTTPTimings{}, 1 * time.Microsecond},
+		{1500*time.Microsecond + 999*time.Nanosecond, monitor.HTTPTimings{}, 1500 * time.Microsecond},
+		{
+			800 * time.Microsecond,
+			monitor.HTTPTimings{DNS: 12 * time.Microsecond, Connect: 90 * time.Microsecond, TLS: 0, TTFB: 640 * time.Microsecond},
+			800 * time.Microsecond,
+		},
+	}
+	for i, tt := range tests {
+		logChecks(t, s, monitor.CheckResult{
+			MonitorName: "local",
+			Timestamp:   at.Add(time.Duration(i) * time.Second),
+			Status:      true,
+			Latency:     tt.latency,
+			Timings:     tt.timings,
+		})
+	}
+	history, err := s.GetHistory("local", len(tests))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != len(tests) {
+		t.Fatalf("%d checks, want %d", len(history), len(tests))
+	}
+	for i, tt := range tests {
+		if got := history[i].Latency; got != tt.want {
+			t.Errorf("check %d: latency %s, want %s", i, got, tt.want)
+		}
+		if got := history[i].Timings; got != tt.timings {
+			t.Errorf("check %d: timings %+v, want %+v", i, got, tt.timings)
+		}
+	}
+}
+
+// Databases from before latency_us keep their millisecond latencies.
+func TestBackfillMicrosecondColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zen.db")
+	s := openStore(t, path)
+	_, err := s.db.Exec(`
+	DROP TABLE checks;
+	CREATE TABLE checks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		monitor_name TEXT NOT NULL,
+		timestamp DATETIME NOT NULL,
+		status INTEGER NOT NULL,
+		latency_ms INTEGER NOT NULL,
+		error_msg TEXT
+	);
+	INSERT INTO checks (monitor_name, timestamp, status, latency_ms, error_msg)
+	VALUES ('api', '2026-06-01 10:30:00+00:00', 1, 42, '');`)
+	s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s = openStore(t, path)
+	defer s.Close()
+	history, err := s.GetHistory("api", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Latency != 42*time.Millisecond {
+		t.Errorf("history %+v, want one check of 42ms", history)
+	}
+}
+
+// Rows sampled by store_every count for the checks they stand for.
+func TestCountChecksSampled(t *testing.T) {
+	s := newStore(t)
+	start := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	rows := []monitor.CheckResult{
+		{Status: true},             // a plain row counts once
+		{Status: true, Checks: 10}, // stands for 10
+		{Status: false},
+		{Status: true, Checks: 1},
+	}
+	for i := range rows {
+		rows[i].MonitorName = "api"
+		rows[i].Timestamp = start.Add(time.Duration(i) * time.Minute)
+	}
+	logChecks(t, s, rows...)
+
+	up, total, err := s.CountChecks("api", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if up != 12 || total != 13 {
+		t.Errorf("CountChecks = %d up of %d, want 12 of 13", up, total)
+	}
+	history, err := s.GetHistory("api", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []int{1, 10, 1, 1} {
+		if history[i].Checks != want {
+			t.Errorf("row %d stands for %d checks, want %d", i, history[i].Checks, want)
+		}
+	}
+}
+
+func TestVacuumShrinksFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zen.db")
+	s := openStore(t, path)
+	defer s.Close()
+
+	fileSize := func() int64 {
+		t.Helper()
+		if err := s.Checkpoint(); err != nil {
+			t.Fatal(err)
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fi.Size()
+	}
+
+	// Nothing to reclaim yet
+	if err := s.Vacuum(); err != nil {
+		t.Fatalf("Vacuum of a fresh database: %v", err)
+	}
+
+	// A year old checks with bulky errors, and a day of recent ones
+	old := minutely("api", time.Now().AddDate(-1, 0, 0), 3000)
+	for i := range old {
+		old[i].Error = strings.Repeat("connection refused ", 25)
+	}
+	logChecks(t, s, old...)
+	logChecks(t, s, minutely("api", time.Now().Add(-24*time.Hour), 100)...)
+	full := fileSize()
+
+	if err := s.PruneOldData(30, nil); err != nil {
+		t.Fatal(err)
+	}
+	pruned := fileSize()
+	if pruned < full {
+		t.Fatalf("file shrank from %d to %d bytes before vacuuming", full, pruned)
+	}
+	if err := s.Vacuum(); err != nil {
+		t.Fatal(err)
+	}
+	if vacuumed := fileSize(); vacuumed > full/4 {
+		t.Errorf("file is %d bytes after vacuuming, was %d", vacuumed, full)
+	}
+	if history, err := s.GetHistory("api", 1000); err != nil || len(history) != 100 {
+		t.Errorf("after vacuuming: %d checks, %v; want the 100 recent ones", len(history), err)
+	}
+}
+
+func TestStateSince(t *testing.T) {
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	at := func(min int) time.Time { return start.Add(time.Duration(min) * time.Minute) }
+	tests := []struct {
+		name   string
+		status []bool // a check a minute
+		up     bool
+		want   time.Time
+	}{
+		{"no checks", nil, true, time.Time{}},
+		{"always up", []bool{true, true, true}, true, at(0)},
+		{"up since the last failure", []bool{true, false, false, true, true}, true, at(3)},
+		{"down run", []bool{true, true, false, false}, false, at(2)},
+		{"asked for the other status", []bool{true, false, true}, false, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStore(t)
+			for i, up := range tt.status {
+				logChecks(t, s, monitor.CheckResult{MonitorName: "api", Timestamp: at(i), Status: up})
+			}
+			// Another monitor's checks don't count
+			logChecks(t, s, monitor.CheckResult{MonitorName: "db", Timestamp: at(10), Status: !tt.up})
+
+			got, err := s.StateSince("api", tt.up)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("StateSince(api, %v) = %s, want %s", tt.up, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatencyPercentile(t *testing.T) {
+	s := newStore(t)
+	start := time.Now().UTC().Add(-time.Hour).Truncate(time.Minute)
+	// minutely: 1ms, 2ms, ... with every fourth check DOWN
+	logChecks(t, s, minutely("api", start, 40)...)
+	logChecks(t, s, monitor.CheckResult{MonitorName: "db", Timestamp: start, Status: true, Latency: time.Hour})
+
+	tests := []struct {
+		since       time.Time
+		percentile  float64
+		want        time.Duration
+		wantSamples int
+	}{
+		{start, 50, 19 * time.Millisecond, 30},
+		{start, 95, 38 * time.Millisecond, 30},
+		{start, 100, 39 * time.Millisecond, 30},
+		{start, 0, 1 * time.Millisecond, 30},
+		{start.Add(20 * time.Minute), 50, 30 * time.Millisecond, 15},
+		{start.Add(time.Hour), 95, 0, 0},
+	}
+	for _, tt := range tests {
+		got, n, err := s.LatencyPercentile("api", tt.since, tt.percentile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want || n != tt.wantSamples {
+			t.Errorf("p%g since +%s: %s of %d checks, want %s of %d",
+				tt.percentile, tt.since.Sub(start), got, n, tt.want, tt.wantSamples)
+		}
+	}
+}
+
+func TestGetHistoryPaged(t *testing.T) {
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	s := newStore(t)
+	checks := minutely("api", start, 20)
+	// Checks sharing a timestamp are told apart by their row ID
+	for i := range 5 {
+		checks = append(checks, monitor.CheckResult{
+			MonitorName: "api",
+			Timestamp:   start.Add(time.Duration(i*3) * time.Minute),
+			Status:      true,
+			Latency:     time.Duration(100+i) * time.Millisecond,
+		})
+	}
+	logChecks(t, s, checks...)
+	logChecks(t, s, minutely("db", start, 10)...)
+	// Oldest first, ties in the order they were stored
+	all := slices.Clone(checks)
+	slices.SortStableFunc(all, func(a, b monitor.CheckResult) int { return a.Timestamp.Compare(b.Timestamp) })
+
+	for _, limit := range []int{1, 7, 25, 100} {
+		var pages [][]monitor.CheckResult
+		var before HistoryCursor
+		for {
+			page, next, err := s.GetHistoryPaged("api", limit, before)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page) > limit {
+				t.Fatalf("limit %d: page of %d", limit, len(page))
+			}
+			pages = append(pages, page)
+			if next.IsZero() {
+				break
+			}
+			if len(pages) == 1 {
+				// Newer checks don't shift the pages being walked
+				logChecks(t, s, monitor.CheckResult{MonitorName: "api", Timestamp: start.Add(time.Hour), Status: true})
+			}
+			before = next
+		}
+		if _, err := s.db.Exec(`DELETE FROM checks WHERE timestamp > ?`, start.Add(30*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+
+		// Newest page first, each oldest first: reversed they make up
+		// the whole history without gaps or overlaps
+		var got []monitor.CheckResult
+		for i := len(pages) - 1; i >= 0; i-- {
+			got = append(got, pages[i]...)
+		}
+		if len(got) != len(all) {
+			t.Fatalf("limit %d: %d checks over %d pages, want %d", limit, len(got), len(pages), len(all))
+		}
+		for i := range all {
+			if !got[i].Timestamp.Equal(all[i].Timestamp) || got[i].Latency != all[i].Latency {
+				t.Errorf("limit %d: check %d is %s/%s, want %s/%s", limit, i,
+					got[i].Timestamp, got[i].Latency, all[i].Timestamp, all[i].Latency)
+			}
+		}
+		if want := (len(all) + limit - 1) / limit; len(pages) != want {
+			t.Errorf("limit %d: %d pages, want %d", limit, len(pages), want)
+		}
+	}
+}
+
+func TestGetIncidents(t *testing.T) {
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	at := func(min int) time.Time { return start.Add(time.Duration(min) * time.Minute) }
+	s := newStore(t)
+	for i, up := range []bool{true, false, false, true, true, false, true, false, false} {
+		logChecks(t, s, monitor.CheckResult{MonitorName: "api", Timestamp: at(i), Status: up})
+	}
+	logChecks(t, s, monitor.CheckResult{MonitorName: "db", Timestamp: at(2), Status: false})
+
+	tests := []struct {
+		since time.Time
+		want  []Incident
+	}{
+		{start, []Incident{
+			{"api", at(1), at(3)},
+			{"api", at(5), at(6)},
+			{"api", at(7), time.Time{}},
+		}},
+		// An incident under way at since starts there
+		{at(2), []Incident{
+			{"api", at(2), at(3)},
+			{"api", at(5), at(6)},
+			{"api", at(7), time.Time{}},
+		}},
+		{at(9), nil},
+	}
+	for _, tt := range tests {
+		got, err := s.GetIncidents("api", tt.since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.EqualFunc(got, tt.want, func(a, b Incident) bool {
+			return a.MonitorName == b.MonitorName && a.Start.Equal(b.Start) && a.End.Equal(b.End)
+		}) {
+			t.Errorf("since +%s: %+v, want %+v", tt.since.Sub(start), got, tt.want)
+		}
+	}
+
+	inc := Incident{Start: at(1), End: at(3)}
+	if d := inc.Duration(at(100)); d != 2*time.Minute {
+		t.Errorf("Duration of a resolved incident = %s, want 2m", d)
+	}
+	inc.End = time.Time{}
+	if d := inc.Duration(at(100)); !inc.Ongoing() || d != 99*time.Minute {
+		t.Errorf("Duration of an ongoing incident = %s, want 99m", d)
+	}
+}
+
+func TestTransitions(t *testing.T) {
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	at := func(min int) time.Time { return start.Add(time.Duration(min) * time.Minute) }
+	s := newStore(t)
+	for i, up := range []bool{true, false, false, true, true, false, true} {
+		logChecks(t, s, monitor.CheckResult{MonitorName: "api", Timestamp: at(i), Status: up})
+	}
+
+	tests := []struct {
+		since time.Time
+		want  []time.Time
+	}{
+		{start, []time.Time{at(1), at(3), at(5), at(6)}},
+		// The first check after since is where counting starts
+		{at(1), []time.Time{at(3), at(5), at(6)}},
+		{at(6), nil},
+	}
+	for _, tt := range tests {
+		got, err := s.Transitions("api", tt.since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.EqualFunc(got, tt.want, time.Time.Equal) {
+			t.Errorf("since +%s: %v, want %v", tt.since.Sub(start), got, tt.want)
+		}
+	}
+}
+
+// A deferred Close after an explicit one must not panic on the closed
+// stop channel.
+func TestCloseTwice(t *testing.T) {
+	s, err := NewSQLiteStoreWithOptions(filepath.Join(t.TempDir(), "zen.db"), Options{CheckpointInterval: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
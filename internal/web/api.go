@@ -0,0 +1,625 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+	"github.com/pronzzz/zenmonitor/internal/store"
+	"gopkg.in/yaml.v3"
+)
+
+// CheckJSON is the API representation of a single check result.
+type CheckJSON struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Up         bool      `json:"up"`
+	Status     string    `json:"status"`     // "up", "degraded" or "down"
+	LatencyMs  float64   `json:"latency_ms"` // microsecond precision
+	Error      string    `json:"error,omitempty"`
+	StatusCode int       `json:"status_code,omitempty"`
+	BodySize   int64     `json:"body_size,omitempty"`
+	// Checks this entry stands for when store_every skipped identical ones
+	Checks int `json:"checks,omitempty"`
+	// HTTP phase breakdown, omitted for non-HTTP checks
+	Timings *TimingsJSON `json:"timings,omitempty"`
+}
+
+// TimingsJSON is the per-phase latency breakdown of an HTTP check.
+type TimingsJSON struct {
+	DNSMs     float64 `json:"dns_ms"`
+	ConnectMs float64 `json:"connect_ms"`
+	TLSMs     float64 `json:"tls_ms"`
+	TTFBMs    float64 `json:"ttfb_ms"`
+}
+
+func toCheckJSON(r monitor.CheckResult) CheckJSON {
+	c := CheckJSON{
+		Timestamp:  r.Timestamp,
+		Up:         r.Status,
+		Status:     statusText(r.Status, r.Degraded),
+		LatencyMs:  millis(r.Latency),
+		Error:      r.Error,
+		StatusCode: r.StatusCode,
+		BodySize:   r.BodySize,
+	}
+	if r.Checks > 1 {
+		c.Checks = r.Checks
+	}
+	if r.Timings != (monitor.HTTPTimings{}) {
+		c.Timings = &TimingsJSON{
+			DNSMs:     millis(r.Timings.DNS),
+			ConnectMs: millis(r.Timings.Connect),
+			TLSMs:     millis(r.Timings.TLS),
+			TTFBMs:    millis(r.Timings.TTFB),
+		}
+	}
+	return c
+}
+
+// millis converts to fractional milliseconds, keeping microseconds.
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func toChecksJSON(results []monitor.CheckResult) []CheckJSON {
+	out := make([]CheckJSON, 0, len(results))
+	for _, r := range results {
+		out = append(out, toCheckJSON(r))
+	}
+	return out
+}
+
+// MonitorStatusJSON is the API representation of a monitor's current state.
+type MonitorStatusJSON struct {
+	Name        string     `json:"name"`
+	Up          bool       `json:"up"`
+	Status      string     `json:"status"` // "up", "degraded", "down" or "unknown" before the first check
+	LastChecked *time.Time `json:"last_checked,omitempty"`
+	NextCheck   *time.Time `json:"next_check,omitempty"`
+
+	ConsecutiveFailures  int `json:"consecutive_failures"`
+	ConsecutiveSuccesses int `json:"consecutive_successes"`
+	// When the monitor went up or down
+	LastChange *time.Time `json:"last_change,omitempty"`
+
+	// No result for global.stale_after intervals; up/status are the last known state
+	Stale bool `json:"stale"`
+	// UP <-> DOWN changes within global.flap_window
+	Flaps    int  `json:"flaps"`
+	Flapping bool `json:"flapping"`
+
+	Paused     bool       `json:"paused"`
+	MutedUntil *time.Time `json:"muted_until,omitempty"`
+}
+
+// handleStatus serves GET /api/status with the current state of every monitor.
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	views := s.buildViews()
+
+	out := make([]MonitorStatusJSON, 0, len(views))
+	for _, v := range views {
+		status := "unknown"
+		if !v.LastChecked.IsZero() {
+			status = statusText(v.IsUp, v.Degraded)
+		}
+		out = append(out, MonitorStatusJSON{
+			Name:        v.Name,
+			Up:          v.IsUp,
+			Status:      status,
+			LastChecked: optionalTime(v.LastChecked),
+			NextCheck:   optionalTime(v.NextCheck),
+
+			ConsecutiveFailures:  v.ConsecutiveFailures,
+			ConsecutiveSuccesses: v.ConsecutiveSuccesses,
+			LastChange:           optionalTime(v.LastChange),
+
+			Stale:    v.Stale,
+			Flaps:    v.Flaps,
+			Flapping: v.Flapping,
+
+			Paused:     v.Paused,
+			MutedUntil: optionalTime(v.MutedUntil),
+		})
+	}
+	writeCachedJSON(w, r, statusMaxAge, out)
+}
+
+// MonitorJSON describes a configured monitor for management UIs.
+type MonitorJSON struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Target   string   `json:"target,omitempty"` // URL (redacted), host:port or host
+	Children []string `json:"children,omitempty"`
+	Interval string   `json:"interval,omitempty"`
+	Cron     string   `json:"cron,omitempty"`
+	// Up means the target is unreachable, as it should be
+	Inverted bool `json:"inverted,omitempty"`
+
+	Status     string     `json:"status"`
+	Paused     bool       `json:"paused"`
+	MutedUntil *time.Time `json:"muted_until,omitempty"`
+}
+
+// handleMonitors serves GET /api/monitors: every configured monitor with
+// its (redacted) settings and current status.
+func (s *Server) handleMonitors(w http.ResponseWriter, r *http.Request) {
+	views := s.buildViews()
+	status := make(map[string]MonitorView, len(views))
+	for _, v := range views {
+		status[v.Name] = v
+	}
+
+	monitors := s.config().Redacted().Monitors
+	out := make([]MonitorJSON, 0, len(monitors))
+	for _, m := range monitors {
+		mj := MonitorJSON{
+			Name:     m.Name,
+			Type:     m.Type,
+			Children: m.Children,
+			Cron:     m.Cron,
+			Inverted: m.Inverted,
+			Status:   "unknown",
+		}
+		switch {
+		case m.URL != "":
+			mj.Target = m.URL
+		case m.Host != "" && m.Port != 0:
+			mj.Target = net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+		default:
+			mj.Target = m.Host
+		}
+		if m.Cron == "" && m.Type != "push" {
+			mj.Interval = m.Interval
+			if mj.Interval == "" {
+				mj.Interval = s.config().Global.CheckInterval
+			}
+		}
+		if v, ok := status[m.Name]; ok {
+			if !v.LastChecked.IsZero() {
+				mj.Status = statusText(v.IsUp, v.Degraded)
+			}
+			mj.Paused = v.Paused
+			mj.MutedUntil = optionalTime(v.MutedUntil)
+		}
+		out = append(out, mj)
+	}
+	writeCachedJSON(w, r, 0, out)
+}
+
+// handleConfig serves GET /api/config: the running configuration with
+// secrets masked. It goes through YAML so the keys match monitors.yaml.
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	raw, err := yaml.Marshal(s.config().Redacted())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to encode config")
+		return
+	}
+	var out map[string]interface{}
+	if err := yaml.Unmarshal(raw, &out); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to encode config")
+		return
+	}
+	writeCachedJSON(w, r, 0, out)
+}
+
+// handleMonitorErrors serves GET /api/monitors/{name}/errors?limit=N
+// with the most recent failed checks, newest first.
+func (s *Server) handleMonitorErrors(w http.ResponseWriter, r *http.Request) {
+	m := s.findMonitor(r.PathValue("name"))
+	if m == nil {
+		writeError(w, http.StatusNotFound, "monitor not found")
+		return
+	}
+
+	limit, err := queryLimit(r, 20, 500)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	failures, err := s.Store.GetErrors(m.Name, limit)
+	if err != nil {
+		log.Printf("Error fetching errors for %s: %v", m.Name, err)
+		writeError(w, http.StatusInternalServerError, "failed to load checks")
+		return
+	}
+
+	writeCachedJSON(w, r, 0, toChecksJSON(failures))
+}
+
+// handleMonitorHistory serves GET /api/monitors/{name}/history?limit=N
+// with the most recent checks, oldest first (same order as the dashboard).
+func (s *Server) handleMonitorHistory(w http.ResponseWriter, r *http.Request) {
+	m := s.findMonitor(r.PathValue("name"))
+	if m == nil {
+		writeError(w, http.StatusNotFound, "monitor not found")
+		return
+	}
+
+	limit, err := queryLimit(r, 90, 1000)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	before, err := parseCursor(r.URL.Query().Get("before"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	history, next, err := s.Store.GetHistoryPaged(m.Name, limit, before)
+	if err != nil {
+		log.Printf("Error fetching history for %s: %v", m.Name, err)
+		writeError(w, http.StatusInternalServerError, "failed to load checks")
+		return
+	}
+
+	if !next.IsZero() {
+		w.Header().Set("X-Next-Cursor", formatCursor(next))
+	}
+	writeCachedJSON(w, r, 0, toChecksJSON(history))
+}
+
+var errBadCursor = errors.New("before must be a cursor from X-Next-Cursor")
+
+// formatCursor encodes a history cursor for X-Next-Cursor as
+// <unix nanoseconds>.<row id>.
+func formatCursor(c store.HistoryCursor) string {
+	return strconv.FormatInt(c.Timestamp.UnixNano(), 10) + "." + strconv.FormatInt(c.ID, 10)
+}
+
+// parseCursor decodes a cursor made by formatCursor. Empty means the
+// newest page.
+func parseCursor(v string) (store.HistoryCursor, error) {
+	if v == "" {
+		return store.HistoryCursor{}, nil
+	}
+	ts, id, ok := strings.Cut(v, ".")
+	if !ok {
+		return store.HistoryCursor{}, errBadCursor
+	}
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return store.HistoryCursor{}, errBadCursor
+	}
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || n <= 0 {
+		return store.HistoryCursor{}, errBadCursor
+	}
+	return store.HistoryCursor{Timestamp: time.Unix(0, nanos).UTC(), ID: n}, nil
+}
+
+// controlActions are the runtime controls of handleMonitorControl.
+var controlActions = []string{"pause", "resume", "mute", "unmute"}
+
+// MonitorControlJSON is a monitor's runtime state after a control action,
+// and the result when it was checked on demand.
+type MonitorControlJSON struct {
+	Name       string     `json:"name"`
+	Paused     bool       `json:"paused"`
+	MutedUntil *time.Time `json:"muted_until,omitempty"`
+	Check      *CheckJSON `json:"check,omitempty"`
+	// The change applied in memory but couldn't be saved
+	Error string `json:"error,omitempty"`
+}
+
+// handleMonitorControl serves POST /api/monitors/{name}/{action} for the
+// pause, resume, mute and unmute actions. Mute takes ?for=<duration>.
+// The change is persisted by the engine and survives a restart.
+func (s *Server) handleMonitorControl(w http.ResponseWriter, r *http.Request) {
+	m := s.findMonitor(r.PathValue("name"))
+	if m == nil {
+		writeError(w, http.StatusNotFound, "monitor not found")
+		return
+	}
+	if s.Engine == nil {
+		writeError(w, http.StatusServiceUnavailable, "monitoring engine not running")
+		return
+	}
+
+	action := r.PathValue("action")
+	if !slices.Contains(controlActions, action) {
+		writeError(w, http.StatusNotFound, "unknown action")
+		return
+	}
+	muteFor, err := muteDuration(r, action)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.applyControl(m.Name, action, muteFor); err != nil {
+		// Applied in memory, only the persistence failed
+		log.Printf("Failed to persist runtime state for %s: %v", m.Name, err)
+		writeError(w, http.StatusInternalServerError, "state changed but could not be saved")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.controlState(m.Name))
+}
+
+// BulkControlJSON is the response of handleBulkControl.
+type BulkControlJSON struct {
+	Affected []MonitorControlJSON `json:"affected"`
+	// Matching monitors left alone: aggregate and push monitors on check
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// handleBulkControl serves POST /api/monitors/{action}?match=<glob> (or
+// ?regex=<pattern>): pause, resume, mute, unmute or check every monitor
+// whose name matches, e.g. ?match=api-*. Responds with the monitors that
+// were affected; none matching isn't an error.
+func (s *Server) handleBulkControl(w http.ResponseWriter, r *http.Request) {
+	if s.Engine == nil {
+		writeError(w, http.StatusServiceUnavailable, "monitoring engine not running")
+		return
+	}
+	action := r.PathValue("action")
+	if action != "check" && !slices.Contains(controlActions, action) {
+		writeError(w, http.StatusNotFound, "unknown action")
+		return
+	}
+	matched, err := s.matchMonitors(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	muteFor, err := muteDuration(r, action)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	out := BulkControlJSON{Affected: []MonitorControlJSON{}}
+	if action == "check" {
+		out.Affected, out.Skipped = s.checkAll(matched)
+		writeJSON(w, http.StatusOK, out)
+		return
+	}
+	for _, m := range matched {
+		err := s.applyControl(m.Name, action, muteFor)
+		res := s.controlState(m.Name)
+		if err != nil {
+			log.Printf("Failed to persist runtime state for %s: %v", m.Name, err)
+			res.Error = "state changed but could not be saved"
+		}
+		out.Affected = append(out.Affected, res)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// checkAll probes monitors concurrently, in the order given.
+func (s *Server) checkAll(monitors []config.MonitorConfig) (checked []MonitorControlJSON, skipped []string) {
+	results := make([]*MonitorControlJSON, len(monitors))
+	var wg sync.WaitGroup
+	for i, m := range monitors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := s.Engine.CheckNow(m.Name)
+			if err != nil {
+				return
+			}
+			res := s.controlState(m.Name)
+			cj := toCheckJSON(result)
+			res.Check = &cj
+			results[i] = &res
+		}()
+	}
+	wg.Wait()
+
+	checked = []MonitorControlJSON{}
+	for i, res := range results {
+		if res == nil {
+			skipped = append(skipped, monitors[i].Name)
+			continue
+		}
+		checked = append(checked, *res)
+	}
+	return checked, skipped
+}
+
+// matchMonitors selects monitors by ?match= (a glob as in path.Match,
+// e.g. api-*) or ?regex=, in config order. Exactly one must be given.
+func (s *Server) matchMonitors(q url.Values) ([]config.MonitorConfig, error) {
+	glob, expr := q.Get("match"), q.Get("regex")
+	var match func(name string) bool
+	switch {
+	case glob != "" && expr != "":
+		return nil, errors.New("use either match or regex, not both")
+	case glob != "":
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid match pattern %q", glob)
+		}
+		match = func(name string) bool {
+			ok, _ := path.Match(glob, name)
+			return ok
+		}
+	case expr != "":
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %v", err)
+		}
+		match = re.MatchString
+	default:
+		return nil, errors.New("match or regex is required, e.g. ?match=api-*")
+	}
+
+	var out []config.MonitorConfig
+	for _, m := range s.config().Monitors {
+		if match(m.Name) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// muteDuration parses ?for= of a mute action; other actions don't take it.
+func muteDuration(r *http.Request, action string) (time.Duration, error) {
+	if action != "mute" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(r.URL.Query().Get("for"))
+	if err != nil || d <= 0 {
+		return 0, errors.New("for must be a positive duration, e.g. ?for=1h")
+	}
+	return d, nil
+}
+
+// applyControl performs one of controlActions on a monitor. The error is
+// from persisting the change, which applies in memory regardless.
+func (s *Server) applyControl(name, action string, muteFor time.Duration) error {
+	switch action {
+	case "pause":
+		return s.Engine.Pause(name)
+	case "resume":
+		return s.Engine.Resume(name)
+	case "mute":
+		return s.Engine.Mute(name, time.Now().Add(muteFor))
+	default:
+		return s.Engine.Unmute(name)
+	}
+}
+
+// controlState reports a monitor's runtime controls.
+func (s *Server) controlState(name string) MonitorControlJSON {
+	st := s.Engine.State(name)
+	return MonitorControlJSON{
+		Name:       name,
+		Paused:     st.Paused,
+		MutedUntil: optionalTime(st.MutedUntil),
+	}
+}
+
+// handleMonitorCheck serves POST /api/monitors/{name}/check: probe the
+// monitor now and return the result, which is recorded like any other.
+func (s *Server) handleMonitorCheck(w http.ResponseWriter, r *http.Request) {
+	if s.Engine == nil {
+		writeError(w, http.StatusServiceUnavailable, "monitoring engine not running")
+		return
+	}
+	result, err := s.Engine.CheckNow(r.PathValue("name"))
+	switch {
+	case errors.Is(err, monitor.ErrUnknownMonitor):
+		writeError(w, http.StatusNotFound, "monitor not found")
+		return
+	case errors.Is(err, monitor.ErrNotCheckable):
+		writeError(w, http.StatusConflict, "aggregate and push monitors can't be checked on demand")
+		return
+	}
+	writeJSON(w, http.StatusOK, toCheckJSON(result))
+}
+
+// NotificationJSON is the API representation of a notification attempt.
+type NotificationJSON struct {
+	Timestamp time.Time `json:"timestamp"`
+	Monitor   string    `json:"monitor"`
+	Channel   string    `json:"channel"`
+	Message   string    `json:"message"`
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// handleNotifications serves GET /api/notifications?limit=N, the audit
+// log of alert send attempts, newest first.
+func (s *Server) handleNotifications(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryLimit(r, 50, 500)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	records, err := s.Store.GetNotifications(limit)
+	if err != nil {
+		log.Printf("Error fetching notifications: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to load notifications")
+		return
+	}
+
+	out := make([]NotificationJSON, 0, len(records))
+	for _, rec := range records {
+		out = append(out, NotificationJSON{
+			Timestamp: rec.Timestamp,
+			Monitor:   rec.MonitorName,
+			Channel:   rec.Channel,
+			Message:   rec.Message,
+			Success:   rec.Success,
+			Error:     rec.Error,
+		})
+	}
+	writeCachedJSON(w, r, 0, out)
+}
+
+// --- Helpers ---
+
+var errBadLimit = errors.New("limit must be a positive integer")
+
+func (s *Server) findMonitor(name string) *config.MonitorConfig {
+	cfg := s.config()
+	for i := range cfg.Monitors {
+		if cfg.Monitors[i].Name == name {
+			return &cfg.Monitors[i]
+		}
+	}
+	return nil
+}
+
+// queryLimit parses ?limit=, falling back to def and capping at max.
+func queryLimit(r *http.Request, def, max int) (int, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, errBadLimit
+	}
+	if n > max {
+		n = max
+	}
+	return n, nil
+}
+
+// statusText spells out a status so clients don't have to rely on color.
+func statusText(up, degraded bool) string {
+	switch {
+	case up && degraded:
+		return "degraded"
+	case up:
+		return "up"
+	}
+	return "down"
+}
+
+// optionalTime turns the zero time into nil so it's omitted from JSON.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,34 @@
+package web
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// adminOnly lets requests through to h only with the admin token:
+// Authorization: Bearer <global.admin_token>. Without a token configured
+// it refuses everything.
+func (s *Server) adminOnly(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.Cfg.Global.AdminToken == "" {
+			writeError(w, http.StatusUnauthorized, "set global.admin_token to use this endpoint")
+			return
+		}
+		if !validBearer(s.Cfg.Global.AdminToken, r.Header.Get("Authorization")) {
+			writeError(w, http.StatusUnauthorized, "invalid or missing admin token")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// validBearer checks an Authorization header against token in constant
+// time. An empty token accepts nothing.
+func validBearer(token, header string) bool {
+	got, ok := strings.CutPrefix(header, "Bearer ")
+	if token == "" || !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
@@ -0,0 +1,49 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+func TestControlsNeedAdminToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		token  string
+		status int
+	}{
+		{"no token configured", "", "s3cret", http.StatusUnauthorized},
+		{"no token sent", "admin_token: s3cret", "", http.StatusUnauthorized},
+		{"wrong token", "admin_token: s3cret", "guess", http.StatusUnauthorized},
+		{"right token", "admin_token: s3cret", "s3cret", http.StatusOK},
+	}
+	paths := []string{
+		"/api/monitors/a/pause",
+		"/api/monitors/a/mute?for=1h",
+		"/api/monitors/a/check",
+		"/api/monitors/resume?match=*",
+		"/api/monitors/check?regex=^a$",
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inRepoRoot(t)
+			cfg := testConfig(t, "global: {check_interval: 1h, "+tt.config+"}\nmonitors: [{name: a, type: tcp, host: 127.0.0.1, port: 1}]")
+			st := testStore(t)
+			h := NewHandler(st, cfg, monitor.NewEngine(cfg, st, nil), nil)
+			for _, path := range paths {
+				req := httptest.NewRequest(http.MethodPost, path, nil)
+				if tt.token != "" {
+					req.Header.Set("Authorization", "Bearer "+tt.token)
+				}
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, req)
+				if rec.Code != tt.status {
+					t.Errorf("POST %s: status %d, want %d: %s", path, rec.Code, tt.status, rec.Body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+func TestBulkControl(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		status     int
+		affected   []string
+		skipped    []string
+		checks     bool // the affected monitors were checked
+		wantPaused []string
+		wantMuted  []string
+	}{
+		{
+			name:       "pause by glob",
+			path:       "/api/monitors/pause?match=api-*",
+			status:     http.StatusOK,
+			affected:   []string{"api-1", "api-2", "api-push"},
+			wantPaused: []string{"api-1", "api-2", "api-push"},
+		},
+		{
+			name:      "mute by single character glob",
+			path:      "/api/monitors/mute?match=api-?&for=1h",
+			status:    http.StatusOK,
+			affected:  []string{"api-1", "api-2"},
+			wantMuted: []string{"api-1", "api-2"},
+		},
+		{
+			name:       "pause by regex",
+			path:       "/api/monitors/pause?regex=^(db|api-2)$",
+			status:     http.StatusOK,
+			affected:   []string{"api-2", "db"},
+			wantPaused: []string{"api-2", "db"},
+		},
+		{
+			name:     "check skips push monitors",
+			path:     "/api/monitors/check?match=api-*",
+			status:   http.StatusOK,
+			affected: []string{"api-1", "api-2"},
+			skipped:  []string{"api-push"},
+			checks:   true,
+		},
+		{name: "nothing matches", path: "/api/monitors/pause?match=web-*", status: http.StatusOK, affected: []string{}},
+		{name: "no pattern", path: "/api/monitors/pause", status: http.StatusBadRequest},
+		{name: "glob and regex", path: "/api/monitors/pause?match=a*&regex=a", status: http.StatusBadRequest},
+		{name: "bad glob", path: "/api/monitors/pause?match=[", status: http.StatusBadRequest},
+		{name: "mute needs a duration", path: "/api/monitors/mute?match=*", status: http.StatusBadRequest},
+		{name: "unknown action", path: "/api/monitors/restart?match=*", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inRepoRoot(t)
+			cfg := testConfig(t, `
+global: {check_interval: 1h, admin_token: s3cret}
+monitors:
+  - {name: api-1, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: api-2, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: api-push, type: push}
+  - {name: db, type: tcp, host: 127.0.0.1, port: 1}
+`)
+			st := testStore(t)
+			engine := monitor.NewEngine(cfg, st, nil)
+			h := NewHandler(st, cfg, engine, nil)
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer s3cret")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var got BulkControlJSON
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			var affected []string
+			for _, m := range got.Affected {
+				affected = append(affected, m.Name)
+				if (m.Check != nil) != tt.checks {
+					t.Errorf("%s: check result %+v", m.Name, m.Check)
+				}
+			}
+			if !slices.Equal(affected, tt.affected) {
+				t.Errorf("affected %v, want %v", affected, tt.affected)
+			}
+			if !slices.Equal(got.Skipped, tt.skipped) {
+				t.Errorf("skipped %v, want %v", got.Skipped, tt.skipped)
+			}
+
+			// The others are left untouched
+			for _, m := range cfg.Monitors {
+				s := engine.State(m.Name)
+				if want := slices.Contains(tt.wantPaused, m.Name); s.Paused != want {
+					t.Errorf("%s: paused %v, want %v", m.Name, s.Paused, want)
+				}
+				if want := slices.Contains(tt.wantMuted, m.Name); !s.MutedUntil.IsZero() != want {
+					t.Errorf("%s: muted until %s, want muted %v", m.Name, s.MutedUntil, want)
+				}
+				history, err := st.GetHistory(m.Name, 10)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if checked, want := len(history) > 0, tt.checks && slices.Contains(tt.affected, m.Name); checked != want {
+					t.Errorf("%s: checked %v, want %v", m.Name, checked, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Cache lifetimes.
+const (
+	// Static assets aren't versioned, so browsers revalidate them (cheap
+	// with the ETag) after a few minutes
+	staticMaxAge = 5 * time.Minute
+	// Live status, short enough that an auto-refreshing page stays current
+	statusMaxAge = 5 * time.Second
+)
+
+// writeCachedJSON is writeJSON for GET endpoints: the response carries an
+// ETag of its content and a request whose If-None-Match has it gets a 304
+// without a body. maxAge 0 makes clients revalidate every time.
+func writeCachedJSON(w http.ResponseWriter, r *http.Request, maxAge time.Duration, v interface{}) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to encode response")
+		return
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Cache-Control", cacheControl(maxAge))
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// cacheControl is the Cache-Control value for API responses. They're
+// private: outside a public page they're for the operator only.
+func cacheControl(maxAge time.Duration) string {
+	if maxAge <= 0 {
+		return "private, no-cache"
+	}
+	return fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds()))
+}
+
+// etagMatches reports whether an If-None-Match header lists etag. Weak
+// comparison, as RFC 9110 asks for If-None-Match.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheStatic adds Cache-Control and an ETag (from size and modification
+// time) to files served from dir. http.FileServer then answers matching
+// conditional requests with 304 itself.
+func cacheStatic(dir string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fi, err := os.Stat(filepath.Join(dir, filepath.FromSlash(filepath.Clean("/"+r.URL.Path))))
+		if err == nil && !fi.IsDir() {
+			w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, fi.ModTime().UnixNano(), fi.Size()))
+			w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(staticMaxAge.Seconds())))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
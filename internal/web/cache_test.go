@@ -0,0 +1,94 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"xyz", "abc"`, true},
+		{"*", true},
+		{`"abcd"`, false},
+		{`abc`, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, `"abc"`); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	inRepoRoot(t)
+	cfg := testConfig(t, "monitors: [{name: api, type: tcp, host: 127.0.0.1, port: 1}]")
+	st := testStore(t)
+	h := NewHandler(st, cfg, nil, nil)
+	logCheck := func() {
+		t.Helper()
+		if err := st.LogCheck(monitor.CheckResult{MonitorName: "api", Timestamp: time.Now(), Status: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	request := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		path         string
+		cacheControl string
+	}{
+		{"/api/monitors/api/history", "private, no-cache"},
+		{"/api/status", "private, max-age=5"},
+		{"/static/style.css", "public, max-age=300"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			logCheck()
+			first := request(tt.path, "")
+			etag := first.Header().Get("ETag")
+			if first.Code != http.StatusOK || etag == "" {
+				t.Fatalf("status %d, ETag %q; want 200 with an ETag", first.Code, etag)
+			}
+			if got := first.Header().Get("Cache-Control"); got != tt.cacheControl {
+				t.Errorf("Cache-Control %q, want %q", got, tt.cacheControl)
+			}
+			if again := request(tt.path, "").Header().Get("ETag"); again != etag {
+				t.Errorf("ETag changed from %s to %s without a change", etag, again)
+			}
+
+			for _, header := range []string{etag, "W/" + etag, `"other", ` + etag} {
+				rec := request(tt.path, header)
+				if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+					t.Errorf("If-None-Match: %s: status %d with %d bytes, want an empty 304", header, rec.Code, rec.Body.Len())
+				}
+			}
+			if rec := request(tt.path, `"other"`); rec.Code != http.StatusOK {
+				t.Errorf("If-None-Match with another ETag: status %d, want 200", rec.Code)
+			}
+		})
+	}
+
+	// A new check changes the content and so the ETag
+	rec := request("/api/monitors/api/history", "")
+	logCheck()
+	if rec := request("/api/monitors/api/history", rec.Header().Get("ETag")); rec.Code != http.StatusOK {
+		t.Errorf("history after a new check: status %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+func TestMonitorCheckNow(t *testing.T) {
+	var up atomic.Bool
+	up.Store(true)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer target.Close()
+
+	inRepoRoot(t)
+	cfg := testConfig(t, `
+global: {check_interval: 1h, admin_token: s3cret}
+monitors:
+  - {name: api, type: http, url: "`+target.URL+`"}
+  - {name: agent, type: push}
+`)
+	st := testStore(t)
+	h := NewHandler(st, cfg, monitor.NewEngine(cfg, st, nil), nil)
+	post := func(name string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/monitors/"+name+"/check", nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	steps := []struct {
+		up         bool
+		wantStatus int // of the check
+	}{
+		{true, http.StatusOK},
+		{false, http.StatusServiceUnavailable},
+	}
+	for i, s := range steps {
+		up.Store(s.up)
+		before := time.Now()
+		rec := post("api")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("check %d: status %d: %s", i, rec.Code, rec.Body)
+		}
+		var got CheckJSON
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Up != s.up || got.StatusCode != s.wantStatus || got.Timestamp.Before(before) {
+			t.Errorf("check %d: %+v, want up %v with status %d from after %s", i, got, s.up, s.wantStatus, before)
+		}
+
+		history, err := st.GetHistory("api", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != i+1 {
+			t.Fatalf("check %d: %d checks stored, want %d", i, len(history), i+1)
+		}
+		if last := history[len(history)-1]; last.Status != s.up || !last.Timestamp.Equal(got.Timestamp) {
+			t.Errorf("check %d: stored %+v, want the returned result", i, last)
+		}
+	}
+
+	for name, want := range map[string]int{"nope": http.StatusNotFound, "agent": http.StatusConflict} {
+		if rec := post(name); rec.Code != want {
+			t.Errorf("check of %s: status %d, want %d", name, rec.Code, want)
+		}
+	}
+}
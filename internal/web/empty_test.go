@@ -0,0 +1,37 @@
+package web
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+// A config without monitors still gives a working server.
+func TestEmptyConfig(t *testing.T) {
+	inRepoRoot(t)
+	cfg := testConfig(t, "global: {check_interval: 1m}\n")
+	st := testStore(t)
+	engine := monitor.NewEngine(cfg, st, nil)
+	engine.Start()
+	defer engine.Stop(context.Background())
+	h := NewHandler(st, cfg, engine, nil)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/", "No monitors configured"},
+		{"/api/status", "[]"},
+		{"/api/monitors", "[]"},
+		{"/api/health-summary", `"status":"unknown"`},
+		{"/healthz", `"initializing":false`},
+		{"/metrics", "zenmonitor_store_errors_total 0"},
+	}
+	for _, tt := range tests {
+		if body := get(t, h, tt.path).Body.String(); !strings.Contains(body, tt.want) {
+			t.Errorf("GET %s: %s, want %q in it", tt.path, body, tt.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+func TestHealthzWhileInitializing(t *testing.T) {
+	release := make(chan struct{})
+	var once sync.Once
+	unblock := func() { once.Do(func() { close(release) }) }
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-release }))
+	t.Cleanup(slow.Close)
+
+	inRepoRoot(t)
+	cfg := testConfig(t, `
+global: {check_interval: 1h}
+monitors:
+  - {name: slow, type: http, url: "`+slow.URL+`", timeout: 30s}
+  - {name: agent, type: push}
+`)
+	st := testStore(t)
+	engine := monitor.NewEngine(cfg, st, nil)
+	h := NewHandler(st, cfg, engine, nil)
+	healthz := func() HealthzJSON {
+		t.Helper()
+		var out HealthzJSON
+		if err := json.NewDecoder(get(t, h, "/healthz").Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	// Before and while the first check runs
+	if got := healthz(); got != (HealthzJSON{Status: "ok", Initializing: true}) {
+		t.Errorf("before starting: %+v", got)
+	}
+	engine.Start()
+	defer engine.Stop(context.Background())
+	defer unblock()
+	if got := healthz(); got != (HealthzJSON{Status: "ok", Initializing: true}) {
+		t.Errorf("during the first check: %+v", got)
+	}
+	if engine.State("slow").Checked {
+		t.Fatal("first check done before the server answered")
+	}
+
+	// The push monitor without an agent doesn't hold it up
+	unblock()
+	waitFor(t, func() bool { return engine.State("slow").Checked })
+	if got := healthz(); got != (HealthzJSON{Status: "ok"}) {
+		t.Errorf("after the first check: %+v", got)
+	}
+
+	// Without an engine there's nothing to wait for
+	if got := get(t, NewHandler(st, cfg, nil, nil), "/healthz").Body.String(); got != `{"status":"ok","initializing":false}`+"\n" {
+		t.Errorf("without an engine: %s", got)
+	}
+}
@@ -0,0 +1,97 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+	"github.com/pronzzz/zenmonitor/internal/store"
+)
+
+// writeConfig writes a monitors.yaml to a temp dir and returns its path.
+func writeConfig(t *testing.T, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "monitors.yaml")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testConfig loads a monitors.yaml from src, validated and resolved like
+// the real thing.
+func testConfig(t *testing.T, src string) *config.Config {
+	t.Helper()
+	cfg, err := config.LoadConfig(writeConfig(t, src))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	return cfg
+}
+
+// inRepoRoot runs the test from the repository root, where the handler
+// finds web/templates and web/static.
+func inRepoRoot(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join("..", "..")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// testStore opens an empty database in a temp dir.
+func testStore(t *testing.T) *store.SQLiteStore {
+	t.Helper()
+	st, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "zen.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+// testServer builds a Server without an engine or routes, for calling
+// handlers directly. Templates are parsed on first use.
+func testServer(cfg *config.Config, st *store.SQLiteStore) *Server {
+	return &Server{
+		Store: st,
+		Cfg:   cfg,
+		Loc:   time.UTC,
+		history: newHistoryCache(func(name string) ([]monitor.CheckResult, error) {
+			return st.GetHistory(name, dashboardHistory)
+		}),
+	}
+}
+
+// get serves a GET of path and returns the response, failing the test
+// unless it's a 200.
+func get(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s: status %d: %s", path, rec.Code, rec.Body)
+	}
+	return rec
+}
+
+// waitFor polls cond until it holds, failing the test after 5 seconds.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
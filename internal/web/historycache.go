@@ -0,0 +1,85 @@
+package web
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+// Dashboard history settings.
+const (
+	// Dots per monitor
+	dashboardHistory = 90
+	// How long cached history is served before it's read again even
+	// without a new check, e.g. after pruning or an import
+	historyTTL = time.Minute
+)
+
+// historyCache keeps each monitor's dashboard history in memory so page
+// loads don't query the store for every monitor. As an engine observer it
+// drops a monitor's entry as soon as a check of it is stored, and the
+// next read loads it again.
+type historyCache struct {
+	load func(name string) ([]monitor.CheckResult, error)
+
+	mu      sync.RWMutex
+	entries map[string]historyEntry
+	// Bumped by invalidate, so a load that raced with a check isn't kept
+	gens map[string]uint64
+}
+
+type historyEntry struct {
+	history []monitor.CheckResult
+	loaded  time.Time
+}
+
+func newHistoryCache(load func(name string) ([]monitor.CheckResult, error)) *historyCache {
+	return &historyCache{
+		load:    load,
+		entries: make(map[string]historyEntry),
+		gens:    make(map[string]uint64),
+	}
+}
+
+// get returns a monitor's history, oldest first. The slice is the
+// caller's to modify.
+func (c *historyCache) get(name string) ([]monitor.CheckResult, error) {
+	c.mu.RLock()
+	e, ok := c.entries[name]
+	gen := c.gens[name]
+	c.mu.RUnlock()
+	if ok && time.Since(e.loaded) < historyTTL {
+		return slices.Clone(e.history), nil
+	}
+
+	loaded := time.Now()
+	history, err := c.load(name)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.gens[name] == gen {
+		c.entries[name] = historyEntry{history: history, loaded: loaded}
+	}
+	c.mu.Unlock()
+	return slices.Clone(history), nil
+}
+
+// invalidate drops a monitor's entry.
+func (c *historyCache) invalidate(name string) {
+	c.mu.Lock()
+	delete(c.entries, name)
+	c.gens[name]++
+	c.mu.Unlock()
+}
+
+// Observe invalidates the entry of a monitor the engine recorded a check
+// for. The store is one of the engine's sinks, which see events before
+// observers do, so the result is in by now.
+func (c *historyCache) Observe(ev monitor.Event) {
+	if ev.Type == monitor.EventCheck {
+		c.invalidate(ev.Result.MonitorName)
+	}
+}
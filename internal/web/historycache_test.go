@@ -0,0 +1,93 @@
+package web
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+func TestHistoryCacheUpdatesAfterCheck(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	cfg := testConfig(t, "monitors: [{name: db, type: tcp, host: 127.0.0.1, port: "+strconv.Itoa(port)+"}]")
+	st := testStore(t)
+	engine := monitor.NewEngine(cfg, st, nil)
+	var loads atomic.Int32
+	cache := newHistoryCache(func(name string) ([]monitor.CheckResult, error) {
+		loads.Add(1)
+		return st.GetHistory(name, dashboardHistory)
+	})
+	engine.Observe(cache)
+
+	for want := 1; want <= 3; want++ {
+		if _, err := engine.CheckNow("db"); err != nil {
+			t.Fatal(err)
+		}
+		// The cache observes the check on a goroutine of its own
+		waitFor(t, func() bool {
+			history, err := cache.get("db")
+			return err == nil && len(history) == want
+		})
+		// Twice: the second read comes from the cache
+		for range 2 {
+			history, err := cache.get("db")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(history) != want {
+				t.Fatalf("%d results after %d checks, want %d", len(history), want, want)
+			}
+		}
+		if got := loads.Load(); got != int32(want) {
+			t.Errorf("%d loads after %d checks, want one per check", got, want)
+		}
+	}
+}
+
+// Run with -race. Reads racing with invalidations never keep history
+// loaded before the last invalidation.
+func TestHistoryCacheConcurrentFillAndInvalidate(t *testing.T) {
+	var version atomic.Int64
+	cache := newHistoryCache(func(name string) ([]monitor.CheckResult, error) {
+		return []monitor.CheckResult{{MonitorName: name, Checks: int(version.Load())}}, nil
+	})
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				if _, err := cache.get("api"); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				version.Add(1)
+				cache.Observe(monitor.Event{Type: monitor.EventCheck, Result: monitor.CheckResult{MonitorName: "api"}})
+			}
+		}()
+	}
+	wg.Wait()
+
+	history, err := cache.get("api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := history[0].Checks, int(version.Load()); got != want {
+		t.Errorf("cached history is from version %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+	"github.com/pronzzz/zenmonitor/internal/store"
+)
+
+// IncidentJSON is the API representation of an incident.
+type IncidentJSON struct {
+	Monitor         string     `json:"monitor"`
+	Start           time.Time  `json:"start"`
+	End             *time.Time `json:"end,omitempty"` // unset while ongoing
+	DurationSeconds float64    `json:"duration_seconds"`
+	Ongoing         bool       `json:"ongoing"`
+}
+
+// handleIncidents serves GET /api/incidents, the outages of every monitor
+// (or ?monitor=name) over the history window or ?since=<duration>, oldest
+// first. ?format=prometheus exports them as one
+// zenmonitor_incident_duration_seconds sample per incident, stamped with
+// its start, for backfilling into Prometheus.
+func (s *Server) handleIncidents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	window := time.Duration(s.Cfg.Global.HistoryDays) * 24 * time.Hour
+	if v := q.Get("since"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "since must be a positive duration, e.g. ?since=168h")
+			return
+		}
+		window = d
+	}
+
+	monitors := s.config().Monitors
+	if name := q.Get("monitor"); name != "" {
+		m := s.findMonitor(name)
+		if m == nil {
+			writeError(w, http.StatusNotFound, "monitor not found")
+			return
+		}
+		monitors = []config.MonitorConfig{*m}
+	}
+
+	now := time.Now()
+	var incidents []store.Incident
+	for _, m := range monitors {
+		found, err := s.Store.GetIncidents(m.Name, now.Add(-window))
+		if err != nil {
+			log.Printf("Error fetching incidents for %s: %v", m.Name, err)
+			writeError(w, http.StatusInternalServerError, "failed to load incidents")
+			return
+		}
+		incidents = append(incidents, found...)
+	}
+	slices.SortStableFunc(incidents, func(a, b store.Incident) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	switch q.Get("format") {
+	case "", "json":
+		out := make([]IncidentJSON, 0, len(incidents))
+		for _, inc := range incidents {
+			ij := IncidentJSON{
+				Monitor:         inc.MonitorName,
+				Start:           inc.Start,
+				DurationSeconds: inc.Duration(now).Seconds(),
+				Ongoing:         inc.Ongoing(),
+			}
+			if !inc.Ongoing() {
+				end := inc.End
+				ij.End = &end
+			}
+			out = append(out, ij)
+		}
+		writeCachedJSON(w, r, 0, out)
+	case "prometheus":
+		var buf bytes.Buffer
+		writeMetricHeader(&buf, "zenmonitor_incident_duration_seconds", "gauge", "How long an incident of the monitor lasted, stamped with its start.")
+		for _, inc := range incidents {
+			// start keeps every incident its own series
+			fmt.Fprintf(&buf, "zenmonitor_incident_duration_seconds{monitor=%s,start=%s,ongoing=\"%t\"} %s %d\n",
+				labelValue(inc.MonitorName), labelValue(inc.Start.Format(time.RFC3339)), inc.Ongoing(),
+				strconv.FormatFloat(inc.Duration(now).Seconds(), 'g', -1, 64), inc.Start.UnixMilli())
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		buf.WriteTo(w)
+	default:
+		writeError(w, http.StatusBadRequest, "format must be json or prometheus")
+	}
+}
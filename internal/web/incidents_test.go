@@ -0,0 +1,99 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+func TestIncidentsExport(t *testing.T) {
+	inRepoRoot(t)
+	cfg := testConfig(t, `
+monitors:
+  - {name: api, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: db, type: tcp, host: 127.0.0.1, port: 1}
+`)
+	st := testStore(t)
+	start := time.Now().UTC().Add(-2 * time.Hour).Truncate(time.Minute)
+	at := func(min int) time.Time { return start.Add(time.Duration(min) * time.Minute) }
+	for name, statuses := range map[string][]bool{
+		// Down from +1m to +4m, and from +10m on
+		"api": {true, false, false, false, true, true, true, true, true, true, false},
+		// Down from +2m to +3m
+		"db": {true, true, false, true},
+	} {
+		for i, up := range statuses {
+			if err := st.LogCheck(monitor.CheckResult{MonitorName: name, Timestamp: at(i), Status: up}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	h := NewHandler(st, cfg, nil, nil)
+
+	decode := func(path string) []IncidentJSON {
+		t.Helper()
+		var out []IncidentJSON
+		if err := json.NewDecoder(get(t, h, path).Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	type incident struct {
+		monitor    string
+		start, end time.Time // end zero while ongoing
+	}
+	tests := []struct {
+		path string
+		want []incident
+	}{
+		{"/api/incidents", []incident{{"api", at(1), at(4)}, {"db", at(2), at(3)}, {"api", at(10), time.Time{}}}},
+		{"/api/incidents?monitor=db", []incident{{"db", at(2), at(3)}}},
+		{"/api/incidents?since=1h", nil},
+	}
+	for _, tt := range tests {
+		got := decode(tt.path)
+		if len(got) != len(tt.want) {
+			t.Fatalf("GET %s: %+v, want %d incidents", tt.path, got, len(tt.want))
+		}
+		for i, want := range tt.want {
+			inc := got[i]
+			if inc.Monitor != want.monitor || !inc.Start.Equal(want.start) || inc.Ongoing != want.end.IsZero() {
+				t.Errorf("GET %s: incident %d is %+v, want %+v", tt.path, i, inc, want)
+				continue
+			}
+			if inc.Ongoing {
+				if inc.End != nil || inc.DurationSeconds < time.Since(want.start).Seconds()-5 {
+					t.Errorf("GET %s: ongoing incident %d ends %v after %gs", tt.path, i, inc.End, inc.DurationSeconds)
+				}
+			} else if !inc.End.Equal(want.end) || inc.DurationSeconds != want.end.Sub(want.start).Seconds() {
+				t.Errorf("GET %s: incident %d ends %v after %gs, want %s after %gs",
+					tt.path, i, inc.End, inc.DurationSeconds, want.end, want.end.Sub(want.start).Seconds())
+			}
+		}
+	}
+
+	body := get(t, h, "/api/incidents?format=prometheus&monitor=db").Body.String()
+	want := `zenmonitor_incident_duration_seconds{monitor="db",start="` + at(2).Format(time.RFC3339) + `",ongoing="false"} 60 ` +
+		strconv.FormatInt(at(2).UnixMilli(), 10)
+	if !strings.Contains(body, want+"\n") || !strings.Contains(body, "# TYPE zenmonitor_incident_duration_seconds gauge") {
+		t.Errorf("prometheus export:\n%s\nwant a line %s", body, want)
+	}
+
+	for path, status := range map[string]int{
+		"/api/incidents?since=yesterday": http.StatusBadRequest,
+		"/api/incidents?format=csv":      http.StatusBadRequest,
+		"/api/incidents?monitor=nope":    http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != status {
+			t.Errorf("GET %s: status %d, want %d", path, rec.Code, status)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+func TestIndexDots(t *testing.T) {
+	at := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	results := []monitor.CheckResult{
+		{MonitorName: "api", Timestamp: at, Status: true, Latency: 250 * time.Microsecond},
+		{MonitorName: "api", Timestamp: at.Add(time.Minute), Status: false, Error: "connection refused"},
+		{MonitorName: "api", Timestamp: at.Add(2 * time.Minute), Status: true, Latency: 12 * time.Millisecond},
+	}
+	tests := []struct {
+		name    string
+		public  bool
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "private",
+			want: []string{
+				`data-time="2026-10-16T09:00:00Z" data-status="up" data-latency-ms="0.25"`,
+				`data-time="2026-10-16T09:01:00Z" data-status="down" data-error="connection refused"`,
+				`data-time="2026-10-16T09:02:00Z" data-status="up" data-latency-ms="12"`,
+			},
+		},
+		{
+			name:    "public",
+			public:  true,
+			want:    []string{`data-time="2026-10-16T09:01:00Z" data-status="down"`},
+			notWant: []string{"data-latency-ms", "data-error", "connection refused"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inRepoRoot(t)
+			cfg := testConfig(t, "monitors: [{name: api, type: tcp, host: 127.0.0.1, port: 1}]")
+			cfg.Global.Public = tt.public
+			st := testStore(t)
+			for _, r := range results {
+				if err := st.LogCheck(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			rec := httptest.NewRecorder()
+			NewHandler(st, cfg, nil, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body)
+			}
+
+			body := rec.Body.String()
+			for _, s := range tt.want {
+				if !strings.Contains(body, s) {
+					t.Errorf("page doesn't contain %s", s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(body, s) {
+					t.Errorf("page contains %s", s)
+				}
+			}
+			// The tooltip adds the error from data-error
+			if n := strings.Count(body, "connection refused"); !tt.public && n != 1 {
+				t.Errorf("error is on the page %d times, want once", n)
+			}
+		})
+	}
+}
+
+func TestIndexRefresh(t *testing.T) {
+	tests := []struct {
+		setting string
+		want    string // empty for no polling
+	}{
+		{"", `hx-trigger="every 30s"`},
+		{"dashboard_refresh: 10s", `hx-trigger="every 10s"`},
+		{"dashboard_refresh: 90", `hx-trigger="every 90s"`},
+		{"dashboard_refresh: 0", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.setting, func(t *testing.T) {
+			inRepoRoot(t)
+			cfg := testConfig(t, "global: {"+tt.setting+"}\nmonitors: [{name: api, type: tcp, host: 127.0.0.1, port: 1}]")
+			body := get(t, NewHandler(testStore(t), cfg, nil, nil), "/").Body.String()
+			if tt.want == "" {
+				if strings.Contains(body, "hx-trigger") {
+					t.Errorf("page polls with auto-refresh off")
+				}
+				return
+			}
+			if !strings.Contains(body, tt.want) {
+				t.Errorf("page doesn't contain %s", tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexAccessibleStatus(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		name       string
+		accessible bool
+		want       []string
+		notWant    []string
+	}{
+		{
+			name:       "accessible",
+			accessible: true,
+			want:       []string{`<body class="accessible">`, "&#10003; Operational", "&#10007; Outage", "&hellip; Initializing"},
+		},
+		{
+			name:    "colors only",
+			want:    []string{"<body>", "Operational", "Outage", "Initializing"},
+			notWant: []string{"accessible", "&#10003;", "&#10007;"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inRepoRoot(t)
+			cfg := testConfig(t, `
+monitors:
+  - {name: web, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: db, type: tcp, host: 127.0.0.1, port: 2}
+  - {name: new, type: tcp, host: 127.0.0.1, port: 3}
+`)
+			cfg.Global.AccessibleStatus = tt.accessible
+			st := testStore(t)
+			for _, r := range []monitor.CheckResult{
+				{MonitorName: "web", Timestamp: now, Status: true},
+				{MonitorName: "db", Timestamp: now, Status: false, Error: "connection refused"},
+			} {
+				if err := st.LogCheck(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			body := get(t, NewHandler(st, cfg, nil, nil), "/").Body.String()
+			for _, s := range tt.want {
+				if !strings.Contains(body, s) {
+					t.Errorf("page doesn't contain %s", s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(body, s) {
+					t.Errorf("page contains %s", s)
+				}
+			}
+		})
+	}
+}
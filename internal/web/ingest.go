@@ -0,0 +1,110 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+// maxIngestBytes caps the size of a pushed result.
+const maxIngestBytes = 64 << 10
+
+// signaturePrefix is prepended to the hex HMAC in X-Signature.
+const signaturePrefix = "sha256="
+
+// ingestMaxAge is how far X-Timestamp may be from the server's clock,
+// either way. A captured push can't be replayed once it's older.
+const ingestMaxAge = 5 * time.Minute
+
+// IngestJSON is a check result pushed by a remote agent.
+type IngestJSON struct {
+	Monitor   string    `json:"monitor"`
+	Up        bool      `json:"up"`
+	LatencyMs int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"` // optional, defaults to receive time
+}
+
+// handleIngest serves POST /api/ingest. The request carries the time it
+// was sent, X-Timestamp: <unix seconds>, and is signed with the shared
+// ingest secret: X-Signature: sha256=<hex HMAC-SHA256 of
+// "<timestamp>.<body>">.
+func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIngestBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "body too large")
+		return
+	}
+	sent := r.Header.Get("X-Timestamp")
+	if !validSignature(s.Cfg.Global.IngestSecret, sent, body, r.Header.Get("X-Signature")) {
+		writeError(w, http.StatusUnauthorized, "invalid signature")
+		return
+	}
+	if err := checkSentAt(sent, time.Now()); err != nil {
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var in IngestJSON
+	if err := json.Unmarshal(body, &in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	err = s.Engine.Ingest(monitor.CheckResult{
+		MonitorName: in.Monitor,
+		Timestamp:   in.Timestamp,
+		Status:      in.Up,
+		Latency:     time.Duration(in.LatencyMs) * time.Millisecond,
+		Error:       in.Error,
+	})
+	if errors.Is(err, monitor.ErrNotPushMonitor) {
+		writeError(w, http.StatusNotFound, "no push monitor named "+in.Monitor)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to ingest result for %s: %v", in.Monitor, err)
+		writeError(w, http.StatusInternalServerError, "failed to record result")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validSignature checks an X-Signature header against the HMAC of the
+// timestamp and body in constant time.
+func validSignature(secret, timestamp string, body []byte, header string) bool {
+	if secret == "" || timestamp == "" || !strings.HasPrefix(header, signaturePrefix) {
+		return false
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(header, signaturePrefix))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// checkSentAt rejects an X-Timestamp further than ingestMaxAge from now.
+func checkSentAt(timestamp string, now time.Time) error {
+	secs, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid X-Timestamp, want unix seconds")
+	}
+	if d := now.Sub(time.Unix(secs, 0)).Abs(); d > ingestMaxAge {
+		return fmt.Errorf("X-Timestamp is %s off the server's clock, more than the %s allowed", d.Round(time.Second), ingestMaxAge)
+	}
+	return nil
+}
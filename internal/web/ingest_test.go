@@ -0,0 +1,75 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+const ingestSecret = "push-s3cret"
+
+// sign returns the X-Signature of body sent at timestamp.
+func sign(secret, timestamp, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + body))
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestHandleIngest(t *testing.T) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-ingestMaxAge-time.Minute).Unix(), 10)
+	future := strconv.FormatInt(time.Now().Add(ingestMaxAge+time.Minute).Unix(), 10)
+	const body = `{"monitor": "agent", "up": true, "latency_ms": 12}`
+
+	tests := []struct {
+		name      string
+		body      string
+		timestamp string
+		signature string
+		status    int
+	}{
+		{"valid", body, now, sign(ingestSecret, now, body), http.StatusNoContent},
+		{"tampered body", strings.Replace(body, "true", "false", 1), now, sign(ingestSecret, now, body), http.StatusUnauthorized},
+		{"wrong secret", body, now, sign("guess", now, body), http.StatusUnauthorized},
+		{"tampered timestamp", body, future, sign(ingestSecret, now, body), http.StatusUnauthorized},
+		{"missing timestamp", body, "", sign(ingestSecret, "", body), http.StatusUnauthorized},
+		{"replayed later", body, old, sign(ingestSecret, old, body), http.StatusUnauthorized},
+		{"from the future", body, future, sign(ingestSecret, future, body), http.StatusUnauthorized},
+		{"not a push monitor", `{"monitor": "web", "up": true}`, now, sign(ingestSecret, now, `{"monitor": "web", "up": true}`), http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inRepoRoot(t)
+			cfg := testConfig(t, `
+global: {check_interval: 1h, ingest_secret: `+ingestSecret+`}
+monitors:
+  - {name: agent, type: push}
+  - {name: web, type: tcp, host: 127.0.0.1, port: 1}
+`)
+			st := testStore(t)
+			engine := monitor.NewEngine(cfg, st, nil)
+			h := NewHandler(st, cfg, engine, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/ingest", strings.NewReader(tt.body))
+			req.Header.Set("X-Timestamp", tt.timestamp)
+			req.Header.Set("X-Signature", tt.signature)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if checked := engine.State("agent").Checked; checked != (tt.status == http.StatusNoContent) {
+				t.Errorf("push monitor checked = %v after a %d", checked, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// handleMetrics serves GET /metrics in the Prometheus text exposition format.
+// Hand-rolled to avoid pulling in the client library for a handful of series.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+
+	if s.Engine != nil {
+		states := s.Engine.States()
+
+		writeMetricHeader(&buf, "zenmonitor_monitor_up", "gauge", "Whether the last check of the monitor succeeded (1) or not (0).")
+		for _, st := range states {
+			if !st.Checked {
+				continue
+			}
+			fmt.Fprintf(&buf, "zenmonitor_monitor_up{monitor=%s} %d\n", labelValue(st.Name), boolToInt(st.IsUp))
+		}
+
+		writeMetricHeader(&buf, "zenmonitor_check_duration_seconds", "histogram", "Duration of monitor checks.")
+		for _, st := range states {
+			h := st.Latency
+			if h == nil {
+				continue
+			}
+			name := labelValue(st.Name)
+			for i, bound := range h.Bounds {
+				fmt.Fprintf(&buf, "zenmonitor_check_duration_seconds_bucket{monitor=%s,le=\"%s\"} %d\n",
+					name, strconv.FormatFloat(bound, 'g', -1, 64), h.Counts[i])
+			}
+			fmt.Fprintf(&buf, "zenmonitor_check_duration_seconds_bucket{monitor=%s,le=\"+Inf\"} %d\n", name, h.Count)
+			fmt.Fprintf(&buf, "zenmonitor_check_duration_seconds_sum{monitor=%s} %s\n", name, strconv.FormatFloat(h.Sum, 'g', -1, 64))
+			fmt.Fprintf(&buf, "zenmonitor_check_duration_seconds_count{monitor=%s} %d\n", name, h.Count)
+		}
+
+		writeMetricHeader(&buf, "zenmonitor_slo_burn_rate", "gauge", "Error budget burn rate of the monitor's SLO over its burn window.")
+		for _, st := range states {
+			if st.SLO != nil {
+				fmt.Fprintf(&buf, "zenmonitor_slo_burn_rate{monitor=%s} %s\n", labelValue(st.Name), strconv.FormatFloat(st.SLO.BurnRate, 'g', -1, 64))
+			}
+		}
+		writeMetricHeader(&buf, "zenmonitor_slo_error_budget_remaining", "gauge", "Fraction of the SLO window's error budget left, negative when overspent.")
+		for _, st := range states {
+			if st.SLO != nil {
+				fmt.Fprintf(&buf, "zenmonitor_slo_error_budget_remaining{monitor=%s} %s\n", labelValue(st.Name), strconv.FormatFloat(st.SLO.BudgetRemaining, 'g', -1, 64))
+			}
+		}
+
+		writeMetricHeader(&buf, "zenmonitor_monitor_flaps", "gauge", "UP/DOWN changes of the monitor within global.flap_window.")
+		for _, st := range states {
+			fmt.Fprintf(&buf, "zenmonitor_monitor_flaps{monitor=%s} %d\n", labelValue(st.Name), st.Flaps)
+		}
+
+		writeMetricHeader(&buf, "zenmonitor_check_overruns_total", "counter", "Checks that took longer than the monitor's interval.")
+		for _, st := range states {
+			fmt.Fprintf(&buf, "zenmonitor_check_overruns_total{monitor=%s} %d\n", labelValue(st.Name), st.Overruns)
+		}
+
+		// Labels go on an info metric rather than every series, so adding
+		// one doesn't multiply the series count. Join on monitor in queries.
+		writeMetricHeader(&buf, "zenmonitor_monitor_info", "gauge", "Configured labels of the monitor, always 1.")
+		for _, m := range s.config().Monitors {
+			keys := make([]string, 0, len(m.Labels))
+			for k := range m.Labels {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			fmt.Fprintf(&buf, "zenmonitor_monitor_info{monitor=%s", labelValue(m.Name))
+			for _, k := range keys {
+				fmt.Fprintf(&buf, ",%s=%s", k, labelValue(m.Labels[k]))
+			}
+			buf.WriteString("} 1\n")
+		}
+
+		writeMetricHeader(&buf, "zenmonitor_store_errors_total", "counter", "Check results that could not be written to the store.")
+		fmt.Fprintf(&buf, "zenmonitor_store_errors_total %d\n", s.Engine.StoreErrors())
+
+		writeMetricHeader(&buf, "zenmonitor_clock_anomalies_total", "counter", "Check results whose timestamp did not fit the system clock.")
+		fmt.Fprintf(&buf, "zenmonitor_clock_anomalies_total %d\n", s.Engine.ClockAnomalies())
+
+		writeMetricHeader(&buf, "zenmonitor_events_dropped_total", "counter", "Engine events dropped because a subscriber was too slow.")
+		fmt.Fprintf(&buf, "zenmonitor_events_dropped_total %d\n", s.Engine.DroppedEvents())
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	buf.WriteTo(w)
+}
+
+func writeMetricHeader(buf *bytes.Buffer, name, typ, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(buf, "# TYPE %s %s\n", name, typ)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelValue quotes and escapes a Prometheus label value.
+func labelValue(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
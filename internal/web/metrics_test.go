@@ -0,0 +1,132 @@
+package web
+
+import (
+	"cmp"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+// scrape serves GET /metrics and returns the samples by series, e.g.
+// `zenmonitor_monitor_up{monitor="db"}` -> "1".
+func scrape(t *testing.T, h http.Handler) map[string]string {
+	t.Helper()
+	samples := make(map[string]string)
+	for _, line := range strings.Split(get(t, h, "/metrics").Body.String(), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		samples[line[:i]] = line[i+1:]
+	}
+	return samples
+}
+
+func TestMetricsLatencyHistogram(t *testing.T) {
+	inRepoRoot(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	cfg := testConfig(t, `
+monitors:
+  - {name: db, type: tcp, host: 127.0.0.1, port: `+strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)+`}
+  - {name: idle, type: tcp, host: 127.0.0.1, port: 1}
+`)
+	st := testStore(t)
+	engine := monitor.NewEngine(cfg, st, nil)
+	for range 3 {
+		if _, err := engine.CheckNow("db"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	samples := scrape(t, NewHandler(st, cfg, engine, nil))
+
+	tests := []struct {
+		series string
+		want   string
+	}{
+		{`zenmonitor_check_duration_seconds_bucket{monitor="db",le="+Inf"}`, "3"},
+		{`zenmonitor_check_duration_seconds_count{monitor="db"}`, "3"},
+		{`zenmonitor_monitor_up{monitor="db"}`, "1"},
+	}
+	for _, tt := range tests {
+		if got := samples[tt.series]; got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.series, got, tt.want)
+		}
+	}
+	if sum, _ := strconv.ParseFloat(samples[`zenmonitor_check_duration_seconds_sum{monitor="db"}`], 64); sum <= 0 {
+		t.Errorf("duration sum %g, want the checks' total", sum)
+	}
+
+	// Buckets are cumulative
+	type bucket struct {
+		le    float64
+		count uint64
+	}
+	var buckets []bucket
+	prefix := `zenmonitor_check_duration_seconds_bucket{monitor="db",le="`
+	for series, v := range samples {
+		le, ok := strings.CutPrefix(series, prefix)
+		if !ok {
+			continue
+		}
+		bound, err := strconv.ParseFloat(strings.TrimSuffix(le, `"}`), 64)
+		if err != nil {
+			t.Fatalf("%s: %v", series, err)
+		}
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			t.Fatalf("%s = %s: %v", series, v, err)
+		}
+		buckets = append(buckets, bucket{bound, n})
+	}
+	slices.SortFunc(buckets, func(a, b bucket) int { return cmp.Compare(a.le, b.le) })
+	if len(buckets) < 2 {
+		t.Fatalf("%d buckets, want several", len(buckets))
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i].count < buckets[i-1].count {
+			t.Errorf("bucket le=%g has %d, fewer than le=%g with %d", buckets[i].le, buckets[i].count, buckets[i-1].le, buckets[i-1].count)
+		}
+	}
+	for series := range samples {
+		if strings.Contains(series, "duration") && strings.Contains(series, `monitor="idle"`) {
+			t.Errorf("unchecked monitor has a histogram: %s", series)
+		}
+	}
+}
+
+func TestMetricsMonitorInfo(t *testing.T) {
+	inRepoRoot(t)
+	cfg := testConfig(t, `
+monitors:
+  - {name: api, type: tcp, host: 127.0.0.1, port: 1, labels: {team: payments, severity: "page \"now\""}}
+  - {name: bare, type: tcp, host: 127.0.0.1, port: 1}
+`)
+	st := testStore(t)
+	samples := scrape(t, NewHandler(st, cfg, monitor.NewEngine(cfg, st, nil), nil))
+
+	// Label keys are sorted, values escaped
+	for _, series := range []string{
+		`zenmonitor_monitor_info{monitor="api",severity="page \"now\"",team="payments"}`,
+		`zenmonitor_monitor_info{monitor="bare"}`,
+	} {
+		if got := samples[series]; got != "1" {
+			t.Errorf("%s = %q, want 1", series, got)
+		}
+	}
+	// Labels aren't repeated on other series
+	for series := range samples {
+		if strings.Contains(series, "team=") && !strings.HasPrefix(series, "zenmonitor_monitor_info{") {
+			t.Errorf("label on %s", series)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+func TestPublicMode(t *testing.T) {
+	const target = "db.internal.example"
+	const failure = "dial tcp 10.1.2.3:5432: connection refused"
+	tests := []struct {
+		path   string
+		method string
+		// Not served in public mode, the request falls through to the
+		// dashboard
+		internal bool
+	}{
+		{"/", http.MethodGet, false},
+		{"/api/status", http.MethodGet, false},
+		{"/api/config", http.MethodGet, true},
+		{"/api/notifications", http.MethodGet, true},
+		{"/api/monitors", http.MethodGet, true},
+		{"/api/monitors/db/history", http.MethodGet, true},
+		{"/api/monitors/db/errors", http.MethodGet, true},
+		{"/api/incidents", http.MethodGet, true},
+		{"/api/monitors/db/pause", http.MethodPost, true},
+	}
+
+	inRepoRoot(t)
+	cfg := testConfig(t, "global: {public: true}\nmonitors: [{name: db, type: tcp, host: "+target+", port: 5432}]")
+	st := testStore(t)
+	if err := st.LogCheck(monitor.CheckResult{MonitorName: "db", Timestamp: time.Now(), Error: failure}); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(st, cfg, nil, nil)
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body)
+			}
+			if ct := rec.Header().Get("Content-Type"); tt.internal && strings.HasPrefix(ct, "application/json") {
+				t.Errorf("served as %s, want the dashboard", ct)
+			}
+			body := rec.Body.String()
+			if !strings.Contains(body, "db") {
+				t.Errorf("monitor name missing from %s", body)
+			}
+			for _, secret := range []string{target, "10.1.2.3", "connection refused"} {
+				if strings.Contains(body, secret) {
+					t.Errorf("response contains %q", secret)
+				}
+			}
+		})
+	}
+}
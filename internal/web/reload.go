@@ -0,0 +1,45 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+// ReloadFunc loads the config again from where it came from and applies it
+// to the engine. An error means the new config was rejected and nothing
+// changed.
+type ReloadFunc func() (monitor.ReloadSummary, error)
+
+// ReloadJSON is the result of a successful reload, by monitor name.
+type ReloadJSON struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+	// Global or notification settings changed too, they need a restart
+	RestartNeeded bool `json:"restart_needed,omitempty"`
+}
+
+// handleReload serves POST /api/reload, see adminOnly. An invalid config
+// is a 400 with the validation error; the running config stays in place.
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	sum, err := s.reload()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, ReloadJSON{
+		Added:         nonNil(sum.Added),
+		Removed:       nonNil(sum.Removed),
+		Changed:       nonNil(sum.Changed),
+		RestartNeeded: sum.RestartNeeded,
+	})
+}
+
+// nonNil makes empty lists encode as [] rather than null.
+func nonNil(names []string) []string {
+	if names == nil {
+		return []string{}
+	}
+	return names
+}
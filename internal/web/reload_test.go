@@ -0,0 +1,130 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/pronzzz/zenmonitor/internal/config"
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+const reloadConfig = `
+global: {check_interval: 1h, admin_token: s3cret}
+monitors:
+  - {name: a, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: b, type: tcp, host: 127.0.0.1, port: 2}
+`
+
+func TestHandleReload(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    string
+		next     string // written to the config file before the request
+		status   int
+		monitors []string // running afterwards
+	}{
+		{
+			name:     "no token",
+			next:     "monitors: [{name: c, type: tcp, host: 127.0.0.1, port: 3}]",
+			status:   http.StatusUnauthorized,
+			monitors: []string{"a", "b"},
+		},
+		{
+			name:     "wrong token",
+			token:    "guess",
+			next:     "monitors: [{name: c, type: tcp, host: 127.0.0.1, port: 3}]",
+			status:   http.StatusUnauthorized,
+			monitors: []string{"a", "b"},
+		},
+		{
+			name:  "good config",
+			token: "s3cret",
+			next: `
+global: {check_interval: 1h, admin_token: s3cret}
+monitors:
+  - {name: a, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: c, type: tcp, host: 127.0.0.1, port: 3}
+`,
+			status:   http.StatusOK,
+			monitors: []string{"a", "c"},
+		},
+		{
+			name:     "invalid config",
+			token:    "s3cret",
+			next:     `monitors: [{name: c, type: tcp, host: 127.0.0.1, port: 3, interval: soon}]`,
+			status:   http.StatusBadRequest,
+			monitors: []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inRepoRoot(t)
+			path := writeConfig(t, reloadConfig)
+			cfg, err := config.LoadConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			engine := monitor.NewEngine(cfg, nil, nil)
+			reload := func() (monitor.ReloadSummary, error) {
+				next, err := config.LoadConfig(path)
+				if err != nil {
+					return monitor.ReloadSummary{}, err
+				}
+				return engine.Reload(next)
+			}
+			h := NewHandler(nil, cfg, engine, reload)
+
+			if err := os.WriteFile(path, []byte(tt.next), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/reload", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			var names []string
+			for _, m := range engine.Config().Monitors {
+				names = append(names, m.Name)
+			}
+			if !slices.Equal(names, tt.monitors) {
+				t.Errorf("running monitors %v, want %v", names, tt.monitors)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var got ReloadJSON
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			want := ReloadJSON{Added: []string{"c"}, Removed: []string{"b"}, Changed: []string{}}
+			if !slices.Equal(got.Added, want.Added) || !slices.Equal(got.Removed, want.Removed) || !slices.Equal(got.Changed, want.Changed) {
+				t.Errorf("response %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestReloadNotServedWithoutToken(t *testing.T) {
+	inRepoRoot(t)
+	cfg := testConfig(t, "monitors: [{name: a, type: tcp, host: 127.0.0.1, port: 1}]")
+	st := testStore(t)
+	engine := monitor.NewEngine(cfg, st, nil)
+	h := NewHandler(st, cfg, engine, func() (monitor.ReloadSummary, error) {
+		t.Error("reload called without an admin token configured")
+		return monitor.ReloadSummary{}, nil
+	})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/reload", nil))
+	if rec.Code == http.StatusOK && rec.Header().Get("Content-Type") == "application/json" {
+		t.Errorf("POST /api/reload was served without global.admin_token")
+	}
+}
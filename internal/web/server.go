@@ -1,10 +1,15 @@
 package web
 
 import (
+	"bytes"
+	"cmp"
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/pronzzz/zenmonitor/internal/config"
@@ -14,88 +19,366 @@ import (
 
 type Server struct {
 	Store *store.SQLiteStore
-	Cfg   *config.Config
-	Tmpl  *template.Template
+	// Config the server started with. Its global settings stay, for
+	// monitors and views see config
+	Cfg    *config.Config
+	Engine *monitor.Engine
+	Tmpl   *template.Template
+	// Timezone the dashboard renders times in (global.timezone)
+	Loc *time.Location
+
+	history *historyCache
+	// Applies a new config for POST /api/reload; nil disables it
+	reload ReloadFunc
 }
 
 type PageData struct {
 	Now      time.Time
 	Monitors []MonitorView
+	// RefreshSeconds drives the htmx poll; 0 means no auto-refresh
+	RefreshSeconds int
+	// Add symbols/shapes to the red/green status colors
+	Accessible bool
+	// Public status page, error details are left out
+	Public bool
+	// Order requested with ?sort=, kept across auto-refreshes. Empty when
+	// the configured default applies.
+	Sort string
+	// Heading of a /view/ page, empty on the main dashboard
+	Title string
+	// Path the page polls for refreshes
+	Path string
+	// Layout of global.timestamp_format
+	TimeFormat string
+	// global.flap_window, as configured
+	FlapWindow string
+	// Weighted health of the monitors on the page, 0-100, see
+	// healthScore. Negative when there's none yet.
+	HealthScore float64
 }
 
 type MonitorView struct {
-	Name    string
-	IsUp    bool
+	Name     string
+	IsUp     bool
+	Degraded bool
+	// Up means unreachable, see MonitorConfig.Inverted
+	Inverted bool
+	// Share of the health score, see healthScore
+	Weight  float64
 	History []monitor.CheckResult
+	// Share of History that was UP, in percent
+	Uptime float64
+	// Zero when unknown (no check yet / not scheduled)
+	LastChecked time.Time
+	NextCheck   time.Time
+	// Current run of failed / successful checks, from the engine
+	ConsecutiveFailures  int
+	ConsecutiveSuccesses int
+	// When the monitor went UP or DOWN; zero when unknown
+	LastChange time.Time
+	// No result for global.stale_after intervals; IsUp is the last known state
+	Stale bool
+	// UP <-> DOWN changes within global.flap_window, and whether that's
+	// reached the monitor's flap_threshold
+	Flaps    int
+	Flapping bool
+	// Runtime controls set through the API
+	Paused     bool
+	MutedUntil time.Time
 }
 
-func NewHandler(st *store.SQLiteStore, cfg *config.Config) http.Handler {
-	// Parse template
-	tmplPath := filepath.Join("web", "templates", "index.html")
-	tmpl, err := template.ParseFiles(tmplPath)
+// NewHandler builds the dashboard and API. reload may be nil, then the
+// config can't be reloaded through the API.
+func NewHandler(st *store.SQLiteStore, cfg *config.Config, engine *monitor.Engine, reload ReloadFunc) http.Handler {
+	tmpl, err := parseTemplate()
 	if err != nil {
 		log.Printf("Error parsing template (might trigger on first request if failing here): %v", err)
 	}
 
+	loc, err := cfg.Global.Location()
+	if err != nil {
+		// Validated in LoadConfig
+		log.Printf("Invalid timezone %q, using UTC: %v", cfg.Global.Timezone, err)
+		loc = time.UTC
+	}
+
 	s := &Server{
-		Store: st,
-		Cfg:   cfg,
-		Tmpl:  tmpl,
+		Store:  st,
+		Cfg:    cfg,
+		Engine: engine,
+		Tmpl:   tmpl,
+		Loc:    loc,
+		reload: reload,
+	}
+	s.history = newHistoryCache(func(name string) ([]monitor.CheckResult, error) {
+		return st.GetHistory(name, dashboardHistory)
+	})
+	if engine != nil {
+		engine.Observe(s.history)
 	}
 
 	mux := http.NewServeMux()
-	
+
 	// Static files
-	fs := http.FileServer(http.Dir("web/static"))
+	fs := cacheStatic("web/static", http.FileServer(http.Dir("web/static")))
 	mux.Handle("/static/", http.StripPrefix("/static/", fs))
 
-	// Main page
+	// JSON API
+	mux.HandleFunc("GET /api/status", s.handleStatus)
+	mux.HandleFunc("GET /api/health-summary", s.handleHealthSummary)
+	// Targets, error messages, secrets (redacted or not) and controls
+	// stay off a public status page. Controls need the admin token.
+	if !cfg.Global.Public {
+		mux.HandleFunc("GET /api/config", s.handleConfig)
+		mux.HandleFunc("GET /api/notifications", s.handleNotifications)
+		mux.HandleFunc("GET /api/monitors", s.handleMonitors)
+		mux.HandleFunc("GET /api/monitors/{name}/history", s.handleMonitorHistory)
+		mux.HandleFunc("GET /api/monitors/{name}/errors", s.handleMonitorErrors)
+		mux.HandleFunc("GET /api/incidents", s.handleIncidents)
+		mux.HandleFunc("POST /api/monitors/{name}/check", s.adminOnly(s.handleMonitorCheck))
+		mux.HandleFunc("POST /api/monitors/{name}/{action}", s.adminOnly(s.handleMonitorControl))
+		mux.HandleFunc("POST /api/monitors/{action}", s.adminOnly(s.handleBulkControl))
+	}
+
+	// Results pushed by remote agents, only with a shared secret to verify them
+	if cfg.Global.IngestSecret != "" && engine != nil {
+		mux.HandleFunc("POST /api/ingest", s.handleIngest)
+	}
+
+	// Config reloads, e.g. from a deploy pipeline, only with an admin token
+	if cfg.Global.AdminToken != "" && reload != nil {
+		mux.HandleFunc("POST /api/reload", s.adminOnly(s.handleReload))
+	}
+
+	// Liveness, answers before the first checks are done
+	mux.HandleFunc("GET /healthz", s.handleHealthz)
+
+	// Embeddable status badge
+	mux.HandleFunc("GET /badge/overall.svg", s.handleOverallBadge)
+
+	// Prometheus metrics
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+
+	// Dashboard pages for configured views, and the main one
+	mux.HandleFunc("GET /view/{name}", s.handleView)
 	mux.HandleFunc("/", s.handleIndex)
 
 	return mux
 }
 
+// config is the current config: the engine's, which follows reloads, or
+// Cfg without an engine.
+func (s *Server) config() *config.Config {
+	if s.Engine != nil {
+		return s.Engine.Config()
+	}
+	return s.Cfg
+}
+
+// templateFuncs are available to index.html.
+var templateFuncs = template.FuncMap{
+	// ago renders "12s ago" style relative times
+	"ago": func(t time.Time) string {
+		return humanizeDuration(time.Since(t)) + " ago"
+	},
+	// since renders "3d" style durations up to now
+	"since": func(t time.Time) string {
+		return humanizeDuration(time.Since(t))
+	},
+	// until renders "in 48s" style relative times
+	"until": func(t time.Time) string {
+		d := time.Until(t)
+		if d <= 0 {
+			return "now"
+		}
+		return "in " + humanizeDuration(d)
+	},
+	// ms renders a latency in milliseconds to the microsecond, "0.25",
+	// for data attributes
+	"ms": func(d time.Duration) string {
+		return strconv.FormatFloat(float64(d.Round(time.Microsecond))/float64(time.Millisecond), 'f', -1, 64)
+	},
+}
+
+// runStart returns when the run of checks with the latest one's status
+// began, as far as history (oldest first) goes back.
+func runStart(history []monitor.CheckResult) time.Time {
+	i := len(history) - 1
+	for i > 0 && history[i-1].Status == history[i].Status {
+		i--
+	}
+	return history[i].Timestamp
+}
+
+func parseTemplate() (*template.Template, error) {
+	tmplPath := filepath.Join("web", "templates", "index.html")
+	return template.New("index.html").Funcs(templateFuncs).ParseFiles(tmplPath)
+}
+
+func humanizeDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
-    if s.Tmpl == nil {
-        tmplPath := filepath.Join("web", "templates", "index.html")
-        var err error
-        s.Tmpl, err = template.ParseFiles(tmplPath)
-        if err != nil {
-            http.Error(w, "Template error: "+err.Error(), 500)
-            return
-        }
-    }
-
-	// Gather data
+	s.renderDashboard(w, r, nil)
+}
+
+// handleView serves GET /view/{name}, the dashboard limited to a view's monitors.
+func (s *Server) handleView(w http.ResponseWriter, r *http.Request) {
+	view := s.config().View(r.PathValue("name"))
+	if view == nil {
+		http.NotFound(w, r)
+		return
+	}
+	s.renderDashboard(w, r, view)
+}
+
+// renderDashboard renders the dashboard with all monitors, or only those
+// of view when it's not nil.
+func (s *Server) renderDashboard(w http.ResponseWriter, r *http.Request, view *config.ViewConfig) {
+	if s.Tmpl == nil {
+		var err error
+		s.Tmpl, err = parseTemplate()
+		if err != nil {
+			http.Error(w, "Template error: "+err.Error(), 500)
+			return
+		}
+	}
+
+	var include func(config.MonitorConfig) bool
+	if view != nil {
+		include = view.Includes
+	}
+	views := s.buildViewsFor(include)
+	order := r.URL.Query().Get("sort")
+	if !slices.Contains(config.DashboardSorts, order) {
+		order = ""
+	}
+	sortViews(views, cmp.Or(order, s.Cfg.Global.DashboardSort))
+	// The store hands out UTC, show the dots in the configured timezone
+	for n, v := range views {
+		views[n].LastChange = v.LastChange.In(s.Loc)
+		for i := range v.History {
+			h := &v.History[i]
+			h.Timestamp = h.Timestamp.In(s.Loc)
+			if s.Cfg.Global.Public {
+				// Errors tend to name hosts, IPs and ports
+				h.Error = ""
+				h.Timings = monitor.HTTPTimings{}
+			}
+		}
+	}
+
+	data := PageData{
+		Now:            time.Now().In(s.Loc),
+		Monitors:       views,
+		RefreshSeconds: int(config.ParseDuration(s.Cfg.Global.DashboardRefresh).Seconds()),
+		Accessible:     s.Cfg.Global.AccessibleStatus,
+		Public:         s.Cfg.Global.Public,
+		Sort:           order,
+		Path:           "/",
+		TimeFormat:     s.Cfg.Global.TimeLayout(),
+		FlapWindow:     s.Cfg.Global.FlapWindow,
+		HealthScore:    -1,
+	}
+	if score, ok := healthScore(views); ok {
+		data.HealthScore = score
+	}
+	if view != nil {
+		data.Title = cmp.Or(view.Title, view.Name)
+		data.Path = "/view/" + view.Name
+	}
+
+	// Render into a buffer first so a failing template can't leave a
+	// half-written page behind a 200 status.
+	var buf bytes.Buffer
+	if err := s.Tmpl.Execute(&buf, data); err != nil {
+		log.Printf("Template execution error: %v", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// buildViews gathers history and runtime state for every configured monitor.
+func (s *Server) buildViews() []MonitorView {
+	return s.buildViewsFor(nil)
+}
+
+// buildViewsFor is buildViews for the monitors include accepts (all when nil).
+func (s *Server) buildViewsFor(include func(config.MonitorConfig) bool) []MonitorView {
 	var views []MonitorView
-	for _, m := range s.Cfg.Monitors {
-		// Get last 90 checks
-		history, err := s.Store.GetHistory(m.Name, 90)
+	for _, m := range s.config().Monitors {
+		if include != nil && !include(m) {
+			continue
+		}
+		history, err := s.history.get(m.Name)
 		if err != nil {
 			log.Printf("Error fetching history for %s: %v", m.Name, err)
 			continue
 		}
 
+		view := MonitorView{
+			Name:     m.Name,
+			Inverted: m.Inverted,
+			Weight:   m.HealthWeight(),
+			History:  history,
+		}
+
 		// Determine current status (latest check)
-		isUp := false
 		if len(history) > 0 {
-			// history is reversed (oldest first) in store.go
-			isUp = history[len(history)-1].Status
-		}
+			// Rows sampled by store_every count for the checks they stand for
+			up, total := 0, 0
+			for _, h := range history {
+				n := max(h.Checks, 1)
+				total += n
+				if h.Status {
+					up += n
+				}
+			}
+			view.Uptime = 100 * float64(up) / float64(total)
 
-		views = append(views, MonitorView{
-			Name:    m.Name,
-			IsUp:    isUp,
-			History: history,
-		})
-	}
+			// history is oldest first (see store.GetHistory)
+			latest := history[len(history)-1]
+			view.IsUp = latest.Status
+			view.Degraded = latest.Degraded
+			view.LastChecked = latest.Timestamp
+			view.LastChange = runStart(history)
+		}
 
-	data := PageData{
-		Now:      time.Now(),
-		Monitors: views,
-	}
+		// The engine knows better than the DB when it's running
+		if s.Engine != nil {
+			st := s.Engine.State(m.Name)
+			if st.Checked {
+				view.IsUp = st.IsUp
+				view.Degraded = st.Degraded
+				view.LastChecked = st.LastCheck
+				view.ConsecutiveFailures = st.ConsecutiveFailures
+				view.ConsecutiveSuccesses = st.ConsecutiveSuccesses
+				view.LastChange = st.LastChange
+			}
+			view.Stale = st.Stale
+			view.Flaps = st.Flaps
+			view.Flapping = st.Flapping
+			view.NextCheck = st.NextCheck
+			view.Paused = st.Paused
+			view.MutedUntil = st.MutedUntil
+		}
 
-	if err := s.Tmpl.Execute(w, data); err != nil {
-		log.Printf("Template execution error: %v", err)
+		views = append(views, view)
 	}
+	return views
 }
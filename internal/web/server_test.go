@@ -0,0 +1,37 @@
+package web
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRenderDashboardTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		tmpl   string
+		status int
+	}{
+		{"good", `<p>{{ len .Monitors }} monitors</p>`, http.StatusOK},
+		{"index out of range", `<p>partial</p>{{ index .Monitors 5 }}`, http.StatusInternalServerError},
+		{"bad field", `<p>partial</p>{{ .Nope }}`, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testServer(testConfig(t, "monitors: [{name: api, type: tcp, host: 127.0.0.1, port: 1}]"), testStore(t))
+			s.Tmpl = template.Must(template.New("index.html").Parse(tt.tmpl))
+
+			rec := httptest.NewRecorder()
+			s.handleIndex(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status != http.StatusOK && strings.Contains(rec.Body.String(), "partial") {
+				t.Errorf("error response carries part of the page: %q", rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package web
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// sortViews orders the dashboard's monitors in place. Sorting is stable,
+// so monitors that compare equal keep their config order. "config" and
+// unknown orders leave the slice alone.
+func sortViews(views []MonitorView, order string) {
+	switch order {
+	case "name":
+		slices.SortStableFunc(views, func(a, b MonitorView) int {
+			return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		})
+	case "status":
+		slices.SortStableFunc(views, func(a, b MonitorView) int {
+			return cmp.Compare(statusRank(a), statusRank(b))
+		})
+	case "uptime":
+		// Monitors without history have no uptime to speak of, put them last
+		slices.SortStableFunc(views, func(a, b MonitorView) int {
+			if (len(a.History) == 0) != (len(b.History) == 0) {
+				if len(a.History) == 0 {
+					return 1
+				}
+				return -1
+			}
+			return cmp.Compare(a.Uptime, b.Uptime)
+		})
+	}
+}
+
+// statusRank puts the monitors needing attention first: down, degraded,
+// not checked yet, up.
+func statusRank(v MonitorView) int {
+	switch {
+	case v.LastChecked.IsZero():
+		return 2
+	case !v.IsUp:
+		return 0
+	case v.Degraded:
+		return 1
+	}
+	return 3
+}
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+var monitorNameRe = regexp.MustCompile(`<div class="monitor-name">([^<]*)</div>`)
+
+// dashboardOrder returns the monitor names on a dashboard page, in order.
+func dashboardOrder(body string) []string {
+	var names []string
+	for _, m := range monitorNameRe.FindAllStringSubmatch(body, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+func TestDashboardSort(t *testing.T) {
+	tests := []struct {
+		name       string
+		globalSort string
+		path       string
+		want       []string
+	}{
+		{"config order by default", "", "/", []string{"web", "DB", "api", "new"}},
+		{"by name, ignoring case", "", "/?sort=name", []string{"api", "DB", "new", "web"}},
+		{"down, degraded, unchecked, up", "", "/?sort=status", []string{"DB", "api", "new", "web"}},
+		{"lowest uptime first, unchecked last", "", "/?sort=uptime", []string{"DB", "web", "api", "new"}},
+		{"configured default", "name", "/", []string{"api", "DB", "new", "web"}},
+		{"query overrides the default", "name", "/?sort=config", []string{"web", "DB", "api", "new"}},
+		{"unknown order ignored", "status", "/?sort=random", []string{"DB", "api", "new", "web"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inRepoRoot(t)
+			global := ""
+			if tt.globalSort != "" {
+				global = "global: {dashboard_sort: " + tt.globalSort + "}\n"
+			}
+			cfg := testConfig(t, global+`
+monitors:
+  - {name: web, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: DB, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: api, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: new, type: tcp, host: 127.0.0.1, port: 1}
+`)
+			st := testStore(t)
+			start := time.Now().Add(-time.Hour)
+			for name, checks := range map[string][]monitor.CheckResult{
+				"web": {{Status: true}, {Status: false}, {Status: true}}, // up, 67%
+				"DB":  {{Status: true}, {Status: false}},                 // down, 50%
+				"api": {{Status: true}, {Status: true, Degraded: true}},  // degraded, 100%
+			} {
+				for i, r := range checks {
+					r.MonitorName = name
+					r.Timestamp = start.Add(time.Duration(i) * time.Minute)
+					if err := st.LogCheck(r); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			got := dashboardOrder(get(t, NewHandler(st, cfg, nil, nil), tt.path).Body.String())
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("GET %s: order %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package web
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"net/http"
+)
+
+// HealthSummaryJSON is the overall system status across all monitors.
+type HealthSummaryJSON struct {
+	// "up" when everything is up, "degraded" when some monitors are
+	// degraded but none down, "down" when any is down, "unknown" before
+	// the first checks.
+	Status   string `json:"status"`
+	Total    int    `json:"total"`
+	Up       int    `json:"up"`
+	Degraded int    `json:"degraded"`
+	Down     int    `json:"down"`
+	Unknown  int    `json:"unknown"` // not checked yet
+	Paused   int    `json:"paused"`  // left out of the overall status
+	// Weighted health, 0-100, see healthScore. Unset until a monitor
+	// with a weight has been checked.
+	Score *float64 `json:"score,omitempty"`
+}
+
+// healthScore is the weighted share of healthy monitors, 0-100:
+//
+//	100 * sum(weight * health) / sum(weight)
+//
+// where health is 1 for UP, 0.5 for degraded and 0 for DOWN or stale,
+// over the monitors that have been checked and aren't paused. Weights
+// come from the monitors' weight (default 1). ok is false when there are
+// no such monitors or their weights add up to 0.
+func healthScore(views []MonitorView) (score float64, ok bool) {
+	var sum, total float64
+	for _, v := range views {
+		if v.Paused || v.LastChecked.IsZero() {
+			continue
+		}
+		health := 0.0
+		switch {
+		case v.Stale || !v.IsUp:
+		case v.Degraded:
+			health = 0.5
+		default:
+			health = 1
+		}
+		sum += v.Weight * health
+		total += v.Weight
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return 100 * sum / total, true
+}
+
+// healthSummary rolls the current monitor states up into one status.
+func (s *Server) healthSummary() HealthSummaryJSON {
+	var sum HealthSummaryJSON
+	views := s.buildViews()
+	if score, ok := healthScore(views); ok {
+		score = math.Round(score*10) / 10
+		sum.Score = &score
+	}
+	for _, v := range views {
+		sum.Total++
+		switch {
+		case v.Paused:
+			sum.Paused++
+		case v.LastChecked.IsZero():
+			sum.Unknown++
+		case v.IsUp && v.Degraded:
+			sum.Degraded++
+		case v.IsUp:
+			sum.Up++
+		default:
+			sum.Down++
+		}
+	}
+
+	switch {
+	case sum.Down > 0:
+		sum.Status = "down"
+	case sum.Degraded > 0:
+		sum.Status = "degraded"
+	case sum.Up > 0:
+		sum.Status = "up"
+	default:
+		sum.Status = "unknown"
+	}
+	return sum
+}
+
+// HealthzJSON is the liveness response of /healthz.
+type HealthzJSON struct {
+	Status string `json:"status"` // always "ok"
+	// Some monitors haven't finished their first check since startup
+	Initializing bool `json:"initializing"`
+}
+
+// handleHealthz serves GET /healthz for load balancers and container
+// health checks. It answers 200 as soon as the server listens, before
+// the first checks are done, and doesn't touch the database.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	out := HealthzJSON{Status: "ok"}
+	if s.Engine != nil {
+		for _, m := range s.config().Monitors {
+			// Push monitors wait for their agents, which may take a while
+			if m.Type == "push" {
+				continue
+			}
+			if st := s.Engine.State(m.Name); !st.Checked && !st.Paused {
+				out.Initializing = true
+				break
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleHealthSummary serves GET /api/health-summary.
+func (s *Server) handleHealthSummary(w http.ResponseWriter, r *http.Request) {
+	writeCachedJSON(w, r, statusMaxAge, s.healthSummary())
+}
+
+// badgeStyles maps an overall status to its badge text and color.
+var badgeStyles = map[string]struct{ text, color string }{
+	"up":       {"operational", "#2ec4b6"},
+	"degraded": {"degraded", "#ff9f1c"},
+	"down":     {"outage", "#e71d36"},
+	"unknown":  {"unknown", "#6c757d"},
+}
+
+// handleOverallBadge serves GET /badge/overall.svg, a shields.io style
+// badge for READMEs and wikis.
+func (s *Server) handleOverallBadge(w http.ResponseWriter, r *http.Request) {
+	style := badgeStyles[s.healthSummary().Status]
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	// Badges get embedded in pages that cache aggressively (GitHub's camo)
+	w.Header().Set("Cache-Control", "no-cache, max-age=0")
+	fmt.Fprint(w, renderBadge("status", style.text, style.color))
+}
+
+// renderBadge draws a two-part flat badge. Widths are estimated from the
+// text length, which is close enough for the short words used here.
+func renderBadge(label, value, color string) string {
+	const charWidth, padding = 7, 10
+	lw := len(label)*charWidth + padding
+	vw := len(value)*charWidth + padding
+	label, value = html.EscapeString(label), html.EscapeString(value)
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[3]s: %[4]s">`+
+		`<title>%[3]s: %[4]s</title>`+
+		`<rect width="%[2]d" height="20" fill="#555"/>`+
+		`<rect x="%[2]d" width="%[5]d" height="20" fill="%[6]s"/>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%[7]d" y="14">%[3]s</text><text x="%[8]d" y="14">%[4]s</text></g></svg>`,
+		lw+vw, lw, label, value, vw, color, lw/2, lw+vw/2)
+}
@@ -0,0 +1,167 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pronzzz/zenmonitor/internal/monitor"
+)
+
+func TestHealthSummary(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok.Close()
+
+	tests := []struct {
+		name      string
+		check     []string
+		pause     []string
+		want      HealthSummaryJSON
+		wantBadge string
+	}{
+		{
+			name:      "nothing checked yet",
+			want:      HealthSummaryJSON{Status: "unknown", Total: 4, Unknown: 4},
+			wantBadge: "status: unknown",
+		},
+		{
+			name:      "all checked up",
+			check:     []string{"up"},
+			pause:     []string{"slow", "down", "idle"},
+			want:      HealthSummaryJSON{Status: "up", Total: 4, Up: 1, Paused: 3},
+			wantBadge: "status: operational",
+		},
+		{
+			name:      "degraded beats up",
+			check:     []string{"up", "slow"},
+			want:      HealthSummaryJSON{Status: "degraded", Total: 4, Up: 1, Degraded: 1, Unknown: 2},
+			wantBadge: "status: degraded",
+		},
+		{
+			name:      "down beats degraded",
+			check:     []string{"up", "slow", "down"},
+			want:      HealthSummaryJSON{Status: "down", Total: 4, Up: 1, Degraded: 1, Down: 1, Unknown: 1},
+			wantBadge: "status: outage",
+		},
+		{
+			name:      "paused monitors don't count",
+			check:     []string{"up", "down"},
+			pause:     []string{"down"},
+			want:      HealthSummaryJSON{Status: "up", Total: 4, Up: 1, Unknown: 2, Paused: 1},
+			wantBadge: "status: operational",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inRepoRoot(t)
+			cfg := testConfig(t, `
+monitors:
+  - {name: up, type: http, url: "`+ok.URL+`"}
+  - {name: slow, type: http, url: "`+ok.URL+`", status_map: {"200": degraded}}
+  - {name: down, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: idle, type: tcp, host: 127.0.0.1, port: 1}
+`)
+			st := testStore(t)
+			engine := monitor.NewEngine(cfg, st, nil)
+			for _, name := range tt.check {
+				if _, err := engine.CheckNow(name); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, name := range tt.pause {
+				if err := engine.Pause(name); err != nil {
+					t.Fatal(err)
+				}
+			}
+			h := NewHandler(st, cfg, engine, nil)
+
+			var got HealthSummaryJSON
+			if err := json.Unmarshal(get(t, h, "/api/health-summary").Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			got.Score = nil // weighted, not what this test is about
+			if got != tt.want {
+				t.Errorf("summary %+v, want %+v", got, tt.want)
+			}
+
+			badge := get(t, h, "/badge/overall.svg")
+			if ct := badge.Header().Get("Content-Type"); ct != "image/svg+xml" {
+				t.Errorf("badge Content-Type %q", ct)
+			}
+			if !strings.Contains(badge.Body.String(), "<title>"+tt.wantBadge+"</title>") {
+				t.Errorf("badge %s, want %q", badge.Body, tt.wantBadge)
+			}
+		})
+	}
+}
+
+func TestHealthScore(t *testing.T) {
+	checked := time.Now()
+	up := MonitorView{IsUp: true, Weight: 1, LastChecked: checked}
+	with := func(v MonitorView, weight float64) MonitorView {
+		v.Weight = weight
+		return v
+	}
+	down := MonitorView{Weight: 1, LastChecked: checked}
+	degraded := MonitorView{IsUp: true, Degraded: true, Weight: 1, LastChecked: checked}
+	stale := MonitorView{IsUp: true, Stale: true, Weight: 1, LastChecked: checked}
+	paused := MonitorView{Paused: true, Weight: 1, LastChecked: checked}
+	unchecked := MonitorView{Weight: 1}
+
+	tests := []struct {
+		name   string
+		views  []MonitorView
+		want   float64
+		wantOK bool
+	}{
+		{"all up", []MonitorView{up, up}, 100, true},
+		{"all down", []MonitorView{down, down}, 0, true},
+		{"equal weights", []MonitorView{up, up, up, down}, 75, true},
+		{"heavy monitor down", []MonitorView{up, with(down, 3)}, 25, true},
+		{"light monitor down", []MonitorView{with(up, 3), down}, 75, true},
+		{"degraded counts half", []MonitorView{up, degraded}, 75, true},
+		{"stale counts as down", []MonitorView{up, stale}, 50, true},
+		{"weight 0 is left out", []MonitorView{up, with(down, 0)}, 100, true},
+		{"fractional weights", []MonitorView{with(up, 0.5), with(down, 1.5)}, 25, true},
+		{"paused and unchecked are left out", []MonitorView{up, paused, unchecked}, 100, true},
+		{"nothing checked", []MonitorView{unchecked, paused}, 0, false},
+		{"only weight 0", []MonitorView{with(up, 0)}, 0, false},
+		{"no monitors", nil, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := healthScore(tt.views)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: healthScore = %v, %v; want %v, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestHealthScoreSummary(t *testing.T) {
+	inRepoRoot(t)
+	cfg := testConfig(t, `
+monitors:
+  - {name: api, type: tcp, host: 127.0.0.1, port: 1, weight: 2}
+  - {name: web, type: tcp, host: 127.0.0.1, port: 1}
+  - {name: docs, type: tcp, host: 127.0.0.1, port: 1, weight: 0}
+`)
+	st := testStore(t)
+	now := time.Now()
+	for name, up := range map[string]bool{"api": true, "web": false, "docs": false} {
+		if err := st.LogCheck(monitor.CheckResult{MonitorName: name, Timestamp: now, Status: up}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := NewHandler(st, cfg, nil, nil)
+
+	var got HealthSummaryJSON
+	if err := json.Unmarshal(get(t, h, "/api/health-summary").Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	// 2 of 3, rounded to a tenth
+	if got.Score == nil || *got.Score != 66.7 {
+		t.Errorf("score %v, want 66.7", got.Score)
+	}
+}
@@ -0,0 +1,51 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestViewPage(t *testing.T) {
+	inRepoRoot(t)
+	cfg := testConfig(t, `
+monitors:
+  - {name: checkout, type: tcp, host: 127.0.0.1, port: 1, labels: {team: payments}}
+  - {name: ledger, type: tcp, host: 127.0.0.1, port: 1, labels: {team: payments, tier: "1"}}
+  - {name: search, type: tcp, host: 127.0.0.1, port: 1, labels: {team: discovery}}
+  - {name: cdn, type: tcp, host: 127.0.0.1, port: 1}
+views:
+  - {name: payments, title: Payments, labels: {team: payments}}
+  - {name: edge, monitors: [cdn, search]}
+  - {name: mixed, monitors: [cdn], labels: {tier: "1"}}
+`)
+	h := NewHandler(testStore(t), cfg, nil, nil)
+
+	tests := []struct {
+		path  string
+		want  []string
+		title string
+	}{
+		{"/", []string{"checkout", "ledger", "search", "cdn"}, "ZenMonitor"},
+		{"/view/payments", []string{"checkout", "ledger"}, "Payments"},
+		{"/view/edge", []string{"search", "cdn"}, "edge"},
+		{"/view/mixed", []string{"ledger", "cdn"}, "mixed"},
+	}
+	for _, tt := range tests {
+		body := get(t, h, tt.path).Body.String()
+		if got := dashboardOrder(body); !slices.Equal(got, tt.want) {
+			t.Errorf("GET %s: monitors %v, want %v", tt.path, got, tt.want)
+		}
+		if !strings.Contains(body, "<h1>"+tt.title+"</h1>") {
+			t.Errorf("GET %s: heading isn't %q", tt.path, tt.title)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/view/nope", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET of an unknown view: status %d, want 404", rec.Code)
+	}
+}